@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,7 +16,10 @@ import (
 	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/repository"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/client"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/keyring"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/services/user"
+	pkgauth "github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/broker"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/cache"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
@@ -28,8 +32,18 @@ const (
 	gracefulShutdownTimeout = time.Second * 30
 )
 
-func runServer(env config.AppSettings, authService *auth.Service, userService *user.Service) {
-	srv := server.NewServer(env, authService, userService)
+func runServer(
+	env config.AppSettings,
+	authService *auth.Service,
+	userService *user.Service,
+	keyRingService *keyring.Service,
+	clientService *client.Service,
+	rateLimitStore cache.Client,
+) {
+	srv, err := server.NewServer(env, authService, userService, keyRingService, clientService, rateLimitStore)
+	if err != nil {
+		logger.Fatal("Error on create server, ", err)
+	}
 
 	// Run server
 	go func() {
@@ -55,7 +69,23 @@ func runServer(env config.AppSettings, authService *auth.Service, userService *u
 	}
 }
 
+func loadSigningKey(env config.AppSettings) (pkgauth.SigningKey, error) {
+	if env.JwtConfig.Algorithm == pkgauth.AlgorithmHS256 {
+		return pkgauth.NewHMACKey(env.JwtConfig.KeyID, env.SecretKey), nil
+	}
+
+	privateKey, err := os.ReadFile(env.JwtConfig.PrivateKeyFile)
+	if err != nil {
+		return pkgauth.SigningKey{}, err
+	}
+
+	return pkgauth.ParseSigningKey(env.JwtConfig.KeyID, env.JwtConfig.Algorithm, privateKey)
+}
+
 func main() {
+	rotateKeys := flag.Bool("rotate-keys", false, "Rotate the token signing key and exit")
+	flag.Parse()
+
 	// Initialize
 	if err := logger.InitLogger(logger.Config{}); err != nil {
 		panic(err)
@@ -63,13 +93,20 @@ func main() {
 
 	ctx := context.Background()
 	env := config.LoadAppSettingsFromEnv()
-	eventChannel := make(chan schemas.Event, eventChannelBuffer)
 
-	cacheClient, err := cache.NewRedisClient(env.CacheConfig)
+	if err := env.AuthConfig.PasswordPolicy.LoadCorpus(); err != nil {
+		logger.Fatal("Error on load password corpus, ", err)
+	}
+
+	passwordHasher, err := env.AuthConfig.PasswordHasher.Hasher()
 	if err != nil {
-		logger.Fatal("Error on connect to redis:", err)
+		logger.Fatal("Error on configure password hasher, ", err)
 	}
 
+	pkgauth.SetPasswordHasher(passwordHasher)
+
+	eventChannel := make(chan schemas.Event, eventChannelBuffer)
+
 	// Database
 	db, err := database.NewPostgreSQLConnection(env.DatabaseConfig)
 	if err != nil {
@@ -81,6 +118,45 @@ func main() {
 		logger.Fatal("Error on run migrations, ", err)
 	}
 
+	// Signing keys
+	longestTokenLifetime := auth.LongestTokenLifetime(env.ClientConfig.MaxTokenLifetime)
+	if err := env.JwtConfig.ValidateRetirementDelay(longestTokenLifetime); err != nil {
+		logger.Fatal("Error on validate signing key config, ", err)
+	}
+
+	if err := env.JwtConfig.ValidateRefreshInterval(); err != nil {
+		logger.Fatal("Error on validate signing key config, ", err)
+	}
+
+	keyRingService := keyring.NewService(repository.NewSigningKeyRepository(db), env.JwtConfig, env.EncryptionKey)
+	if err := keyRingService.Bootstrap(ctx, func() (pkgauth.SigningKey, error) {
+		return loadSigningKey(env)
+	}); err != nil {
+		logger.Fatal("Error on load signing keys, ", err)
+	}
+
+	if key, err := keyRingService.KeyRing().SigningKey(); err == nil && key.IsSymmetric() {
+		logger.Warning("OpenID Connect is disabled, the id tokens require an asymmetric signing key")
+	}
+
+	if *rotateKeys {
+		if _, err := keyRingService.Rotate(ctx); err != nil {
+			logger.Fatal("Error on rotate signing key, ", err)
+		}
+
+		return
+	}
+
+	cacheClient, err := cache.NewRedisClient(env.CacheConfig)
+	if err != nil {
+		logger.Fatal("Error on connect to redis:", err)
+	}
+
+	rateLimitStore, err := newRateLimitStore(env, cacheClient)
+	if err != nil {
+		logger.Fatal("Error on create rate limit store:", err)
+	}
+
 	// Broker
 	eventBroker, err := broker.NewRabbitMqClient(env.BrokerConfig)
 	if err != nil {
@@ -104,10 +180,37 @@ func main() {
 	defer eventBroker.End()
 	defer provider.Close(ctx)
 
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+
+	go keyRingService.Watch(watchCtx)
+
 	userRepository := repository.NewUserRepository(db)
-	userService := user.NewService(userRepository, eventChannel)
-	authService := auth.NewService(userService, cacheClient, env.SecretKey, eventChannel)
+	userService := user.NewService(
+		userRepository,
+		repository.NewPasswordHistoryRepository(db),
+		env.AuthConfig.PasswordPolicy.Policy(),
+		eventChannel,
+	)
+	authService := auth.NewService(
+		userService,
+		repository.NewWebAuthnCredentialRepository(db),
+		cacheClient,
+		keyRingService.KeyRing(),
+		env.AuthConfig,
+		eventChannel,
+	)
+	clientService := client.NewService(repository.NewClientRepository(db), env.ClientConfig)
 
 	// Server
-	runServer(env, authService, userService)
+	runServer(env, authService, userService, keyRingService, clientService, rateLimitStore)
+}
+
+// newRateLimitStore shares the counters between the instances through redis, unless the memory store is set.
+func newRateLimitStore(env config.AppSettings, redisClient *cache.RedisClient) (cache.Client, error) {
+	if env.RateLimitConfig.Store == config.RateLimitStoreMemory {
+		return cache.NewMemoryCacheClient()
+	}
+
+	return redisClient, nil
 }
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+// AuthorizationCode is the grant issued by the authorize endpoint, it's exchanged once for the tokens.
+type AuthorizationCode struct {
+	ClientID            string    `json:"client_id"`
+	UserID              uuid.UUID `json:"user_id"`
+	RedirectURI         string    `json:"redirect_uri"`
+	Scope               string    `json:"scope"`
+	Nonce               string    `json:"nonce"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+func (c AuthorizationCode) IsExpired() bool {
+	return !time.Now().Before(c.ExpiresAt)
+}
+
+func (c AuthorizationCode) VerifyCodeVerifier(verifier string) bool {
+	return auth.VerifyCodeChallenge(verifier, c.CodeChallenge, c.CodeChallengeMethod)
+}
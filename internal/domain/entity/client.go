@@ -0,0 +1,128 @@
+package entity
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+const clientSecretSize = 32
+
+// Client is an application allowed to call the oauth endpoints. Public clients, like SPAs and mobile apps,
+// can't keep a secret, so they don't have one and must use PKCE to exchange authorization codes.
+// The allowed scopes and the token lifetime, in seconds, apply to the tokens the client requests for itself
+// with the client credentials grant.
+type Client struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	SecretHash    string     `json:"-"`
+	RedirectURIs  StringList `json:"redirect_uris" gorm:"type:text"`
+	Public        bool       `json:"public"`
+	AllowedScopes StringList `json:"allowed_scopes" gorm:"type:text"`
+	TokenLifetime int        `json:"token_lifetime"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ValidateSecret compares the SHA-256 of the secret in constant time, the secrets are random so the slow password
+// hash would only make each token request expensive.
+func (c Client) ValidateSecret(secret string) bool {
+	if c.Public {
+		return secret == ""
+	}
+
+	return auth.CheckSecretHash(secret, c.SecretHash)
+}
+
+// TokenDuration returns the lifetime of the tokens issued to the client itself.
+func (c Client) TokenDuration(defaultDuration time.Duration) time.Duration {
+	if c.TokenLifetime <= 0 {
+		return defaultDuration
+	}
+
+	return time.Duration(c.TokenLifetime) * time.Second
+}
+
+// GrantScope returns the requested scopes when all of them are allowed, or every allowed scope when none is
+// requested.
+func (c Client) GrantScope(requested string) (string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(c.AllowedScopes, " "), true
+	}
+
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !c.AllowedScopes.Contains(scope) {
+			return "", false
+		}
+	}
+
+	return strings.Join(scopes, " "), true
+}
+
+// HasRedirectURI only accepts the exact uris registered, partial matches would allow open redirects.
+func (c Client) HasRedirectURI(redirectURI string) bool {
+	return c.RedirectURIs.Contains(redirectURI)
+}
+
+// NewClient returns the client and its secret, only the secret hash is stored so it can't be recovered later.
+func NewClient(
+	name string, redirectURIs []string, public bool, allowedScopes []string, tokenLifetime int,
+	maxTokenLifetime time.Duration,
+) (Client, string, error) {
+	validator := NewValidator()
+
+	if strings.TrimSpace(name) == "" {
+		validator.AddError("name", "field is required")
+	}
+
+	for _, redirectURI := range redirectURIs {
+		if uri, err := url.Parse(redirectURI); err != nil || !uri.IsAbs() || uri.Fragment != "" {
+			validator.AddError("redirect_uris", "'"+redirectURI+"' is not a valid absolute uri")
+		}
+	}
+
+	for _, scope := range allowedScopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\n") {
+			validator.AddError("allowed_scopes", "'"+scope+"' is not a valid scope")
+		}
+	}
+
+	if tokenLifetime < 0 {
+		validator.AddError("token_lifetime", "must be zero or positive")
+	}
+
+	if time.Duration(tokenLifetime)*time.Second > maxTokenLifetime {
+		validator.AddError("token_lifetime", fmt.Sprintf("must be at most %d", int(maxTokenLifetime.Seconds())))
+	}
+
+	if validator.HasErrors() {
+		return Client{}, "", validator.GetError()
+	}
+
+	client := Client{
+		ID:            uuid.NewString(),
+		Name:          strings.TrimSpace(name),
+		RedirectURIs:  redirectURIs,
+		Public:        public,
+		AllowedScopes: allowedScopes,
+		TokenLifetime: tokenLifetime,
+		CreatedAt:     time.Now(),
+	}
+
+	if public {
+		return client, "", nil
+	}
+
+	secret, err := auth.GenerateSecret(clientSecretSize)
+	if err != nil {
+		return Client{}, "", err
+	}
+
+	client.SecretHash = auth.HashSecret(secret)
+
+	return client, secret, nil
+}
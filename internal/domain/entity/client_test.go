@@ -0,0 +1,78 @@
+package entity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+)
+
+func TestClientGrantScope(t *testing.T) {
+	t.Parallel()
+
+	client := entity.Client{AllowedScopes: entity.StringList{"read", "write"}}
+
+	tests := []struct {
+		scenario      string
+		requested     string
+		expectedScope string
+		expectedOk    bool
+	}{
+		{scenario: "when nothing is requested", requested: "", expectedScope: "read write", expectedOk: true},
+		{scenario: "when a subset is requested", requested: " write ", expectedScope: "write", expectedOk: true},
+		{scenario: "when a scope is not allowed", requested: "read admin", expectedOk: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			scope, ok := client.GrantScope(tc.requested)
+
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedScope, scope)
+		})
+	}
+}
+
+func TestClientValidateSecret(t *testing.T) {
+	t.Parallel()
+
+	redirectURIs := []string{"https://app.example.com/callback"}
+
+	confidential, secret, err := entity.NewClient("Backend", redirectURIs, false, nil, 0, 0)
+	assert.NoError(t, err)
+
+	public, _, err := entity.NewClient("Mobile", redirectURIs, true, nil, 0, 0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario string
+		client   entity.Client
+		secret   string
+		expected bool
+	}{
+		{scenario: "when the secret matches", client: confidential, secret: secret, expected: true},
+		{scenario: "when the secret is wrong", client: confidential, secret: secret + "a", expected: false},
+		{scenario: "when the secret is missing", client: confidential, secret: "", expected: false},
+		{scenario: "when a public client sends no secret", client: public, secret: "", expected: true},
+		{scenario: "when a public client sends a secret", client: public, secret: secret, expected: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			valid := tc.client.ValidateSecret(tc.secret)
+
+			// Assert
+			assert.Equal(t, tc.expected, valid)
+			assert.NotEqual(t, secret, tc.client.SecretHash, "only the hash is stored")
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAChallenge is created when the password is verified, it keeps the login data until the second factor is.
+type MFAChallenge struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Device    string    `json:"device"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Challenge signed by the webauthn credentials, empty when the user has none
+	WebAuthnChallenge string `json:"webauthn_challenge,omitempty"`
+}
+
+func (c MFAChallenge) IsExpired() bool {
+	return !time.Now().Before(c.ExpiresAt)
+}
@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneVerification is the code sent by SMS to the user, it's only valid for the phone it was sent to.
+type PhoneVerification struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Phone     string    `json:"phone"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (v PhoneVerification) IsExpired() bool {
+	return !time.Now().Before(v.ExpiresAt)
+}
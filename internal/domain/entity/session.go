@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	ClientID   string    `json:"client_id,omitempty"`
+	Scope      string    `json:"scope,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func NewSession(userID uuid.UUID, device, ipAddress, userAgent string, duration time.Duration) Session {
+	now := time.Now()
+
+	return Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Device:     device,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(duration),
+	}
+}
+
+func (s Session) IsExpired() bool {
+	return !time.Now().Before(s.ExpiresAt)
+}
+
+// Touch registers the session activity.
+func (s *Session) Touch() {
+	s.LastSeenAt = time.Now()
+}
+
+// Renew registers the session activity and extends its expiration, it's used when the tokens are refreshed.
+func (s *Session) Renew(duration time.Duration) {
+	s.Touch()
+	s.ExpiresAt = s.LastSeenAt.Add(duration)
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+type SigningKey struct {
+	ID          string    `json:"id"`
+	Algorithm   string    `json:"algorithm"`
+	PrivateKey  string    `json:"-"`
+	ActivatesAt time.Time `json:"activates_at"`
+	RetiresAt   time.Time `json:"retires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is stored as a json array in a text column.
+type StringList []string
+
+func (l *StringList) Scan(value any) error {
+	switch data := value.(type) {
+	case nil:
+		*l = nil
+
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), l)
+	case []byte:
+		return json.Unmarshal(data, l)
+	default:
+		return fmt.Errorf("unsupported type for string list: %T", value)
+	}
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(l)
+
+	return string(data), err
+}
+
+func (l StringList) Contains(value string) bool {
+	for _, item := range l {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -11,15 +12,25 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id" binding:"required"`
-	Name         string    `json:"name" binding:"required"`
-	Email        string    `json:"email" binding:"required"`
-	Phone        string    `json:"phone" binding:"required"`
-	PasswordHash string    `json:"-" binding:"required"`
-	Active       bool      `json:"active" default:"true"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	DeletedAt    time.Time `json:"deleted_at"`
+	ID            uuid.UUID `json:"id" binding:"required"`
+	Name          string    `json:"name" binding:"required"`
+	Email         string    `json:"email" binding:"required"`
+	Phone         string    `json:"phone" binding:"required"`
+	PasswordHash  string    `json:"-" binding:"required"`
+	Active        bool      `json:"active" default:"true"`
+	EmailVerified bool      `json:"email_verified"`
+	// Nil until the phone is verified with a code sent by SMS
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"`
+	TOTPSecret      string     `json:"-"`
+	TOTPEnabled     bool       `json:"totp_enabled"`
+	// Hashes of the unused recovery codes, each one satisfies a second factor challenge once
+	RecoveryCodes StringList `json:"-" gorm:"type:text"`
+	// The password expires after the max age of the config, or on the next login when an admin forced its change
+	PasswordChangedAt  time.Time `json:"password_changed_at"`
+	MustChangePassword bool      `json:"must_change_password"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	DeletedAt          time.Time `json:"deleted_at"`
 }
 
 func (u *User) Validate() error {
@@ -48,7 +59,11 @@ func (u *User) Validate() error {
 	return nil
 }
 
-func (u *User) Update(payload schemas.UpdateUserPayload) error {
+// Update applies the changes of the profile, the new password must follow the policy and can't be the current
+// one or any of the previous passwords of the history.
+func (u *User) Update(
+	payload schemas.UpdateUserPayload, policy auth.PasswordPolicy, history []PasswordHistory,
+) error {
 	if payload == (schemas.UpdateUserPayload{}) {
 		return ErrInvalidData
 	}
@@ -63,20 +78,54 @@ func (u *User) Update(payload schemas.UpdateUserPayload) error {
 			return err
 		}
 
+		// The new address must be verified again.
+		if email != u.Email {
+			u.EmailVerified = false
+		}
+
 		u.Email = email
 	}
 
 	if payload.Phone != "" {
-		u.Phone, _ = validators.NormalizePhoneNumber(payload.Phone)
+		phone, _ := validators.NormalizePhoneNumber(payload.Phone)
+
+		// The new number must be verified again.
+		if phone != u.Phone {
+			u.PhoneVerifiedAt = nil
+		}
+
+		u.Phone = phone
 	}
 
 	if payload.Password != "" {
+		validator := NewValidator()
+		validatePassword(&validator, policy, payload.Password, u.Name, u.Email)
+
+		if policy.HistorySize > 0 && u.passwordReused(payload.Password, history) {
+			if policy.HistorySize == 1 {
+				validator.AddRuleError(
+					"password", auth.PasswordRuleHistory, "password must be different from the current password",
+				)
+			} else {
+				validator.AddRuleError(
+					"password", auth.PasswordRuleHistory,
+					fmt.Sprintf("password must be different from the last %d passwords", policy.HistorySize),
+				)
+			}
+		}
+
+		if validator.HasErrors() {
+			return validator.GetError()
+		}
+
 		passwordHash, err := auth.GeneratePasswordHash(payload.Password)
 		if err != nil {
 			return err
 		}
 
 		u.PasswordHash = passwordHash
+		u.PasswordChangedAt = time.Now()
+		u.MustChangePassword = false
 	}
 
 	u.UpdatedAt = time.Now()
@@ -84,11 +133,147 @@ func (u *User) Update(payload schemas.UpdateUserPayload) error {
 	return u.Validate()
 }
 
+// passwordReused checks the password against the current hash and the previous ones.
+func (u *User) passwordReused(password string, history []PasswordHistory) bool {
+	if u.PasswordHash != "" && auth.CheckPasswordHash(password, u.PasswordHash) {
+		return true
+	}
+
+	for _, entry := range history {
+		if auth.CheckPasswordHash(password, entry.PasswordHash) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (u *User) ValidatePassword(password string) bool {
 	return auth.CheckPasswordHash(password, u.PasswordHash)
 }
 
-func NewUser(name, email, phone, password string) (User, error) {
+// ForcePasswordChange makes the next login require a new password before the session starts.
+func (u *User) ForcePasswordChange() {
+	u.MustChangePassword = true
+}
+
+// PasswordExpired tells if the password is older than the max age, zero max age never expires. The users created
+// before the change date was tracked count from the creation date.
+func (u *User) PasswordExpired(maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return false
+	}
+
+	changedAt := u.PasswordChangedAt
+	if changedAt.IsZero() {
+		changedAt = u.CreatedAt
+	}
+
+	return time.Since(changedAt) > maxAge
+}
+
+func (u *User) PasswordChangeRequired(maxAge time.Duration) bool {
+	return u.MustChangePassword || u.PasswordExpired(maxAge)
+}
+
+// RehashPassword replaces an outdated hash of the password, it must be called after the password was validated.
+func (u *User) RehashPassword(password string) (bool, error) {
+	if !auth.PasswordNeedsRehash(u.PasswordHash) {
+		return false, nil
+	}
+
+	passwordHash, err := auth.GeneratePasswordHash(password)
+	if err != nil {
+		return false, err
+	}
+
+	u.PasswordHash = passwordHash
+
+	return true, nil
+}
+
+func (u *User) VerifyEmail() {
+	u.EmailVerified = true
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) VerifyPhone() {
+	now := time.Now()
+
+	u.PhoneVerifiedAt = &now
+	u.UpdatedAt = now
+}
+
+func (u *User) PhoneVerified() bool {
+	return u.PhoneVerifiedAt != nil
+}
+
+// SetTOTPSecret starts the enrollment, the secret is stored encrypted and only used after it's confirmed.
+func (u *User) SetTOTPSecret(encryptedSecret string) {
+	u.TOTPSecret = encryptedSecret
+	u.TOTPEnabled = false
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) EnableTOTP() {
+	u.TOTPEnabled = true
+	u.UpdatedAt = time.Now()
+}
+
+func (u *User) DisableTOTP() {
+	u.TOTPSecret = ""
+	u.TOTPEnabled = false
+	u.UpdatedAt = time.Now()
+}
+
+// GenerateRecoveryCodes replaces the recovery codes of the user, the plain codes are only available now.
+func (u *User) GenerateRecoveryCodes() ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(StringList, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashSecret(auth.NormalizeRecoveryCode(code)))
+	}
+
+	u.RecoveryCodes = hashes
+	u.UpdatedAt = time.Now()
+
+	return codes, nil
+}
+
+// ConsumeRecoveryCode removes the code when it's valid, so it can't be used again.
+func (u *User) ConsumeRecoveryCode(code string) bool {
+	normalized := auth.NormalizeRecoveryCode(code)
+
+	for i, hash := range u.RecoveryCodes {
+		if auth.CheckSecretHash(normalized, hash) {
+			u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+			u.UpdatedAt = time.Now()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *User) ClearRecoveryCodes() {
+	u.RecoveryCodes = nil
+	u.UpdatedAt = time.Now()
+}
+
+// validatePassword reports each rule of the policy violated by the password as an error of the password field,
+// with the code of the rule.
+func validatePassword(validator *Validator, policy auth.PasswordPolicy, password string, personalInfo ...string) {
+	for _, violation := range policy.Validate(password, personalInfo...) {
+		validator.AddRuleError("password", violation.Rule, violation.Message)
+	}
+}
+
+func NewUser(name, email, phone, password string, policy auth.PasswordPolicy) (User, error) {
 	validator := NewValidator()
 
 	phone, err := validators.NormalizePhoneNumber(phone)
@@ -96,9 +281,14 @@ func NewUser(name, email, phone, password string) (User, error) {
 		validator.AddError("phone", err.Error())
 	}
 
-	passwordHash, err := auth.GeneratePasswordHash(password)
-	if err != nil {
-		validator.AddError("password", err.Error())
+	var passwordHash string
+
+	validatePassword(&validator, policy, password, name, email)
+
+	if !validator.HasErrors() {
+		if passwordHash, err = auth.GeneratePasswordHash(password); err != nil {
+			validator.AddError("password", err.Error())
+		}
 	}
 
 	email, err = validators.NormalizeEmail(email)
@@ -118,13 +308,16 @@ func NewUser(name, email, phone, password string) (User, error) {
 		return User{}, validator.GetError()
 	}
 
+	now := time.Now()
+
 	return User{
-		ID:           uuid.New(),
-		Name:         strings.ToTitle(name),
-		Email:        email,
-		Phone:        phone,
-		PasswordHash: passwordHash,
-		CreatedAt:    time.Now(),
-		Active:       true,
+		ID:                uuid.New(),
+		Name:              strings.ToTitle(name),
+		Email:             email,
+		Phone:             phone,
+		PasswordHash:      passwordHash,
+		PasswordChangedAt: now,
+		CreatedAt:         now,
+		Active:            true,
 	}, nil
 }
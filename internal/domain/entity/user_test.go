@@ -3,11 +3,13 @@ package entity_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 )
 
 func TestNewUserErrorMessage(t *testing.T) {
@@ -60,7 +62,7 @@ func TestNewUserErrorMessage(t *testing.T) {
 		t.Run(tc.scenario, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := entity.NewUser(tc.name, tc.email, tc.phone, tc.password)
+			_, err := entity.NewUser(tc.name, tc.email, tc.phone, tc.password, auth.DefaultPasswordPolicy())
 			assert.Error(t, err)
 
 			for _, expectedMessage := range tc.expectedMessages {
@@ -78,6 +80,7 @@ func TestNewUser(t *testing.T) {
 		gofakeit.Email(),
 		gofakeit.Phone(),
 		gofakeit.Password(true, true, true, true, true, 10),
+		auth.DefaultPasswordPolicy(),
 	)
 
 	assert.Nil(t, err)
@@ -96,6 +99,7 @@ func TestUserUpdate(t *testing.T) {
 		gofakeit.Email(),
 		gofakeit.Phone(),
 		gofakeit.Password(true, true, true, true, true, 10),
+		auth.DefaultPasswordPolicy(),
 	)
 	assert.Nil(t, err)
 
@@ -108,7 +112,11 @@ func TestUserUpdate(t *testing.T) {
 	newPassword := gofakeit.Password(true, true, true, true, true, 10)
 	newPhone := gofakeit.Phone()
 
-	err = user.Update(schemas.UpdateUserPayload{Name: newName, Email: newEmail, Phone: newPhone, Password: newPassword})
+	err = user.Update(
+		schemas.UpdateUserPayload{Name: newName, Email: newEmail, Phone: newPhone, Password: newPassword},
+		auth.DefaultPasswordPolicy(),
+		nil,
+	)
 	assert.Nil(t, err)
 	assert.Equal(t, user.Name, strings.ToTitle(newName))
 	assert.NotEqual(t, user.Email, oldEmail)
@@ -127,10 +135,11 @@ func TestUserUpdateSetUpdatedAt(t *testing.T) {
 		gofakeit.Email(),
 		gofakeit.Phone(),
 		gofakeit.Password(true, true, true, true, true, 10),
+		auth.DefaultPasswordPolicy(),
 	)
 	assert.Nil(t, err)
 
-	err = user.Update(schemas.UpdateUserPayload{Name: "New user name"})
+	err = user.Update(schemas.UpdateUserPayload{Name: "New user name"}, auth.DefaultPasswordPolicy(), nil)
 	assert.Nil(t, err)
 
 	assert.False(t, user.UpdatedAt.IsZero())
@@ -217,9 +226,305 @@ func TestUserValidatePassword(t *testing.T) {
 		gofakeit.Email(),
 		gofakeit.Phone(),
 		password,
+		auth.DefaultPasswordPolicy(),
 	)
 	assert.Nil(t, err)
 
 	assert.True(t, user.ValidatePassword(password))
 	assert.False(t, user.ValidatePassword("wrongpassword"))
 }
+
+func TestConsumeRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	user, err := entity.NewUser(
+		gofakeit.Name(), gofakeit.Email(), gofakeit.Phone(), gofakeit.Password(true, true, true, true, true, 10),
+		auth.DefaultPasswordPolicy(),
+	)
+	assert.NoError(t, err)
+
+	codes, err := user.GenerateRecoveryCodes()
+	assert.NoError(t, err)
+
+	// Action
+	consumed := user.ConsumeRecoveryCode(strings.ToUpper(codes[3]))
+
+	// Assert
+	assert.True(t, consumed)
+	assert.Len(t, user.RecoveryCodes, len(codes)-1)
+	assert.False(t, user.ConsumeRecoveryCode(codes[3]), "a code must be used only once")
+	assert.False(t, user.ConsumeRecoveryCode("0000-0000"))
+	assert.True(t, user.ConsumeRecoveryCode(codes[0]))
+}
+
+func TestUpdateEmailRequiresVerification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario         string
+		email            func(current string) string
+		expectedVerified bool
+	}{
+		{
+			scenario:         "when email changes",
+			email:            func(string) string { return gofakeit.Email() },
+			expectedVerified: false,
+		},
+		{
+			scenario:         "when email is the same",
+			email:            func(current string) string { return current },
+			expectedVerified: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			user, err := entity.NewUser(
+				gofakeit.Name(), gofakeit.Email(), gofakeit.Phone(), gofakeit.Password(true, true, true, true, true, 10),
+				auth.DefaultPasswordPolicy(),
+			)
+			assert.NoError(t, err)
+
+			user.VerifyEmail()
+
+			// Action
+			err = user.Update(schemas.UpdateUserPayload{Email: tc.email(user.Email)}, auth.DefaultPasswordPolicy(), nil)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedVerified, user.EmailVerified)
+		})
+	}
+}
+
+func TestUpdatePhoneRequiresVerification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario         string
+		phone            func(current string) string
+		expectedVerified bool
+	}{
+		{
+			scenario:         "when phone changes",
+			phone:            func(string) string { return "5511912345678" },
+			expectedVerified: false,
+		},
+		{
+			scenario:         "when phone is the same",
+			phone:            func(current string) string { return current },
+			expectedVerified: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			user, err := entity.NewUser(
+				gofakeit.Name(), gofakeit.Email(), "5521987654321", gofakeit.Password(true, true, true, true, true, 10),
+				auth.DefaultPasswordPolicy(),
+			)
+			assert.NoError(t, err)
+
+			user.VerifyPhone()
+
+			// Action
+			err = user.Update(schemas.UpdateUserPayload{Phone: tc.phone(user.Phone)}, auth.DefaultPasswordPolicy(), nil)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedVerified, user.PhoneVerified())
+		})
+	}
+}
+
+func TestNewUserPasswordPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	policy := auth.PasswordPolicy{MinLength: 10, RequireDigit: true, BanPersonalInfo: true}
+
+	// Action
+	_, err := entity.NewUser("Maria Silva", "maria@example.com", gofakeit.Phone(), "maria", policy)
+
+	// Assert
+	var validationErr *entity.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{
+		"password must be 10 or more caracters",
+		"password must contain a digit",
+		"password must not contain the name or email",
+	}, validationErr.Fields["password"])
+	assert.Equal(t, []string{
+		auth.PasswordRuleMinLength,
+		auth.PasswordRuleDigit,
+		auth.PasswordRulePersonalInfo,
+	}, validationErr.Rules["password"])
+}
+
+func TestUserUpdatePasswordHistory(t *testing.T) {
+	t.Parallel()
+
+	currentPassword := "current-password"
+	previousPassword := "previous-password"
+
+	previousHash, err := auth.GeneratePasswordHash(previousPassword)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario        string
+		historySize     int
+		password        string
+		expectedMessage string
+	}{
+		{
+			scenario:    "when password is new",
+			historySize: 3,
+			password:    "brand-new-password",
+		},
+		{
+			scenario:        "when password is the current one",
+			historySize:     3,
+			password:        currentPassword,
+			expectedMessage: "password must be different from the last 3 passwords",
+		},
+		{
+			scenario:        "when password is in the history",
+			historySize:     3,
+			password:        previousPassword,
+			expectedMessage: "password must be different from the last 3 passwords",
+		},
+		{
+			scenario:        "when history only remembers the current password",
+			historySize:     1,
+			password:        currentPassword,
+			expectedMessage: "password must be different from the current password",
+		},
+		{
+			scenario:    "when history is disabled",
+			historySize: 0,
+			password:    currentPassword,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			user, err := entity.NewUser(
+				"Maria Silva", "maria@example.com", gofakeit.Phone(), currentPassword, auth.DefaultPasswordPolicy(),
+			)
+			assert.NoError(t, err)
+
+			policy := auth.PasswordPolicy{HistorySize: tc.historySize}
+			history := []entity.PasswordHistory{entity.NewPasswordHistory(user.ID, previousHash)}
+
+			// Action
+			err = user.Update(schemas.UpdateUserPayload{Password: tc.password}, policy, history)
+
+			// Assert
+			if tc.expectedMessage == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var validationErr *entity.ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, []string{tc.expectedMessage}, validationErr.Fields["password"])
+			assert.Equal(t, []string{auth.PasswordRuleHistory}, validationErr.Rules["password"])
+		})
+	}
+}
+
+func TestUserPasswordChangeRequired(t *testing.T) {
+	t.Parallel()
+
+	maxAge := time.Hour * 24 * 90
+
+	tests := []struct {
+		scenario string
+		user     entity.User
+		maxAge   time.Duration
+		expected bool
+	}{
+		{
+			scenario: "when password was changed recently",
+			user:     entity.User{PasswordChangedAt: time.Now().Add(-time.Hour)},
+			maxAge:   maxAge,
+			expected: false,
+		},
+		{
+			scenario: "when password is older than the max age",
+			user:     entity.User{PasswordChangedAt: time.Now().Add(-maxAge - time.Hour)},
+			maxAge:   maxAge,
+			expected: true,
+		},
+		{
+			scenario: "when max age is disabled",
+			user:     entity.User{PasswordChangedAt: time.Now().Add(-maxAge - time.Hour)},
+			expected: false,
+		},
+		{
+			scenario: "when change date is unknown the creation date is used",
+			user:     entity.User{CreatedAt: time.Now().Add(-maxAge - time.Hour)},
+			maxAge:   maxAge,
+			expected: true,
+		},
+		{
+			scenario: "when an admin forced the change",
+			user:     entity.User{PasswordChangedAt: time.Now(), MustChangePassword: true},
+			maxAge:   maxAge,
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			required := tc.user.PasswordChangeRequired(tc.maxAge)
+
+			// Assert
+			assert.Equal(t, tc.expected, required)
+		})
+	}
+}
+
+func TestUserUpdatePasswordClearsForcedChange(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	user, err := entity.NewUser(
+		gofakeit.Name(), gofakeit.Email(), gofakeit.Phone(), "current-password", auth.DefaultPasswordPolicy(),
+	)
+	assert.NoError(t, err)
+
+	changedAt := time.Now().Add(-time.Hour)
+	user.PasswordChangedAt = changedAt
+	user.ForcePasswordChange()
+
+	// Action
+	err = user.Update(schemas.UpdateUserPayload{Password: "new-password"}, auth.DefaultPasswordPolicy(), nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, user.MustChangePassword)
+	assert.True(t, user.PasswordChangedAt.After(changedAt))
+}
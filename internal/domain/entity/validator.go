@@ -8,10 +8,15 @@ import (
 type ValidationErrorProps struct {
 	Context string
 	Message string
+	Rule    string
 }
 
 type ValidationError struct {
 	Message string
+	// Messages grouped by field, like the failed rules of the password policy
+	Fields map[string][]string
+	// Codes of the failed rules grouped by field, for the clients to translate or match them
+	Rules map[string][]string
 }
 
 func (e ValidationError) Error() string {
@@ -32,6 +37,11 @@ func (e *Validator) AddError(context, message string) {
 	e.errors = append(e.errors, ValidationErrorProps{Context: context, Message: message})
 }
 
+// AddRuleError adds the error of a failed rule, the code of the rule is kept with the message.
+func (e *Validator) AddRuleError(context, rule, message string) {
+	e.errors = append(e.errors, ValidationErrorProps{Context: context, Message: message, Rule: rule})
+}
+
 func (e *Validator) HasErrors() bool {
 	return len(e.errors) > 0
 }
@@ -42,8 +52,14 @@ func (e Validator) GetError() *ValidationError {
 	}
 
 	errGroup := make(map[string][]string, 0)
+	ruleGroup := make(map[string][]string, 0)
+
 	for _, err := range e.errors {
 		errGroup[err.Context] = append(errGroup[err.Context], err.Message)
+
+		if err.Rule != "" {
+			ruleGroup[err.Context] = append(ruleGroup[err.Context], err.Rule)
+		}
 	}
 
 	errMsgs := []string{}
@@ -53,5 +69,5 @@ func (e Validator) GetError() *ValidationError {
 		errMsgs = append(errMsgs, fmt.Sprintf("%s: %s", k, msg))
 	}
 
-	return &ValidationError{Message: strings.Join(errMsgs, "\n")}
+	return &ValidationError{Message: strings.Join(errMsgs, "\n"), Fields: errGroup, Rules: ruleGroup}
 }
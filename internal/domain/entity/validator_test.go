@@ -74,3 +74,20 @@ func TestValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatorGroupsTheRules(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := entity.NewValidator()
+	sut.AddRuleError("password", "min_length", "password is too short")
+	sut.AddRuleError("password", "digit", "password must contain a digit")
+	sut.AddError("email", "invalid email")
+
+	// Action
+	err := sut.GetError()
+
+	// Assert
+	assert.Equal(t, map[string][]string{"password": {"min_length", "digit"}}, err.Rules)
+	assert.Equal(t, []string{"password is too short", "password must contain a digit"}, err.Fields["password"])
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+const defaultWebAuthnCredentialName = "Security key"
+
+// WebAuthnCredential is a passkey or security key registered by the user, the id is the base64url encoded
+// credential id and the public key is COSE encoded.
+type WebAuthnCredential struct {
+	ID         string    `json:"id"`
+	UserID     uuid.UUID `json:"-"`
+	Name       string    `json:"name"`
+	PublicKey  []byte    `json:"-"`
+	SignCount  uint32    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func NewWebAuthnCredential(userID uuid.UUID, name string, credential auth.WebAuthnCredential) WebAuthnCredential {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultWebAuthnCredentialName
+	}
+
+	return WebAuthnCredential{
+		ID:        auth.EncodeWebAuthnID(credential.ID),
+		UserID:    userID,
+		Name:      name,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+		CreatedAt: time.Now(),
+	}
+}
+
+// UpdateSignCount registers the use of the credential. The counter must always increase, otherwise the
+// authenticator may have been cloned, authenticators that don't implement it always send zero.
+func (c *WebAuthnCredential) UpdateSignCount(signCount uint32) bool {
+	if (signCount != 0 || c.SignCount != 0) && signCount <= c.SignCount {
+		return false
+	}
+
+	c.SignCount = signCount
+	c.LastUsedAt = time.Now()
+
+	return true
+}
@@ -0,0 +1,30 @@
+package config
+
+import "time"
+
+type AuthConfig struct {
+	// Public url of the service, it's the issuer of the id tokens and the base of the discovery endpoints.
+	Issuer string `env:"ISSUER_URL,default=http://localhost:8000"`
+	// Audience of the access tokens, the resource servers must check the iss and aud claims and require
+	// token_use=access, or client_access for the client credentials. Empty uses the issuer.
+	TokenAudience string `env:"TOKEN_AUDIENCE"`
+	// Relying party of the passkeys, the credentials are bound to the domain and only usable from the origin.
+	WebAuthnRPID   string `env:"WEBAUTHN_RP_ID,default=localhost"`
+	WebAuthnOrigin string `env:"WEBAUTHN_ORIGIN,default=http://localhost:8000"`
+	// Blocks the login until the user confirms the email with the token sent on the sign up.
+	RequireEmailVerification bool `env:"REQUIRE_EMAIL_VERIFICATION,default=false"`
+	// Locks the password login after the failed attempts, the lockout doubles each time the account is locked
+	// again, up to the max duration. Zero attempts disable the lockout and a zero max duration lets it grow up to
+	// a year.
+	LoginMaxAttempts        int           `env:"LOGIN_MAX_ATTEMPTS,default=5"`
+	LoginLockoutDuration    time.Duration `env:"LOGIN_LOCKOUT_DURATION,default=15m"`
+	LoginMaxLockoutDuration time.Duration `env:"LOGIN_MAX_LOCKOUT_DURATION,default=24h"`
+	// Requirements of the new passwords, the current ones keep working when the policy changes.
+	PasswordPolicy PasswordPolicyConfig
+	// Passwords older than the max age must be changed on the login, zero never expires them.
+	PasswordMaxAge time.Duration `env:"PASSWORD_MAX_AGE,default=0s"`
+	// Algorithm and costs of the new password hashes, the outdated hashes are replaced on the login.
+	PasswordHasher PasswordHasherConfig
+	// Encrypts the second factor secrets, it's filled with the application encryption key.
+	EncryptionKey string
+}
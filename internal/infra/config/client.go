@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+// ClientConfig bounds the lifetime the oauth clients can set for their own tokens.
+type ClientConfig struct {
+	MaxTokenLifetime time.Duration `env:"CLIENT_MAX_TOKEN_LIFETIME,default=1h"`
+}
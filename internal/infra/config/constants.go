@@ -10,6 +10,7 @@ const (
 
 const (
 	HeaderUserID         = "X-User-ID"
+	HeaderAdminKey       = "X-Admin-Key"
 	HeaderAuthentication = "Authorization"
 	TokenSchema          = "Bearer"
 )
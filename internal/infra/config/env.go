@@ -1,6 +1,8 @@
 package config
 
 import (
+	"strings"
+
 	"github.com/netflix/go-env"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/logger"
 )
@@ -11,6 +13,9 @@ type AppSettings struct {
 	Debug      bool   `env:"DEBUG,default=false"`
 	ServerPort int    `env:"PORT,default=8000"`
 
+	EncryptionKey string `env:"ENCRYPTION_KEY,default=MyEncryptionKey"`
+	AdminAPIKey   string `env:"ADMIN_API_KEY"`
+
 	TraceServiceName string `env:"TRACE_SERVICE_NAME"`
 	TraceURL         string `env:"TRACE_URL,default=http://localhost:14268"`
 
@@ -18,9 +23,18 @@ type AppSettings struct {
 	CorsAllowMethods string `env:"CORS_ALLOW_METHODS,default=*"`
 	CorsAllowHeaders string `env:"CORS_ALLOW_HEADERS,default=*"`
 
-	DatabaseConfig DatabaseConfig
-	BrokerConfig   BrokerConfig
-	CacheConfig    CacheConfig
+	// TrustedProxies are the comma separated addresses or CIDRs of the proxies allowed to forward the client
+	// address, the address of the connection is used when it's empty.
+	TrustedProxies string `env:"TRUSTED_PROXIES"`
+
+	DatabaseConfig  DatabaseConfig
+	BrokerConfig    BrokerConfig
+	CacheConfig     CacheConfig
+	JwtConfig       JwtConfig
+	AuthConfig      AuthConfig
+	RateLimitConfig RateLimitConfig
+	OAuthConfig     OAuthConfig
+	ClientConfig    ClientConfig
 }
 
 func LoadAppSettingsFromEnv() AppSettings {
@@ -37,5 +51,22 @@ func LoadAppSettingsFromEnv() AppSettings {
 		cfg.TraceServiceName = ServiceName
 	}
 
+	cfg.AuthConfig.EncryptionKey = cfg.EncryptionKey
+
+	// The issuer is compared with the iss claim and prefixes the discovery endpoints, it's kept without the slash.
+	cfg.AuthConfig.Issuer = strings.TrimRight(cfg.AuthConfig.Issuer, "/")
+
 	return cfg
 }
+
+func (cfg AppSettings) TrustedProxyList() []string {
+	var proxies []string
+
+	for _, proxy := range strings.Split(cfg.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
+}
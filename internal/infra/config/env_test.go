@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+)
+
+func TestLoadAppSettingsFromEnvIssuer(t *testing.T) {
+	tests := []struct {
+		scenario       string
+		issuer         string
+		expectedIssuer string
+	}{
+		{
+			scenario:       "when the issuer has no trailing slash",
+			issuer:         "https://auth.example.com",
+			expectedIssuer: "https://auth.example.com",
+		},
+		{
+			scenario:       "when the issuer has a trailing slash",
+			issuer:         "https://auth.example.com/",
+			expectedIssuer: "https://auth.example.com",
+		},
+		{
+			scenario:       "when the issuer has a path with trailing slashes",
+			issuer:         "https://example.com/auth//",
+			expectedIssuer: "https://example.com/auth",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			// Arrange
+			t.Setenv("ISSUER_URL", tc.issuer)
+
+			// Action
+			cfg := config.LoadAppSettingsFromEnv()
+
+			// Assert
+			assert.Equal(t, tc.expectedIssuer, cfg.AuthConfig.Issuer)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type JwtConfig struct {
+	Algorithm      string `env:"JWT_ALGORITHM,default=HS256"`
+	KeyID          string `env:"JWT_KEY_ID"`
+	PrivateKeyFile string `env:"JWT_PRIVATE_KEY_FILE"`
+
+	// A rotated key is published this long before it starts signing, so verifiers can fetch it first.
+	KeyActivationDelay time.Duration `env:"JWT_KEY_ACTIVATION_DELAY,default=2m"`
+	// A superseded key keeps verifying tokens for this long, it must cover the longest token lifetime.
+	KeyRetirementDelay time.Duration `env:"JWT_KEY_RETIREMENT_DELAY,default=24h"`
+	// The instances reload the keys with this interval to pick up the rotations made by the others.
+	KeyRefreshInterval time.Duration `env:"JWT_KEY_REFRESH_INTERVAL,default=1m"`
+}
+
+// ValidateRetirementDelay rejects a retirement delay shorter than the longest token lifetime, the tokens signed
+// just before a rotation would stop being verified before expiring.
+func (c JwtConfig) ValidateRetirementDelay(longestTokenLifetime time.Duration) error {
+	if c.KeyRetirementDelay < longestTokenLifetime {
+		return fmt.Errorf(
+			"JWT_KEY_RETIREMENT_DELAY must be at least the longest token lifetime of %s, got %s",
+			longestTokenLifetime, c.KeyRetirementDelay,
+		)
+	}
+
+	return nil
+}
+
+// ValidateRefreshInterval rejects a refresh interval that isn't positive, the keys wouldn't be reloaded.
+func (c JwtConfig) ValidateRefreshInterval() error {
+	if c.KeyRefreshInterval <= 0 {
+		return fmt.Errorf("JWT_KEY_REFRESH_INTERVAL must be positive, got %s", c.KeyRefreshInterval)
+	}
+
+	return nil
+}
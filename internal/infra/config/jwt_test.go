@@ -0,0 +1,91 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+)
+
+func TestValidateRetirementDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario             string
+		retirementDelay      time.Duration
+		longestTokenLifetime time.Duration
+		expectError          bool
+	}{
+		{
+			scenario:             "when delay covers the longest token lifetime",
+			retirementDelay:      time.Hour * 24,
+			longestTokenLifetime: time.Hour * 24,
+		},
+		{
+			scenario:             "when delay is shorter than the longest token lifetime",
+			retirementDelay:      time.Hour,
+			longestTokenLifetime: time.Hour * 24,
+			expectError:          true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			cfg := config.JwtConfig{KeyRetirementDelay: tc.retirementDelay}
+
+			// Action
+			err := cfg.ValidateRetirementDelay(tc.longestTokenLifetime)
+
+			// Assert
+			assert.Equal(t, tc.expectError, err != nil)
+		})
+	}
+}
+
+func TestValidateRefreshInterval(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario        string
+		refreshInterval time.Duration
+		expectError     bool
+	}{
+		{
+			scenario:        "when the interval is positive",
+			refreshInterval: time.Minute,
+		},
+		{
+			scenario:        "when the interval is zero",
+			refreshInterval: 0,
+			expectError:     true,
+		},
+		{
+			scenario:        "when the interval is negative",
+			refreshInterval: -time.Minute,
+			expectError:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			cfg := config.JwtConfig{KeyRefreshInterval: tc.refreshInterval}
+
+			// Action
+			err := cfg.ValidateRefreshInterval()
+
+			// Assert
+			assert.Equal(t, tc.expectError, err != nil)
+		})
+	}
+}
@@ -0,0 +1,10 @@
+package config
+
+// OAuthConfig has the pages of the first party app used by the authorize endpoint, it redirects to the login page
+// while the browser has no session cookie and to the consent page so the user approves the client.
+type OAuthConfig struct {
+	LoginURL   string `env:"OAUTH_LOGIN_URL,default=http://localhost:3000/login"`
+	ConsentURL string `env:"OAUTH_CONSENT_URL,default=http://localhost:3000/consent"`
+	// The session cookie is only sent over https when it's secure, it should only be disabled on local runs.
+	SessionCookieSecure bool `env:"OAUTH_SESSION_COOKIE_SECURE,default=true"`
+}
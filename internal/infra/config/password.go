@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+// PasswordPolicyConfig are the requirements of the user passwords, the banned words are comma separated. The
+// breached passwords are read from a file of SHA-1 hashes and the common ones from a list of plain passwords,
+// both are loaded on the startup into the corpus.
+type PasswordPolicyConfig struct {
+	MinLength        int    `env:"PASSWORD_MIN_LENGTH,default=8"`
+	MaxLength        int    `env:"PASSWORD_MAX_LENGTH,default=128"`
+	RequireUppercase bool   `env:"PASSWORD_REQUIRE_UPPERCASE,default=false"`
+	RequireLowercase bool   `env:"PASSWORD_REQUIRE_LOWERCASE,default=false"`
+	RequireDigit     bool   `env:"PASSWORD_REQUIRE_DIGIT,default=false"`
+	RequireSymbol    bool   `env:"PASSWORD_REQUIRE_SYMBOL,default=false"`
+	MaxRepeated      int    `env:"PASSWORD_MAX_REPEATED,default=0"`
+	BanPersonalInfo  bool   `env:"PASSWORD_BAN_PERSONAL_INFO,default=true"`
+	BannedWords      string `env:"PASSWORD_BANNED_WORDS"`
+	BreachedFile     string `env:"PASSWORD_BREACHED_SHA1_FILE"`
+	CommonFile       string `env:"PASSWORD_COMMON_LIST_FILE"`
+	HistorySize      int    `env:"PASSWORD_HISTORY_SIZE,default=5"`
+	Corpus           auth.PasswordCorpus
+}
+
+// Bloom filters of the common passwords report 1 in 1000 of the other passwords as common.
+const commonPasswordsFalsePositiveRate = 0.001
+
+// LoadCorpus reads the breached and common password files, they stay in memory for local lookups.
+func (cfg *PasswordPolicyConfig) LoadCorpus() error {
+	var corpora auth.PasswordCorpora
+
+	if cfg.BreachedFile != "" {
+		breached, err := auth.LoadSHA1Corpus(cfg.BreachedFile)
+		if err != nil {
+			return err
+		}
+
+		corpora = append(corpora, breached)
+	}
+
+	if cfg.CommonFile != "" {
+		common, err := auth.LoadPasswordList(cfg.CommonFile, commonPasswordsFalsePositiveRate)
+		if err != nil {
+			return err
+		}
+
+		corpora = append(corpora, common)
+	}
+
+	if len(corpora) > 0 {
+		cfg.Corpus = corpora
+	}
+
+	return nil
+}
+
+// PasswordHasherConfig selects the hasher of the passwords, argon2id or bcrypt, the argon2 memory is in KiB.
+type PasswordHasherConfig struct {
+	Algorithm         string `env:"PASSWORD_HASH_ALGORITHM,default=argon2id"`
+	Argon2Memory      int    `env:"PASSWORD_ARGON2_MEMORY,default=19456"`
+	Argon2Iterations  int    `env:"PASSWORD_ARGON2_ITERATIONS,default=2"`
+	Argon2Parallelism int    `env:"PASSWORD_ARGON2_PARALLELISM,default=1"`
+	BcryptCost        int    `env:"PASSWORD_BCRYPT_COST,default=10"`
+}
+
+func (cfg PasswordHasherConfig) Hasher() (auth.PasswordHasher, error) {
+	switch cfg.Algorithm {
+	case auth.PasswordHashArgon2id:
+		if err := cfg.validateArgon2(); err != nil {
+			return nil, err
+		}
+
+		params := auth.DefaultArgon2Params()
+		params.Memory = uint32(cfg.Argon2Memory)
+		params.Iterations = uint32(cfg.Argon2Iterations)
+		params.Parallelism = uint8(cfg.Argon2Parallelism)
+
+		return auth.NewArgon2idHasher(params), nil
+	case auth.PasswordHashBcrypt:
+		return auth.NewBcryptHasher(cfg.BcryptCost), nil
+	default:
+		return nil, auth.ErrUnknownPasswordHash
+	}
+}
+
+func (cfg PasswordHasherConfig) validateArgon2() error {
+	if cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > auth.Argon2MaxIterations {
+		return fmt.Errorf("PASSWORD_ARGON2_ITERATIONS must be between 1 and %d, got %d", auth.Argon2MaxIterations,
+			cfg.Argon2Iterations)
+	}
+
+	if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > math.MaxUint8 {
+		return fmt.Errorf("PASSWORD_ARGON2_PARALLELISM must be between 1 and %d, got %d", math.MaxUint8,
+			cfg.Argon2Parallelism)
+	}
+
+	minMemory := auth.Argon2MemoryPerThread * cfg.Argon2Parallelism
+	if cfg.Argon2Memory < minMemory || cfg.Argon2Memory > auth.Argon2MaxMemory {
+		return fmt.Errorf("PASSWORD_ARGON2_MEMORY must be between %d and %d KiB, got %d", minMemory, auth.Argon2MaxMemory,
+			cfg.Argon2Memory)
+	}
+
+	return nil
+}
+
+func (cfg PasswordPolicyConfig) Policy() auth.PasswordPolicy {
+	var bannedWords []string
+
+	for _, word := range strings.Split(cfg.BannedWords, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			bannedWords = append(bannedWords, word)
+		}
+	}
+
+	return auth.PasswordPolicy{
+		MinLength:        cfg.MinLength,
+		MaxLength:        cfg.MaxLength,
+		RequireUppercase: cfg.RequireUppercase,
+		RequireLowercase: cfg.RequireLowercase,
+		RequireDigit:     cfg.RequireDigit,
+		RequireSymbol:    cfg.RequireSymbol,
+		MaxRepeated:      cfg.MaxRepeated,
+		BanPersonalInfo:  cfg.BanPersonalInfo,
+		BannedWords:      bannedWords,
+		Corpus:           cfg.Corpus,
+		HistorySize:      cfg.HistorySize,
+	}
+}
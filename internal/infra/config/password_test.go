@@ -0,0 +1,100 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestPasswordHasherConfigHasher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario    string
+		memory      int
+		iterations  int
+		parallelism int
+		expectError bool
+	}{
+		{
+			scenario:    "when the parameters are valid",
+			memory:      19456,
+			iterations:  2,
+			parallelism: 1,
+		},
+		{
+			scenario:    "when there are no iterations",
+			memory:      19456,
+			iterations:  0,
+			parallelism: 1,
+			expectError: true,
+		},
+		{
+			scenario:    "when the iterations are negative",
+			memory:      19456,
+			iterations:  -1,
+			parallelism: 1,
+			expectError: true,
+		},
+		{
+			scenario:    "when the iterations are more than the limit",
+			memory:      19456,
+			iterations:  1025,
+			parallelism: 1,
+			expectError: true,
+		},
+		{
+			scenario:    "when there is no parallelism",
+			memory:      19456,
+			iterations:  2,
+			parallelism: 0,
+			expectError: true,
+		},
+		{
+			scenario:    "when the parallelism overflows a byte",
+			memory:      19456,
+			iterations:  2,
+			parallelism: 256,
+			expectError: true,
+		},
+		{
+			scenario:    "when the memory is less than 8 KiB per thread",
+			memory:      31,
+			iterations:  2,
+			parallelism: 4,
+			expectError: true,
+		},
+		{
+			scenario:    "when the memory is more than 4 GiB",
+			memory:      4*1024*1024 + 1,
+			iterations:  2,
+			parallelism: 1,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			cfg := config.PasswordHasherConfig{
+				Algorithm:         auth.PasswordHashArgon2id,
+				Argon2Memory:      tc.memory,
+				Argon2Iterations:  tc.iterations,
+				Argon2Parallelism: tc.parallelism,
+			}
+
+			// Action
+			hasher, err := cfg.Hasher()
+
+			// Assert
+			assert.Equal(t, tc.expectError, err != nil)
+			assert.Equal(t, tc.expectError, hasher == nil)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+const (
+	RateLimitStoreRedis  = "redis"
+	RateLimitStoreMemory = "memory"
+)
+
+// RateLimitConfig limits the requests to the public auth routes, zero limits disable them. The counters are kept
+// in redis so they're shared by every instance, the memory store is only meant for local runs.
+type RateLimitConfig struct {
+	Store   string        `env:"RATE_LIMIT_STORE,default=redis"`
+	Window  time.Duration `env:"RATE_LIMIT_WINDOW,default=1m"`
+	ByIP    int           `env:"RATE_LIMIT_BY_IP,default=30"`
+	ByEmail int           `env:"RATE_LIMIT_BY_EMAIL,default=10"`
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// RotateSigningKey godoc
+// @Summary      Rotate token signing key
+// @Description  Create a new signing key and schedule the retirement of the current ones
+// @Param        X-Admin-Key  header  string  true  "Admin api key"
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  entity.SigningKey
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/admin/keys/rotate [post].
+func (h *Handler) RotateSigningKey(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.rotate-signing-key")
+	defer span.End()
+
+	key, err := h.KeyRingSvc.Rotate(ctx)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to rotate signing key"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to rotate signing key")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListUserSessions godoc
+// @Summary  List user sessions
+// @Param    X-Admin-Key  header  string  true  "Admin api key"
+// @Param    id           path    string  true  "User ID"
+// @Tags     Admin
+// @Accept   json
+// @Produce  json
+// @Success  200  {array}   handler.SessionJSON
+// @Failure  400  {object}  handler.MessageJSON
+// @Failure  403  {object}  handler.MessageJSON
+// @Failure  500  {object}  handler.MessageJSON
+// @Router   /api/v1/admin/users/{id}/sessions [get].
+func (h *Handler) ListUserSessions(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.list-user-sessions")
+	defer span.End()
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Invalid user id"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	sessions, err := h.AuthSvc.ListSessions(ctx, userID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to list sessions"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to list sessions")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newSessionsJSON(sessions, uuid.UUID{}))
+}
+
+// RevokeUserSession godoc
+// @Summary  Revoke a user session
+// @Param    X-Admin-Key  header  string  true  "Admin api key"
+// @Param    id           path    string  true  "User ID"
+// @Param    session_id   path    string  true  "Session ID"
+// @Tags     Admin
+// @Accept   json
+// @Produce  json
+// @Success  200  {object}  handler.MessageJSON
+// @Failure  400  {object}  handler.MessageJSON
+// @Failure  403  {object}  handler.MessageJSON
+// @Failure  404  {object}  handler.MessageJSON
+// @Router   /api/v1/admin/users/{id}/sessions/{session_id} [delete].
+func (h *Handler) RevokeUserSession(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.revoke-user-session")
+	defer span.End()
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Invalid user id"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "session_id": c.Param("session_id")})
+
+	h.revokeSession(ctx, c, userID, c.Param("session_id"))
+}
+
+// UnlockUser godoc
+// @Summary  Unlock user login
+// @Param    X-Admin-Key  header  string  true  "Admin api key"
+// @Param    id           path    string  true  "User ID"
+// @Tags     Admin
+// @Accept   json
+// @Produce  json
+// @Success  200  {object}  handler.MessageJSON
+// @Failure  400  {object}  handler.MessageJSON
+// @Failure  403  {object}  handler.MessageJSON
+// @Failure  500  {object}  handler.MessageJSON
+// @Router   /api/v1/admin/users/{id}/unlock [post].
+func (h *Handler) UnlockUser(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.unlock-user")
+	defer span.End()
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Invalid user id"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	if err := h.AuthSvc.UnlockAccount(ctx, userID); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to unlock user"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to unlock user")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
+
+// ForcePasswordChange godoc
+// @Summary      Force user password change
+// @Description  The next login of the user returns a password change token instead of the session
+// @Param        X-Admin-Key  header  string  true  "Admin api key"
+// @Param        id           path    string  true  "User ID"
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/admin/users/{id}/force-password-change [post].
+func (h *Handler) ForcePasswordChange(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.force-password-change")
+	defer span.End()
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Invalid user id"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	if err := h.AuthSvc.ForcePasswordChange(ctx, userID); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to force password change"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to force password change")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
+
+// ClientCredentialsJSON is only returned when the client is created, the secret can't be recovered later.
+type ClientCredentialsJSON struct {
+	entity.Client
+	Secret string `json:"secret"`
+}
+
+// CreateClient godoc
+// @Summary      Create oauth client
+// @Description  Register a client allowed to call the oauth endpoints and return its credentials
+// @Param        X-Admin-Key  header  string                       true  "Admin api key"
+// @Param        payload      body    schemas.CreateClientPayload  true  "Client data"
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  handler.ClientCredentialsJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/admin/clients [post].
+func (h *Handler) CreateClient(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.create-client")
+	defer span.End()
+
+	var payload schemas.CreateClientPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	client, secret, err := h.ClientSvc.Create(ctx, payload)
+
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to create client"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to create client")
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, ClientCredentialsJSON{Client: client, Secret: secret})
+}
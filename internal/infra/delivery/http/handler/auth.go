@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
 )
 
@@ -36,7 +42,7 @@ func (h *Handler) SignUp(c *gin.Context) {
 
 	user, err := h.AuthSvc.SignUp(ctx, payload)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: err.Error()})
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
 		trace.AddSpanError(span, err)
 		trace.FailSpan(span, "Bad request")
 
@@ -48,7 +54,7 @@ func (h *Handler) SignUp(c *gin.Context) {
 
 // Login godoc
 // @Summary      Get user access token
-// @Description  Generate a new access token
+// @Description  Generate a new access token, users with mfa enabled get a challenge to verify the second factor
 // @Param        payload  body  schemas.Login  true  "User data"
 // @Tags         Auth
 // @Accept       json
@@ -56,6 +62,7 @@ func (h *Handler) SignUp(c *gin.Context) {
 // @Success      200  {object}  schemas.LoginResponse
 // @Failure      401  {object}  handler.MessageJSON
 // @Failure      422  {object}  handler.MessageJSON
+// @Failure      423  {object}  handler.MessageJSON
 // @Router       /api/v1/auth/login [post].
 func (h *Handler) Login(c *gin.Context) {
 	ctx, span := trace.NewSpan(c.Request.Context(), "handler.login")
@@ -70,7 +77,57 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	payload.IPAddress = c.ClientIP()
+	payload.UserAgent = c.Request.UserAgent()
+
 	res, err := h.AuthSvc.Login(ctx, payload)
+	if errors.Is(err, auth.ErrAccountLocked) && res.LockedUntil != nil {
+		retryAfter := math.Ceil(time.Until(*res.LockedUntil).Seconds())
+
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter)))
+		c.AbortWithStatusJSON(http.StatusLocked, MessageJSON{Message: res.Message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Login Locked")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: res.Message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Login Unauthorized")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// LoginMFA godoc
+// @Summary      Verify login second factor
+// @Description  Exchange the mfa challenge of the login and a totp code or webauthn assertion for the tokens
+// @Param        payload  body  schemas.LoginMFA  true  "Challenge and code"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  schemas.LoginResponse
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/login/mfa [post].
+func (h *Handler) LoginMFA(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.login-mfa")
+	defer span.End()
+
+	var payload schemas.LoginMFA
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	res, err := h.AuthSvc.LoginMFA(ctx, payload)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: res.Message})
 		trace.AddSpanError(span, err)
@@ -84,7 +141,7 @@ func (h *Handler) Login(c *gin.Context) {
 
 // Logout godoc
 // @Summary      Logout user
-// @Description  Logout current user and expire access token
+// @Description  Logout current user and expire the access and refresh tokens of the session
 // @Param        Authorization  header  string  true  "Acess token"
 // @Tags         Auth
 // @Accept       json
@@ -99,9 +156,12 @@ func (h *Handler) Logout(c *gin.Context) {
 	ctxUserID, _ := c.Get("userID")
 	userID, _ := ctxUserID.(uuid.UUID)
 
-	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+	ctxSessionID, _ := c.Get("sessionID")
+	sessionID, _ := ctxSessionID.(uuid.UUID)
 
-	if err := h.AuthSvc.Logout(ctx, userID); err != nil {
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "session_id": sessionID.String()})
+
+	if err := h.AuthSvc.Logout(ctx, userID, sessionID); err != nil {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		trace.AddSpanError(span, err)
 		trace.FailSpan(span, "logout error")
@@ -176,7 +236,7 @@ func (h *Handler) Authorize(c *gin.Context) {
 		accessToken = payload.Token[idx+1:]
 	}
 
-	userID, err := h.AuthSvc.ValidateAccessToken(ctx, accessToken)
+	session, err := h.AuthSvc.ValidateAccessToken(ctx, accessToken)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: "Invalid token"})
 		trace.AddSpanError(span, err)
@@ -185,8 +245,8 @@ func (h *Handler) Authorize(c *gin.Context) {
 		return
 	}
 
-	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
-	c.Header(config.HeaderUserID, userID.String())
+	trace.AddSpanTags(span, map[string]string{"user_id": session.UserID.String()})
+	c.Header(config.HeaderUserID, session.UserID.String())
 	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
 }
 
@@ -234,6 +294,7 @@ func (h *Handler) SendRecoveryPasswordToken(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
 // @Success      401  {object}  handler.MessageJSON
 // @Failure      422  {object}  handler.MessageJSON
 // @Router       /api/v1/auth/reset-password [post].
@@ -251,6 +312,16 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 	}
 
 	err := h.AuthSvc.RecoveryPassword(ctx, payload.Token, payload.Password)
+
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: err.Error()})
 		trace.AddSpanError(span, err)
@@ -261,3 +332,51 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, MessageJSON{Message: "Password updated"})
 }
+
+// ChangeRequiredPassword godoc
+// @Summary      Change required password
+// @Description  Set a new password with the password change token returned by the login, when the password expired
+// @Description  or an admin forced its change. The user logs in again with the new password
+// @Param        payload  body  schemas.ChangeRequiredPassword  true  "Token and new password"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/change-password [post].
+func (h *Handler) ChangeRequiredPassword(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.change-required-password")
+	defer span.End()
+
+	var payload schemas.ChangeRequiredPassword
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	err := h.AuthSvc.ChangeRequiredPassword(ctx, payload)
+
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on change password")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "Password updated"})
+}
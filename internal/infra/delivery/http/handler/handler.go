@@ -1,13 +1,27 @@
 package handler
 
+import "github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+
 type Handler struct {
-	AuthSvc AuthenticationService
-	UserSvc UserService
+	AuthSvc    AuthenticationService
+	UserSvc    UserService
+	KeyRingSvc KeyRingService
+	ClientSvc  ClientService
+	OAuthCfg   config.OAuthConfig
 }
 
-func NewHandler(authenticationService AuthenticationService, userService UserService) *Handler {
+func NewHandler(
+	authenticationService AuthenticationService,
+	userService UserService,
+	keyRingService KeyRingService,
+	clientService ClientService,
+	oauthConfig config.OAuthConfig,
+) *Handler {
 	return &Handler{
-		AuthSvc: authenticationService,
-		UserSvc: userService,
+		AuthSvc:    authenticationService,
+		UserSvc:    userService,
+		KeyRingSvc: keyRingService,
+		ClientSvc:  clientService,
+		OAuthCfg:   oauthConfig,
 	}
 }
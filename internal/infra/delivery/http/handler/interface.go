@@ -2,30 +2,104 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 )
 
 type AuthenticationService interface {
 	RefreshAccessToken(ctx context.Context, refreshToken schemas.RefreshToken) (schemas.LoginResponse, error)
-	ValidateAccessToken(ctx context.Context, token string) (uuid.UUID, error)
+	ValidateAccessToken(ctx context.Context, token string) (entity.Session, error)
 	SignUp(ctx context.Context, payload schemas.SignUp) (*entity.User, error)
+	UpdateProfile(ctx context.Context, userID uuid.UUID, payload schemas.UpdateProfilePayload) (*entity.User, error)
 	Login(ctx context.Context, payload schemas.Login) (schemas.LoginResponse, error)
-	Logout(ctx context.Context, id uuid.UUID) error
+	Logout(ctx context.Context, userID, sessionID uuid.UUID) error
 	SendRecoveryPasswordToken(ctx context.Context, payload schemas.SendRecoveryPasswordPayload) error
 	RecoveryPassword(ctx context.Context, token, password string) error
+	ChangeRequiredPassword(ctx context.Context, payload schemas.ChangeRequiredPassword) error
+	ForcePasswordChange(ctx context.Context, userID uuid.UUID) error
+	ChangePassword(ctx context.Context, userID, sessionID uuid.UUID, payload schemas.ChangePasswordPayload) error
+	SendMagicLink(ctx context.Context, payload schemas.SendMagicLinkPayload) error
+	LoginMagicLink(ctx context.Context, payload schemas.MagicLinkLogin) (schemas.LoginResponse, error)
+	SendEmailVerificationToken(ctx context.Context, payload schemas.SendEmailVerificationPayload) error
+	VerifyEmail(ctx context.Context, token string) error
+	SendPhoneVerificationCode(ctx context.Context, userID uuid.UUID) error
+	VerifyPhone(ctx context.Context, userID uuid.UUID, code string) error
+	JWKS() auth.JWKS
+	UnlockAccount(ctx context.Context, userID uuid.UUID) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]entity.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error
+	IntrospectToken(ctx context.Context, token, tokenTypeHint string) schemas.Introspection
+	RevokeToken(ctx context.Context, clientID, token, tokenTypeHint string) error
+	Authorize(
+		ctx context.Context, client entity.Client, userID uuid.UUID, request schemas.AuthorizeRequest,
+	) (string, error)
+	StartAuthorizationSession(ctx context.Context, accessToken string) (string, time.Time, error)
+	ValidateAuthorizationSession(ctx context.Context, secret string) (entity.Session, error)
+	RequestConsent(
+		ctx context.Context, client entity.Client, userID uuid.UUID, request schemas.AuthorizeRequest,
+	) (schemas.ConsentRequest, error)
+	ConsumeConsent(ctx context.Context, userID uuid.UUID, challenge string) (schemas.AuthorizeRequest, error)
+	ExchangeAuthorizationCode(
+		ctx context.Context, client entity.Client, request schemas.TokenRequest,
+	) (schemas.LoginResponse, error)
+	RefreshClientToken(ctx context.Context, client entity.Client, refreshToken string) (schemas.LoginResponse, error)
+	IssueClientToken(ctx context.Context, client entity.Client, scope string) (schemas.LoginResponse, error)
+	UserInfo(ctx context.Context, session entity.Session) (schemas.UserInfo, error)
+	LoginMFA(ctx context.Context, payload schemas.LoginMFA) (schemas.LoginResponse, error)
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (schemas.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error
+	BeginWebAuthnRegistration(
+		ctx context.Context, userID uuid.UUID, payload schemas.ReAuthentication,
+	) (schemas.WebAuthnCreationOptions, error)
+	FinishWebAuthnRegistration(
+		ctx context.Context, userID uuid.UUID, payload schemas.WebAuthnRegistration,
+	) (entity.WebAuthnCredential, []string, error)
+	ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]entity.WebAuthnCredential, error)
+	DeleteWebAuthnCredential(ctx context.Context, userID uuid.UUID, id string, payload schemas.ReAuthentication) error
+	BeginWebAuthnLogin(ctx context.Context) (schemas.WebAuthnRequestOptions, error)
+	FinishWebAuthnLogin(ctx context.Context, payload schemas.WebAuthnLogin) (schemas.LoginResponse, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error)
+	OpenIDConfiguration() schemas.OpenIDConfiguration
 }
 
 type UserService interface {
 	Get(ctx context.Context, id uuid.UUID) (user entity.User, err error)
 	GetByEmail(ctx context.Context, email string) (user entity.User, err error)
 	Create(ctx context.Context, user entity.User) error
-	Update(ctx context.Context, id uuid.UUID, payload schemas.UpdateUserPayload) (*entity.User, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+type KeyRingService interface {
+	Rotate(ctx context.Context) (entity.SigningKey, error)
+}
+
+type ClientService interface {
+	Create(ctx context.Context, payload schemas.CreateClientPayload) (entity.Client, string, error)
+	Get(ctx context.Context, id string) (entity.Client, error)
+	Authenticate(ctx context.Context, id, secret string) (entity.Client, error)
+}
+
 type MessageJSON struct {
 	Message string `json:"message"`
+	// Validation errors grouped by field, so each failed requirement can be shown
+	Errors map[string][]string `json:"errors,omitempty"`
+	// Codes of the failed rules grouped by field, like the rules of the password policy
+	Rules map[string][]string `json:"rules,omitempty"`
+}
+
+// newErrorJSON adds the errors of each field when err is a validation error.
+func newErrorJSON(err error) MessageJSON {
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		return MessageJSON{Message: validationErr.Message, Errors: validationErr.Fields, Rules: validationErr.Rules}
+	}
+
+	return MessageJSON{Message: err.Error()}
 }
@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary      Get public signing keys
+// @Description  Return the JSON Web Key Set used to verify issued tokens, the resource servers must also check the
+// @Description  iss and aud claims and only accept token_use=access, or client_access for the client credentials
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  auth.JWKS
+// @Router       /.well-known/jwks.json [get].
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.AuthSvc.JWKS())
+}
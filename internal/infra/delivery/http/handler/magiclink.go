@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// SendMagicLink godoc
+// @Summary      Send magic link
+// @Description  Send a single use login token to the email of the user, it expires in 15 minutes
+// @Param        payload  body  schemas.SendMagicLinkPayload  true  "User email"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      202  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/login/magic-link [post].
+func (h *Handler) SendMagicLink(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.send-magic-link")
+	defer span.End()
+
+	var payload schemas.SendMagicLinkPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	if err := h.AuthSvc.SendMagicLink(ctx, payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on send magic link")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, MessageJSON{Message: "Accepted"})
+}
+
+// LoginMagicLink godoc
+// @Summary      Login with magic link
+// @Description  Exchange the token of the magic link for the same response of the password login, the token
+// @Description  only works once
+// @Param        payload  body  schemas.MagicLinkLogin  true  "Login token"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  schemas.LoginResponse
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/login/magic-link/consume [post].
+func (h *Handler) LoginMagicLink(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.login-magic-link")
+	defer span.End()
+
+	var payload schemas.MagicLinkLogin
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	payload.IPAddress = c.ClientIP()
+	payload.UserAgent = c.Request.UserAgent()
+
+	res, err := h.AuthSvc.LoginMagicLink(ctx, payload)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: res.Message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Login Unauthorized")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// EnrollTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and the provisioning uri to be shown as QR code, it must be confirmed
+// @Param        Authorization  header  string  true  "Bearer token"
+// @Tags         User
+// @Produce      json
+// @Success      200  {object}  schemas.TOTPEnrollment
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/mfa/totp [post].
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.enroll-totp")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	enrollment, err := h.AuthSvc.EnrollTOTP(ctx, userID)
+	if err != nil {
+		status, message := mfaErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Enable the TOTP second factor with a code generated by the authenticator app, the recovery
+// @Description  codes are returned when it's the first second factor of the user
+// @Param        Authorization  header  string            true  "Bearer token"
+// @Param        payload        body    schemas.TOTPCode  true  "TOTP code"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  schemas.RecoveryCodes
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/mfa/totp/confirm [post].
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.confirm-totp")
+	defer span.End()
+
+	var payload schemas.TOTPCode
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	recoveryCodes, err := h.AuthSvc.ConfirmTOTP(ctx, userID, payload.Code)
+	if err != nil {
+		status, message := mfaErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, schemas.RecoveryCodes{RecoveryCodes: recoveryCodes})
+}
+
+// DisableTOTP godoc
+// @Summary      Disable TOTP
+// @Description  Disable the TOTP second factor, a valid code is required
+// @Param        Authorization  header  string            true  "Bearer token"
+// @Param        payload        body    schemas.TOTPCode  true  "TOTP code"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/mfa/totp/disable [post].
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.disable-totp")
+	defer span.End()
+
+	var payload schemas.TOTPCode
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	if err := h.AuthSvc.DisableTOTP(ctx, userID, payload.Code); err != nil {
+		status, message := mfaErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace the recovery codes of the current user, the previous codes stop working
+// @Param        Authorization  header  string  true  "Bearer token"
+// @Tags         User
+// @Produce      json
+// @Success      200  {object}  schemas.RecoveryCodes
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/mfa/recovery-codes [post].
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.regenerate-recovery-codes")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	recoveryCodes, err := h.AuthSvc.RegenerateRecoveryCodes(ctx, userID)
+	if err != nil {
+		status, message := mfaErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, schemas.RecoveryCodes{RecoveryCodes: recoveryCodes})
+}
+
+func mfaErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidMFACode), errors.Is(err, auth.ErrMFANotEnabled):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, auth.ErrMFAAlreadyEnabled):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, auth.ErrTooManyRequests):
+		return http.StatusTooManyRequests, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to update mfa settings"
+	}
+}
@@ -0,0 +1,500 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	authorizationSessionCookie     = "authorization_session"
+	authorizationSessionCookiePath = "/oauth"
+	authorizePath                  = "/oauth/authorize"
+)
+
+// OAuthErrorJSON is the error response defined by RFC 6749 section 5.2.
+type OAuthErrorJSON struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// IntrospectToken godoc
+// @Summary      Introspect token
+// @Description  Return the state of an access or refresh token as RFC 7662, the client must be authenticated
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200  {object}  schemas.Introspection
+// @Failure      400  {object}  handler.OAuthErrorJSON
+// @Failure      401  {object}  handler.OAuthErrorJSON
+// @Router       /oauth/introspect [post].
+func (h *Handler) IntrospectToken(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.introspect-token")
+	defer span.End()
+
+	c.Header("Cache-Control", "no-store")
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request", Description: "token is required"},
+		)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"client_id": c.GetString("clientID")})
+
+	c.JSON(http.StatusOK, h.AuthSvc.IntrospectToken(ctx, token, c.PostForm("token_type_hint")))
+}
+
+// RevokeToken godoc
+// @Summary      Revoke token
+// @Description  Revoke an access or refresh token as RFC 7009, revoking a refresh token ends its session
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token"
+// @Param        token_type_hint  formData  string  false  "access_token or refresh_token"
+// @Success      200
+// @Failure      400  {object}  handler.OAuthErrorJSON
+// @Failure      401  {object}  handler.OAuthErrorJSON
+// @Failure      500  {object}  handler.OAuthErrorJSON
+// @Router       /oauth/revoke [post].
+func (h *Handler) RevokeToken(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.revoke-token")
+	defer span.End()
+
+	token := c.PostForm("token")
+	if token == "" {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request", Description: "token is required"},
+		)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	clientID := c.GetString("clientID")
+	trace.AddSpanTags(span, map[string]string{"client_id": clientID})
+
+	err := h.AuthSvc.RevokeToken(ctx, clientID, token, c.PostForm("token_type_hint"))
+	if errors.Is(err, auth.ErrClientMismatch) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newOAuthError(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unauthorized client")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, OAuthErrorJSON{Error: "server_error"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to revoke token")
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// AuthorizeClient godoc
+// @Summary      Authorize client
+// @Description  Start the authorization of the client, PKCE S256 is required. The browser is redirected to the
+// @Description  login page while it has no session cookie, and then to the consent page so the user approves it
+// @Param        response_type          query   string  true   "code"
+// @Param        client_id              query   string  true   "Client ID"
+// @Param        redirect_uri           query   string  true   "Registered redirect uri"
+// @Param        code_challenge         query   string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query   string  true   "S256"
+// @Param        scope                  query   string  false  "Requested scope"
+// @Param        state                  query   string  false  "Opaque value sent back to the client"
+// @Tags         OAuth
+// @Produce      json
+// @Success      302
+// @Failure      400  {object}  handler.OAuthErrorJSON
+// @Router       /oauth/authorize [get].
+func (h *Handler) AuthorizeClient(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.authorize-client")
+	defer span.End()
+
+	var request schemas.AuthorizeRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"client_id": request.ClientID})
+
+	client, ok := h.authorizationClient(ctx, c, request)
+	if !ok {
+		return
+	}
+
+	cookie, _ := c.Cookie(authorizationSessionCookie)
+
+	session, err := h.AuthSvc.ValidateAuthorizationSession(ctx, cookie)
+	if err != nil {
+		// The login page exchanges the access token for the session cookie, that sends the browser back here.
+		c.Redirect(
+			http.StatusFound,
+			withQuery(h.OAuthCfg.LoginURL, url.Values{"return_to": {c.Request.URL.RequestURI()}}),
+		)
+
+		return
+	}
+
+	consent, err := h.AuthSvc.RequestConsent(ctx, client, session.UserID, request)
+	if err != nil {
+		redirectAuthorizationError(c, request, err)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Authorization denied")
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, withQuery(h.OAuthCfg.ConsentURL, url.Values{
+		"consent_challenge": {consent.Challenge},
+		"client_id":         {consent.ClientID},
+		"client_name":       {consent.ClientName},
+		"scope":             {consent.Scope},
+	}))
+}
+
+// StartAuthorizationSession godoc
+// @Summary      Start authorization session
+// @Description  Exchange the access token of the user for the session cookie of the authorize endpoint, it's
+// @Description  posted by the login page, that sends the browser back to the authorize endpoint
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Param        access_token  formData  string  true  "Access token of the user"
+// @Param        return_to     formData  string  true  "Authorize uri the login page was opened with"
+// @Success      303
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Router       /oauth/session [post].
+func (h *Handler) StartAuthorizationSession(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.start-authorization-session")
+	defer span.End()
+
+	var payload schemas.AuthorizationSessionRequest
+	if err := c.ShouldBind(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	// Only the authorize endpoint of this service is accepted, otherwise it would be an open redirect.
+	if !isAuthorizeURI(payload.ReturnTo) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "return_to must be the authorize uri"})
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	// Only the login page can start a session, so other sites can't log the browser in with their own token.
+	if origin := c.GetHeader("Origin"); origin != "" && !sameOrigin(origin, h.OAuthCfg.LoginURL) {
+		c.AbortWithStatusJSON(http.StatusForbidden, MessageJSON{Message: "Origin is not allowed"})
+		trace.FailSpan(span, "Forbidden")
+
+		return
+	}
+
+	secret, expiresAt, err := h.AuthSvc.StartAuthorizationSession(ctx, payload.AccessToken)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: "Invalid token"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unauthorized")
+
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     authorizationSessionCookie,
+		Value:    secret,
+		Path:     authorizationSessionCookiePath,
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   h.OAuthCfg.SessionCookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.Redirect(http.StatusSeeOther, payload.ReturnTo)
+}
+
+// Consent godoc
+// @Summary      Consent authorization
+// @Description  Approve or deny the authorization requested to the user, it's posted by the consent page with
+// @Description  the session cookie. The browser is redirected back to the client with the code or the error
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Param        consent_challenge  formData  string  true   "Challenge sent to the consent page"
+// @Param        approve            formData  bool    false  "If the user approved the client"
+// @Success      302
+// @Failure      400  {object}  handler.OAuthErrorJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Router       /oauth/consent [post].
+func (h *Handler) Consent(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.consent")
+	defer span.End()
+
+	var payload schemas.Consent
+	if err := c.ShouldBind(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	// The cookie is SameSite, so it isn't sent when other sites post the form.
+	cookie, _ := c.Cookie(authorizationSessionCookie)
+
+	session, err := h.AuthSvc.ValidateAuthorizationSession(ctx, cookie)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: "Authorization session not found"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unauthorized")
+
+		return
+	}
+
+	request, err := h.AuthSvc.ConsumeConsent(ctx, session.UserID, payload.Challenge)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newOAuthError(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	trace.AddSpanTags(span, map[string]string{"client_id": request.ClientID})
+
+	client, ok := h.authorizationClient(ctx, c, request)
+	if !ok {
+		return
+	}
+
+	if !payload.Approve {
+		redirectAuthorizationError(c, request, auth.ErrAccessDenied)
+		trace.FailSpan(span, "Authorization denied")
+
+		return
+	}
+
+	code, err := h.AuthSvc.Authorize(ctx, client, session.UserID, request)
+	if err != nil {
+		redirectAuthorizationError(c, request, err)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Authorization denied")
+
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	c.Redirect(http.StatusFound, withQuery(request.RedirectURI, params))
+}
+
+// authorizationClient returns the client of the authorization request. Errors about the client or the redirect
+// uri can't be sent to the redirect uri, it may not be trusted.
+func (h *Handler) authorizationClient(
+	ctx context.Context, c *gin.Context, request schemas.AuthorizeRequest,
+) (entity.Client, bool) {
+	span := trace.SpanFromContext(ctx)
+
+	client, err := h.ClientSvc.Get(ctx, request.ClientID)
+	if err != nil {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request", Description: "unknown client"},
+		)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return entity.Client{}, false
+	}
+
+	if !client.HasRedirectURI(request.RedirectURI) {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			OAuthErrorJSON{Error: "invalid_request", Description: "redirect_uri is not registered for the client"},
+		)
+		trace.FailSpan(span, "Bad request")
+
+		return entity.Client{}, false
+	}
+
+	return client, true
+}
+
+// redirectAuthorizationError sends the error back to the client, the redirect uri must have been validated.
+func redirectAuthorizationError(c *gin.Context, request schemas.AuthorizeRequest, err error) {
+	errorResponse := newOAuthError(err)
+
+	params := url.Values{"error": {errorResponse.Error}}
+	if errorResponse.Description != "" {
+		params.Set("error_description", errorResponse.Description)
+	}
+
+	if request.State != "" {
+		params.Set("state", request.State)
+	}
+
+	c.Redirect(http.StatusFound, withQuery(request.RedirectURI, params))
+}
+
+// Token godoc
+// @Summary      Exchange grant for tokens
+// @Description  Exchange an authorization code or a refresh token for a new pair of tokens, or issue a token
+// @Description  to the client itself with the client credentials grant
+// @Tags         OAuth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code, refresh_token or client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect uri of the authorization request"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Scope requested by the client credentials grant"
+// @Success      200  {object}  schemas.LoginResponse
+// @Failure      400  {object}  handler.OAuthErrorJSON
+// @Failure      401  {object}  handler.OAuthErrorJSON
+// @Failure      500  {object}  handler.OAuthErrorJSON
+// @Router       /oauth/token [post].
+func (h *Handler) Token(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.token")
+	defer span.End()
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var request schemas.TokenRequest
+	if err := c.ShouldBind(&request); err != nil {
+		c.AbortWithStatusJSON(
+			http.StatusBadRequest, OAuthErrorJSON{Error: "invalid_request", Description: "grant_type is required"},
+		)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	request.IPAddress = c.ClientIP()
+	request.UserAgent = c.Request.UserAgent()
+
+	ctxClient, _ := c.Get("client")
+	client, _ := ctxClient.(entity.Client)
+
+	trace.AddSpanTags(span, map[string]string{"client_id": client.ID, "grant_type": request.GrantType})
+
+	var (
+		response schemas.LoginResponse
+		err      error
+	)
+
+	switch request.GrantType {
+	case auth.GrantTypeAuthorizationCode:
+		response, err = h.AuthSvc.ExchangeAuthorizationCode(ctx, client, request)
+	case auth.GrantTypeRefreshToken:
+		response, err = h.AuthSvc.RefreshClientToken(ctx, client, request.RefreshToken)
+	case auth.GrantTypeClientCredentials:
+		response, err = h.AuthSvc.IssueClientToken(ctx, client, request.Scope)
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, OAuthErrorJSON{Error: "unsupported_grant_type"})
+		trace.FailSpan(span, "Unsupported grant type")
+
+		return
+	}
+
+	if err != nil {
+		errorResponse := newOAuthError(err)
+
+		status := http.StatusBadRequest
+		if errorResponse.Error == "server_error" {
+			status = http.StatusInternalServerError
+		}
+
+		c.AbortWithStatusJSON(status, errorResponse)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, errorResponse.Error)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// newOAuthError translates the service errors to the error codes of RFC 6749.
+func newOAuthError(err error) OAuthErrorJSON {
+	switch {
+	case errors.Is(err, auth.ErrInvalidRequest):
+		return OAuthErrorJSON{Error: "invalid_request", Description: err.Error()}
+	case errors.Is(err, auth.ErrInvalidGrant):
+		return OAuthErrorJSON{Error: "invalid_grant", Description: err.Error()}
+	case errors.Is(err, auth.ErrUnsupportedResponseType):
+		return OAuthErrorJSON{Error: "unsupported_response_type", Description: err.Error()}
+	case errors.Is(err, auth.ErrClientMismatch), errors.Is(err, auth.ErrUnauthorizedClient):
+		return OAuthErrorJSON{Error: "unauthorized_client", Description: err.Error()}
+	case errors.Is(err, auth.ErrInvalidScope):
+		return OAuthErrorJSON{Error: "invalid_scope", Description: err.Error()}
+	case errors.Is(err, auth.ErrAccessDenied):
+		return OAuthErrorJSON{Error: "access_denied", Description: err.Error()}
+	default:
+		return OAuthErrorJSON{Error: "server_error"}
+	}
+}
+
+func withQuery(rawURL string, params url.Values) string {
+	uri, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := uri.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+
+	uri.RawQuery = query.Encode()
+
+	return uri.String()
+}
+
+// isAuthorizeURI only accepts relative uris to the authorize endpoint.
+func isAuthorizeURI(rawURL string) bool {
+	uri, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return uri.Scheme == "" && uri.Host == "" && uri.Path == authorizePath && !strings.HasPrefix(rawURL, "//")
+}
+
+func sameOrigin(origin, rawURL string) bool {
+	uri, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return origin == uri.Scheme+"://"+uri.Host
+}
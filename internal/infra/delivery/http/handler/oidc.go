@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// OpenIDConfiguration godoc
+// @Summary      Get OpenID Connect discovery document
+// @Description  Return the provider metadata used by OpenID Connect clients to integrate with the service
+// @Tags         OAuth
+// @Produce      json
+// @Success      200  {object}  schemas.OpenIDConfiguration
+// @Router       /.well-known/openid-configuration [get].
+func (h *Handler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.AuthSvc.OpenIDConfiguration())
+}
+
+// UserInfo godoc
+// @Summary      Get OpenID Connect user info
+// @Description  Return the claims about the user allowed by the scope of the access token
+// @Param        Authorization  header  string  true  "Acess token"
+// @Tags         OAuth
+// @Produce      json
+// @Success      200  {object}  schemas.UserInfo
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.OAuthErrorJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /userinfo [get].
+func (h *Handler) UserInfo(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.user-info")
+	defer span.End()
+
+	ctxSession, _ := c.Get("session")
+	session, _ := ctxSession.(entity.Session)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": session.UserID.String()})
+
+	info, err := h.AuthSvc.UserInfo(ctx, session)
+	if errors.Is(err, auth.ErrInsufficientScope) {
+		c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		c.AbortWithStatusJSON(
+			http.StatusForbidden, OAuthErrorJSON{Error: "insufficient_scope", Description: err.Error()},
+		)
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Insufficient scope")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to get user info"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to get user info")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+type SessionJSON struct {
+	entity.Session
+	Current bool `json:"current"`
+}
+
+func newSessionsJSON(sessions []entity.Session, currentSessionID uuid.UUID) []SessionJSON {
+	res := make([]SessionJSON, 0, len(sessions))
+
+	for _, session := range sessions {
+		res = append(res, SessionJSON{Session: session, Current: session.ID == currentSessionID})
+	}
+
+	return res
+}
+
+// revokeSession is shared by the user and admin routes.
+func (h *Handler) revokeSession(ctx context.Context, c *gin.Context, userID uuid.UUID, rawSessionID string) {
+	span := trace.SpanFromContext(ctx)
+
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Invalid session id"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	err = h.AuthSvc.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Session not found")
+
+		return
+	}
+
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to revoke session"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to revoke session")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
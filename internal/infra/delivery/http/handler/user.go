@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
 )
 
@@ -41,16 +44,20 @@ func (h *Handler) GetMe(c *gin.Context) {
 }
 
 // SignUp godoc
-// @Summary  Update current user data
-// @Param    Authorization  header  string  true  "Bearer token"
-// @Tags     User
-// @Accept   json
-// @produce  json
-// @Success  200  {object}  entity.User
-// @Failure  400  {object}  handler.MessageJSON
-// @Failure  401  {object}  handler.MessageJSON
-// @Failure  422  {object}  handler.MessageJSON
-// @Router   /api/v1/user/me [post].
+// @Summary      Update current user data
+// @Description  The current password is required to change the email, the old address is notified of the change
+// @Param        Authorization  header  string                        true  "Bearer token"
+// @Param        payload        body    schemas.UpdateProfilePayload  true  "Fields to change, the empty ones are kept"
+// @Tags         User
+// @Accept       json
+// @produce      json
+// @Success      200  {object}  entity.User
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      423  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me [post].
 func (h *Handler) UpdateMe(c *gin.Context) {
 	ctx, span := trace.NewSpan(c.Request.Context(), "handler.update-me")
 	defer span.End()
@@ -60,7 +67,7 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 
 	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
 
-	var payload schemas.UpdateUserPayload
+	var payload schemas.UpdateProfilePayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
 		trace.AddSpanError(span, err)
@@ -69,9 +76,20 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 		return
 	}
 
-	user, err := h.UserSvc.Update(ctx, userID, payload)
+	user, err := h.AuthSvc.UpdateProfile(ctx, userID, payload)
+
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, MessageJSON{Message: "Failed to update user"})
+		status, message := updateProfileErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
 		trace.AddSpanError(span, err)
 		trace.FailSpan(span, "Failed to update user data")
 
@@ -81,6 +99,90 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// ChangeMyPassword godoc
+// @Summary      Change current user password
+// @Description  Replace the password after verifying the current one, the other sessions of the user are revoked
+// @Param        Authorization  header  string                          true  "Bearer token"
+// @Param        payload        body    schemas.ChangePasswordPayload  true  "Current and new password"
+// @Tags         User
+// @Accept       json
+// @produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      423  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/password [post].
+func (h *Handler) ChangeMyPassword(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.change-my-password")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	ctxSessionID, _ := c.Get("sessionID")
+	sessionID, _ := ctxSessionID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "session_id": sessionID.String()})
+
+	var payload schemas.ChangePasswordPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	err := h.AuthSvc.ChangePassword(ctx, userID, sessionID, payload)
+
+	var validationErr *entity.ValidationError
+	if errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, newErrorJSON(err))
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Bad request")
+
+		return
+	}
+
+	if err != nil {
+		status, message := changePasswordErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "Password updated"})
+}
+
+func updateProfileErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrReAuthRequired):
+		return http.StatusBadRequest, "The current password is required to change the email"
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, auth.ErrAccountLocked):
+		return http.StatusLocked, err.Error()
+	default:
+		return http.StatusBadRequest, "Failed to update user"
+	}
+}
+
+func changePasswordErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, auth.ErrAccountLocked):
+		return http.StatusLocked, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to change password"
+	}
+}
+
 // SignUp godoc
 // @Summary  Delete current user
 // @Param    Authorization  header  string  true  "Bearer token"
@@ -109,3 +211,95 @@ func (h *Handler) DeleteMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
 }
+
+// ListMySessions godoc
+// @Summary  List current user sessions
+// @Param    Authorization  header  string  true  "Bearer token"
+// @Tags     User
+// @Accept   json
+// @produce  json
+// @Success  200  {array}   handler.SessionJSON
+// @Failure  401  {object}  handler.MessageJSON
+// @Failure  500  {object}  handler.MessageJSON
+// @Router   /api/v1/user/me/sessions [get].
+func (h *Handler) ListMySessions(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.list-my-sessions")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	ctxSessionID, _ := c.Get("sessionID")
+	sessionID, _ := ctxSessionID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	sessions, err := h.AuthSvc.ListSessions(ctx, userID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to list sessions"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to list sessions")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, newSessionsJSON(sessions, sessionID))
+}
+
+// RevokeMySession godoc
+// @Summary  Revoke a session of the current user
+// @Param    Authorization  header  string  true  "Bearer token"
+// @Param    id             path    string  true  "Session ID"
+// @Tags     User
+// @Accept   json
+// @produce  json
+// @Success  200  {object}  handler.MessageJSON
+// @Failure  400  {object}  handler.MessageJSON
+// @Failure  401  {object}  handler.MessageJSON
+// @Failure  404  {object}  handler.MessageJSON
+// @Router   /api/v1/user/me/sessions/{id} [delete].
+func (h *Handler) RevokeMySession(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.revoke-my-session")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "session_id": c.Param("id")})
+
+	h.revokeSession(ctx, c, userID, c.Param("id"))
+}
+
+// RevokeMyOtherSessions godoc
+// @Summary      Log out everywhere else
+// @Description  Revoke every session of the current user except the one making the request
+// @Param        Authorization  header  string  true  "Bearer token"
+// @Tags         User
+// @Accept       json
+// @produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/sessions/revoke-others [post].
+func (h *Handler) RevokeMyOtherSessions(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.revoke-my-other-sessions")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	ctxSessionID, _ := c.Get("sessionID")
+	sessionID, _ := ctxSessionID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "session_id": sessionID.String()})
+
+	if err := h.AuthSvc.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to revoke sessions"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to revoke sessions")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
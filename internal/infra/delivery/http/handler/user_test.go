@@ -0,0 +1,163 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/delivery/http/handler"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/repository"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	authservice "github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/user"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/cache"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
+)
+
+const (
+	testName     = "Maria Silva"
+	testPassword = "current-password"
+	testEmail    = "maria@example.com"
+	testPhone    = "5521987654321"
+	testNewEmail = "ana@example.com"
+)
+
+func newUpdateMeRouter(t *testing.T) (*gin.Engine, *repository.UserRepository, entity.User) {
+	t.Helper()
+
+	db, err := database.NewSQLiteMemoryConnection()
+	assert.NoError(t, err)
+	assert.NoError(t, repository.AutoMigrate(db))
+
+	cacheClient, err := cache.NewMemoryCacheClient()
+	assert.NoError(t, err)
+
+	eventChannel := make(chan schemas.Event, 10)
+
+	userRepository := repository.NewUserRepository(db)
+	userService := user.NewService(
+		userRepository, repository.NewPasswordHistoryRepository(db), auth.DefaultPasswordPolicy(), eventChannel,
+	)
+	authService := authservice.NewService(
+		userService, repository.NewWebAuthnCredentialRepository(db), cacheClient,
+		auth.NewKeyRing(auth.NewHMACKey("", "test-secret-key")), authservice.Config{}, eventChannel,
+	)
+
+	current, err := entity.NewUser(testName, testEmail, testPhone, testPassword, auth.DefaultPasswordPolicy())
+	assert.NoError(t, err)
+	assert.NoError(t, userRepository.Create(context.TODO(), current))
+
+	h := handler.NewHandler(authService, userService, nil, nil, config.OAuthConfig{})
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/me", func(c *gin.Context) { c.Set("userID", current.ID) }, h.UpdateMe)
+
+	return router, userRepository, current
+}
+
+func TestUpdateMe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario       string
+		body           string
+		expectedStatus int
+		expectedName   string
+		expectedEmail  string
+	}{
+		{
+			scenario:       "when only the name is sent",
+			body:           `{"name": "Ana"}`,
+			expectedStatus: http.StatusOK,
+			expectedName:   strings.ToTitle("Ana"),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when only the password is sent",
+			body:           `{"password": "new-password"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedName:   strings.ToTitle(testName),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when the password is sent with the name",
+			body:           `{"name": "Ana", "password": "new-password"}`,
+			expectedStatus: http.StatusOK,
+			expectedName:   strings.ToTitle("Ana"),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when the body isn't json",
+			body:           `name=Ana`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedName:   strings.ToTitle(testName),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when the email is changed without the current password",
+			body:           `{"email": "` + testNewEmail + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedName:   strings.ToTitle(testName),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when the email is changed with a wrong password",
+			body:           `{"email": "` + testNewEmail + `", "current_password": "wrong-password"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedName:   strings.ToTitle(testName),
+			expectedEmail:  testEmail,
+		},
+		{
+			scenario:       "when the email is changed with the current password",
+			body:           `{"email": "` + testNewEmail + `", "current_password": "` + testPassword + `"}`,
+			expectedStatus: http.StatusOK,
+			expectedName:   strings.ToTitle(testName),
+			expectedEmail:  testNewEmail,
+		},
+		{
+			scenario:       "when the same email is sent without the current password",
+			body:           `{"name": "Ana", "email": "` + testEmail + `"}`,
+			expectedStatus: http.StatusOK,
+			expectedName:   strings.ToTitle("Ana"),
+			expectedEmail:  testEmail,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			router, userRepository, current := newUpdateMeRouter(t)
+
+			req := httptest.NewRequest(http.MethodPost, "/me", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			response := httptest.NewRecorder()
+
+			// Action
+			router.ServeHTTP(response, req)
+
+			// Assert
+			assert.Equal(t, tc.expectedStatus, response.Code)
+
+			stored, err := userRepository.Get(context.TODO(), current.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedName, stored.Name)
+			assert.Equal(t, tc.expectedEmail, stored.Email)
+			assert.Equal(t, testPhone, stored.Phone, "the fields that weren't sent are kept")
+			assert.True(t, stored.ValidatePassword(testPassword), "the password is only changed by its own endpoint")
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// SendEmailVerificationToken godoc
+// @Summary      Resend email verification token
+// @Description  Send a new token to verify the email, it can be requested once per minute
+// @Param        payload  body  schemas.SendEmailVerificationPayload  true  "User email"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      202  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/verify-email/resend [post].
+func (h *Handler) SendEmailVerificationToken(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.send-email-verification-token")
+	defer span.End()
+
+	var payload schemas.SendEmailVerificationPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	err := h.AuthSvc.SendEmailVerificationToken(ctx, payload)
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, auth.ErrEmailVerified):
+			status = http.StatusConflict
+		case errors.Is(err, auth.ErrTooManyRequests):
+			status = http.StatusTooManyRequests
+		}
+
+		c.AbortWithStatusJSON(status, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on send email verification token")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, MessageJSON{Message: "Accepted"})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email
+// @Description  Confirm the user owns the email with the token sent on the sign up
+// @Param        payload  body  schemas.VerifyEmailPayload  true  "Verification token"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/verify-email [post].
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.verify-email")
+	defer span.End()
+
+	var payload schemas.VerifyEmailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	if err := h.AuthSvc.VerifyEmail(ctx, payload.Token); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on verify email")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "Email verified"})
+}
+
+// SendPhoneVerificationCode godoc
+// @Summary      Send phone verification code
+// @Description  Send a code by SMS to verify the phone of the current user, it can be requested once per minute
+// @Param        Authorization  header  string  true  "Bearer token"
+// @Tags         User
+// @Produce      json
+// @Success      202  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/phone/verify/resend [post].
+func (h *Handler) SendPhoneVerificationCode(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.send-phone-verification-code")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	err := h.AuthSvc.SendPhoneVerificationCode(ctx, userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, auth.ErrPhoneVerified):
+			status = http.StatusConflict
+		case errors.Is(err, auth.ErrTooManyRequests):
+			status = http.StatusTooManyRequests
+		}
+
+		c.AbortWithStatusJSON(status, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on send phone verification code")
+
+		return
+	}
+
+	c.JSON(http.StatusAccepted, MessageJSON{Message: "Accepted"})
+}
+
+// VerifyPhone godoc
+// @Summary      Verify phone
+// @Description  Confirm the user owns the phone with the code sent by SMS, the code is dropped after 5 wrong
+// @Description  attempts and a new one must be requested
+// @Param        Authorization  header  string                      true  "Bearer token"
+// @Param        payload        body    schemas.VerifyPhonePayload  true  "Verification code"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/phone/verify [post].
+func (h *Handler) VerifyPhone(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.verify-phone")
+	defer span.End()
+
+	var payload schemas.VerifyPhonePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	if err := h.AuthSvc.VerifyPhone(ctx, userID, payload.Code); err != nil {
+		status := http.StatusInternalServerError
+
+		switch {
+		case errors.Is(err, auth.ErrInvalidPhoneCode):
+			status = http.StatusBadRequest
+		case errors.Is(err, auth.ErrPhoneVerified):
+			status = http.StatusConflict
+		}
+
+		c.AbortWithStatusJSON(status, MessageJSON{Message: err.Error()})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Error on verify phone")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "Phone verified"})
+}
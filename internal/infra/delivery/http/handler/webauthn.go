@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// WebAuthnCredentialJSON has the recovery codes when the credential is the first second factor of the user.
+type WebAuthnCredentialJSON struct {
+	entity.WebAuthnCredential
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// BeginWebAuthnRegistration godoc
+// @Summary      Start passkey registration
+// @Description  Return the options to create a passkey or security key with navigator.credentials.create, the user
+// @Description  must re-authenticate with the current password or a code of the authenticator app
+// @Param        Authorization  header  string                    true  "Bearer token"
+// @Param        payload        body    schemas.ReAuthentication  true  "Current password or totp code"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  schemas.WebAuthnCreationOptions
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      423  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/webauthn/register/begin [post].
+func (h *Handler) BeginWebAuthnRegistration(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.begin-webauthn-registration")
+	defer span.End()
+
+	var payload schemas.ReAuthentication
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	options, err := h.AuthSvc.BeginWebAuthnRegistration(ctx, userID, payload)
+	if err != nil {
+		status, message := webAuthnErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnRegistration godoc
+// @Summary      Finish passkey registration
+// @Description  Verify the response of the authenticator and register the credential
+// @Param        Authorization  header  string                        true  "Bearer token"
+// @Param        payload        body    schemas.WebAuthnRegistration  true  "Authenticator response"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      201  {object}  handler.WebAuthnCredentialJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      409  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/webauthn/register/finish [post].
+func (h *Handler) FinishWebAuthnRegistration(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.finish-webauthn-registration")
+	defer span.End()
+
+	var payload schemas.WebAuthnRegistration
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	credential, recoveryCodes, err := h.AuthSvc.FinishWebAuthnRegistration(ctx, userID, payload)
+	if err != nil {
+		status, message := webAuthnErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, WebAuthnCredentialJSON{WebAuthnCredential: credential, RecoveryCodes: recoveryCodes})
+}
+
+// ListMyWebAuthnCredentials godoc
+// @Summary      List passkeys
+// @Description  List the passkeys and security keys registered by the current user
+// @Param        Authorization  header  string  true  "Bearer token"
+// @Tags         User
+// @Produce      json
+// @Success      200  {array}   entity.WebAuthnCredential
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/webauthn/credentials [get].
+func (h *Handler) ListMyWebAuthnCredentials(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.list-my-webauthn-credentials")
+	defer span.End()
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String()})
+
+	credentials, err := h.AuthSvc.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to list credentials"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to list credentials")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// DeleteMyWebAuthnCredential godoc
+// @Summary      Delete passkey
+// @Description  Delete a passkey or security key of the current user, the user must re-authenticate with the
+// @Description  current password or a code of the authenticator app
+// @Param        Authorization  header  string                    true  "Bearer token"
+// @Param        id             path    string                    true  "Credential id"
+// @Param        payload        body    schemas.ReAuthentication  true  "Current password or totp code"
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  handler.MessageJSON
+// @Failure      400  {object}  handler.MessageJSON
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      403  {object}  handler.MessageJSON
+// @Failure      404  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Failure      423  {object}  handler.MessageJSON
+// @Failure      429  {object}  handler.MessageJSON
+// @Router       /api/v1/user/me/webauthn/credentials/{id} [delete].
+func (h *Handler) DeleteMyWebAuthnCredential(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.delete-my-webauthn-credential")
+	defer span.End()
+
+	var payload schemas.ReAuthentication
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	ctxUserID, _ := c.Get("userID")
+	userID, _ := ctxUserID.(uuid.UUID)
+
+	trace.AddSpanTags(span, map[string]string{"user_id": userID.String(), "credential_id": c.Param("id")})
+
+	if err := h.AuthSvc.DeleteWebAuthnCredential(ctx, userID, c.Param("id"), payload); err != nil {
+		status, message := webAuthnErrorStatus(err)
+		c.AbortWithStatusJSON(status, MessageJSON{Message: message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, message)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageJSON{Message: "ok"})
+}
+
+// BeginWebAuthnLogin godoc
+// @Summary      Start passwordless login
+// @Description  Return the options to sign in with a passkey using navigator.credentials.get
+// @Tags         Auth
+// @Produce      json
+// @Success      200  {object}  schemas.WebAuthnRequestOptions
+// @Failure      500  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/login/webauthn/begin [post].
+func (h *Handler) BeginWebAuthnLogin(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.begin-webauthn-login")
+	defer span.End()
+
+	options, err := h.AuthSvc.BeginWebAuthnLogin(ctx)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, MessageJSON{Message: "Failed to start login"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Failed to start login")
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishWebAuthnLogin godoc
+// @Summary      Finish passwordless login
+// @Description  Verify the passkey assertion and return the same tokens of the password login
+// @Param        payload  body  schemas.WebAuthnLogin  true  "Challenge and authenticator response"
+// @Tags         Auth
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  schemas.LoginResponse
+// @Failure      401  {object}  handler.MessageJSON
+// @Failure      422  {object}  handler.MessageJSON
+// @Router       /api/v1/auth/login/webauthn/finish [post].
+func (h *Handler) FinishWebAuthnLogin(c *gin.Context) {
+	ctx, span := trace.NewSpan(c.Request.Context(), "handler.finish-webauthn-login")
+	defer span.End()
+
+	var payload schemas.WebAuthnLogin
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, MessageJSON{Message: "Invalid Payload"})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Unprocessable entity")
+
+		return
+	}
+
+	payload.IPAddress = c.ClientIP()
+	payload.UserAgent = c.Request.UserAgent()
+
+	res, err := h.AuthSvc.FinishWebAuthnLogin(ctx, payload)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, MessageJSON{Message: res.Message})
+		trace.AddSpanError(span, err)
+		trace.FailSpan(span, "Login Unauthorized")
+
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func webAuthnErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidWebAuthnCredential):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, auth.ErrWebAuthnCredentialNotFound):
+		return http.StatusNotFound, auth.ErrWebAuthnCredentialNotFound.Error()
+	case errors.Is(err, auth.ErrWebAuthnCredentialAlreadyRegistered):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, auth.ErrReAuthRequired), errors.Is(err, auth.ErrInvalidMFACode):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, auth.ErrInvalidPassword):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, auth.ErrAccountLocked):
+		return http.StatusLocked, err.Error()
+	case errors.Is(err, auth.ErrTooManyRequests):
+		return http.StatusTooManyRequests, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to update webauthn credentials"
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// AdminMiddleware only lets requests with the admin api key through, admin routes are disabled when it's empty.
+func AdminMiddleware(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, span := trace.NewSpan(c.Request.Context(), "Middleware.Admin")
+		defer span.End()
+
+		key := c.GetHeader(config.HeaderAdminKey)
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"message": "Forbidden"})
+			trace.FailSpan(span, "Forbidden")
+
+			return
+		}
+	}
+}
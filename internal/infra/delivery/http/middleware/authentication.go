@@ -22,7 +22,7 @@ func AuthenticationMiddlware(service TokenService) gin.HandlerFunc {
 
 		token := authHeader[len(config.TokenSchema)+1:]
 
-		userID, err := service.ValidateAccessToken(ctx, token)
+		session, err := service.ValidateAccessToken(ctx, token)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"message": err.Error()})
 			trace.AddSpanError(span, err)
@@ -31,7 +31,9 @@ func AuthenticationMiddlware(service TokenService) gin.HandlerFunc {
 			return
 		}
 
-		c.Header(config.HeaderUserID, userID.String())
-		c.Set("userID", userID)
+		c.Header(config.HeaderUserID, session.UserID.String())
+		c.Set("userID", session.UserID)
+		c.Set("sessionID", session.ID)
+		c.Set("session", session)
 	}
 }
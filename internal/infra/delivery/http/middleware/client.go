@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// ClientAuthenticationMiddleware authenticates the oauth client with http basic or the client_id and
+// client_secret form fields, as described by RFC 6749 section 2.3.1. Public clients only send their id,
+// they're only accepted on the routes that allow them.
+func ClientAuthenticationMiddleware(service ClientService, allowPublic bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := trace.NewSpan(c.Request.Context(), "Middleware.ClientAuthentication")
+		defer span.End()
+
+		clientID, secret, ok := clientCredentials(c)
+		if !ok || clientID == "" || secret == "" && !allowPublic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			trace.FailSpan(span, "Unauthorized")
+
+			return
+		}
+
+		client, err := service.Authenticate(ctx, clientID, secret)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			trace.AddSpanError(span, err)
+			trace.FailSpan(span, "Unauthorized")
+
+			return
+		}
+
+		c.Set("clientID", client.ID)
+		c.Set("client", client)
+	}
+}
+
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return c.PostForm("client_id"), c.PostForm("client_secret"), true
+	}
+
+	// Basic credentials are form encoded before being joined.
+	clientID, err := url.QueryUnescape(clientID)
+	if err != nil {
+		return "", "", false
+	}
+
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return clientID, secret, true
+}
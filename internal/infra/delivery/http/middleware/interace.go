@@ -2,10 +2,20 @@ package middleware
 
 import (
 	"context"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 )
 
 type TokenService interface {
-	ValidateAccessToken(ctx context.Context, token string) (uuid.UUID, error)
+	ValidateAccessToken(ctx context.Context, token string) (entity.Session, error)
+}
+
+type ClientService interface {
+	Authenticate(ctx context.Context, id, secret string) (entity.Client, error)
+}
+
+type RateLimitStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
 }
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	rateLimitKeyPrefix = "rate-limit"
+
+	// The json bodies of the limited routes are small, larger ones aren't read to find the email.
+	rateLimitMaxBodySize = 1 << 20
+)
+
+// RateLimit allows Limit requests per Window for each value returned by Key, requests without a key, like the
+// ones without an email, aren't limited by it.
+type RateLimit struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    func(c *gin.Context) string
+}
+
+type rateLimitState struct {
+	limit     int
+	remaining int
+	reset     time.Duration
+}
+
+// RateLimitByIP counts the requests of the client address.
+func RateLimitByIP(limit int, window time.Duration) RateLimit {
+	return RateLimit{Name: "ip", Limit: limit, Window: window, Key: func(c *gin.Context) string { return c.ClientIP() }}
+}
+
+// RateLimitByEmail counts the requests for the email of the json body, whatever the address sending them.
+func RateLimitByEmail(limit int, window time.Duration) RateLimit {
+	return RateLimit{Name: "email", Limit: limit, Window: window, Key: requestEmail}
+}
+
+// RateLimitMiddleware applies the limits to each route separately, with a sliding window estimated from the
+// counters of the current and the previous fixed windows. The state of the most restrictive limit is sent in
+// the RateLimit headers, and a 429 with Retry-After when any of them is exceeded.
+func RateLimitMiddleware(store RateLimitStore, limits ...RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, span := trace.NewSpan(c.Request.Context(), "Middleware.RateLimit")
+		defer span.End()
+
+		var current *rateLimitState
+
+		for _, limit := range limits {
+			if limit.Limit <= 0 || limit.Window <= 0 {
+				continue
+			}
+
+			subject := limit.Key(c)
+			if subject == "" {
+				continue
+			}
+
+			now := time.Now()
+			window := now.UnixNano() / int64(limit.Window)
+			elapsed := time.Duration(now.UnixNano() % int64(limit.Window))
+			key := fmt.Sprintf("%s-%s-%s-%s", rateLimitKeyPrefix, limit.Name, c.FullPath(), subject)
+
+			// The previous window must be kept while the current one is open.
+			count, err := store.Incr(ctx, fmt.Sprintf("%s-%d", key, window), limit.Window*2)
+			if err != nil {
+				// The routes keep working when the store is unavailable.
+				trace.AddSpanError(span, err)
+
+				continue
+			}
+
+			data, _ := store.Get(ctx, fmt.Sprintf("%s-%d", key, window-1))
+			previous, _ := strconv.ParseInt(data, 10, 64)
+
+			weight := float64(limit.Window-elapsed) / float64(limit.Window)
+			estimated := int(math.Floor(float64(previous)*weight)) + int(count)
+
+			state := rateLimitState{
+				limit:     limit.Limit,
+				remaining: limit.Limit - estimated,
+				reset:     limit.Window - elapsed,
+			}
+
+			if current == nil || state.remaining < current.remaining {
+				current = &state
+			}
+		}
+
+		if current == nil {
+			return
+		}
+
+		setRateLimitHeaders(c, *current)
+
+		if current.remaining < 0 {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(current.reset)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, map[string]string{"message": "Too many requests"})
+			trace.FailSpan(span, "Too many requests")
+
+			return
+		}
+	}
+}
+
+func setRateLimitHeaders(c *gin.Context, state rateLimitState) {
+	remaining := state.remaining
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(state.limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(state.reset)))
+}
+
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
+
+// requestEmail reads the email of the json body, the body is restored for the handler. Bodies over the max size
+// aren't limited by the email, the handler gets the error of the size when it reads them.
+func requestEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	limited := http.MaxBytesReader(c.Writer, c.Request.Body, rateLimitMaxBodySize)
+
+	body, err := io.ReadAll(limited)
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), limited))
+
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
@@ -0,0 +1,206 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/delivery/http/middleware"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/cache"
+)
+
+const (
+	rateLimitPath     = "/login"
+	rateLimitClientIP = "192.0.2.1"
+)
+
+func newRateLimitRouter(t *testing.T, limits ...middleware.RateLimit) (*gin.Engine, *cache.MemoryCache) {
+	t.Helper()
+
+	store, err := cache.NewMemoryCacheClient()
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST(rateLimitPath, middleware.RateLimitMiddleware(store, limits...), func(c *gin.Context) {
+		var payload struct {
+			Email string `json:"email"`
+		}
+
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.Status(http.StatusBadRequest)
+
+			return
+		}
+
+		c.String(http.StatusOK, payload.Email)
+	})
+
+	return router, store
+}
+
+func sendRateLimitedRequest(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, rateLimitPath, strings.NewReader(body))
+	req.RemoteAddr = rateLimitClientIP + ":1234"
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	return recorder
+}
+
+// previousWindowKey is the counter of the window before the current one for the requests of the client address.
+func previousWindowKey(window time.Duration) string {
+	current := time.Now().UnixNano() / int64(window)
+
+	return fmt.Sprintf("rate-limit-ip-%s-%s-%d", rateLimitPath, rateLimitClientIP, current-1)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario        string
+		limits          []middleware.RateLimit
+		arrange         func(t *testing.T, store *cache.MemoryCache)
+		requests        int
+		body            string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			scenario:       "when the requests are under the limit",
+			limits:         []middleware.RateLimit{middleware.RateLimitByIP(3, time.Hour)},
+			requests:       3,
+			body:           `{"email": "user@email.com"}`,
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "0",
+				"Retry-After":         "",
+			},
+		},
+		{
+			scenario:       "when the requests exceed the limit",
+			limits:         []middleware.RateLimit{middleware.RateLimitByIP(3, time.Hour)},
+			requests:       4,
+			body:           `{"email": "user@email.com"}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "0",
+			},
+		},
+		{
+			scenario: "when the previous window exceeded the limit",
+			limits:   []middleware.RateLimit{middleware.RateLimitByIP(3, time.Hour)},
+			arrange: func(t *testing.T, store *cache.MemoryCache) {
+				t.Helper()
+
+				key := previousWindowKey(time.Hour)
+				assert.NoError(t, store.Set(context.TODO(), key, fmt.Sprint(math.MaxInt32), time.Hour))
+			},
+			requests:       1,
+			body:           `{"email": "user@email.com"}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit":     "3",
+				"RateLimit-Remaining": "0",
+			},
+		},
+		{
+			scenario: "when the email limit is the most restrictive",
+			limits: []middleware.RateLimit{
+				middleware.RateLimitByIP(10, time.Hour),
+				middleware.RateLimitByEmail(2, time.Hour),
+			},
+			requests:       2,
+			body:           `{"email": "User@Email.com "}`,
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"RateLimit-Limit":     "2",
+				"RateLimit-Remaining": "0",
+			},
+		},
+		{
+			scenario:        "when the request has no email",
+			limits:          []middleware.RateLimit{middleware.RateLimitByEmail(1, time.Hour)},
+			requests:        2,
+			body:            `{"name": "user"}`,
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"RateLimit-Limit": ""},
+		},
+		{
+			scenario:        "when the body is too large to read the email",
+			limits:          []middleware.RateLimit{middleware.RateLimitByEmail(1, time.Hour)},
+			requests:        2,
+			body:            `{"email": "user@email.com", "name": "` + strings.Repeat("a", 1<<20) + `"}`,
+			expectedStatus:  http.StatusBadRequest,
+			expectedHeaders: map[string]string{"RateLimit-Limit": ""},
+		},
+		{
+			scenario:        "when the limit is disabled",
+			limits:          []middleware.RateLimit{middleware.RateLimitByIP(0, time.Hour)},
+			requests:        5,
+			body:            `{"email": "user@email.com"}`,
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"RateLimit-Limit": ""},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			router, store := newRateLimitRouter(t, tc.limits...)
+			if tc.arrange != nil {
+				tc.arrange(t, store)
+			}
+
+			var response *httptest.ResponseRecorder
+
+			// Action
+			for i := 0; i < tc.requests; i++ {
+				response = sendRateLimitedRequest(router, tc.body)
+			}
+
+			// Assert
+			assert.Equal(t, tc.expectedStatus, response.Code)
+
+			for header, value := range tc.expectedHeaders {
+				assert.Equal(t, value, response.Header().Get(header), header)
+			}
+
+			if tc.expectedStatus == http.StatusTooManyRequests {
+				assert.NotEmpty(t, response.Header().Get("Retry-After"))
+				assert.NotEmpty(t, response.Header().Get("RateLimit-Reset"))
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddlewareRestoresTheBody(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	router, _ := newRateLimitRouter(t, middleware.RateLimitByEmail(5, time.Hour))
+
+	// Action
+	response := sendRateLimitedRequest(router, `{"email": "user@email.com"}`)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "user@email.com", response.Body.String())
+	assert.Equal(t, "4", response.Header().Get("RateLimit-Remaining"))
+}
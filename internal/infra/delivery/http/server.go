@@ -16,32 +16,91 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func initRoutes(engine *gin.Engine, handlers *handler.Handler) {
+func initRoutes(
+	engine *gin.Engine, handlers *handler.Handler, cfg config.AppSettings, rateLimitStore middleware.RateLimitStore,
+) {
 	authMiddleware := middleware.AuthenticationMiddlware(handlers.AuthSvc)
+	adminMiddleware := middleware.AdminMiddleware(cfg.AdminAPIKey)
+	clientMiddleware := middleware.ClientAuthenticationMiddleware(handlers.ClientSvc, false)
+	publicClientMiddleware := middleware.ClientAuthenticationMiddleware(handlers.ClientSvc, true)
+	rateLimitMiddleware := middleware.RateLimitMiddleware(
+		rateLimitStore,
+		middleware.RateLimitByIP(cfg.RateLimitConfig.ByIP, cfg.RateLimitConfig.Window),
+		middleware.RateLimitByEmail(cfg.RateLimitConfig.ByEmail, cfg.RateLimitConfig.Window),
+	)
 
 	engine.GET("/health-check", handlers.HealthCheck)
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	engine.GET("/.well-known/jwks.json", handlers.JWKS)
+	engine.GET("/.well-known/openid-configuration", handlers.OpenIDConfiguration)
+	engine.GET("/userinfo", authMiddleware, handlers.UserInfo)
+	engine.POST("/userinfo", authMiddleware, handlers.UserInfo)
 
 	auth := engine.Group("/v1/auth")
 
-	auth.POST("/signup", handlers.SignUp)
-	auth.POST("/login", handlers.Login)
-	auth.POST("/recovery-password", handlers.SendRecoveryPasswordToken)
-	auth.POST("/reset-password", handlers.ResetPassword)
+	auth.POST("/signup", rateLimitMiddleware, handlers.SignUp)
+	auth.POST("/login", rateLimitMiddleware, handlers.Login)
+	auth.POST("/login/mfa", rateLimitMiddleware, handlers.LoginMFA)
+	auth.POST("/login/webauthn/begin", rateLimitMiddleware, handlers.BeginWebAuthnLogin)
+	auth.POST("/login/webauthn/finish", rateLimitMiddleware, handlers.FinishWebAuthnLogin)
+	auth.POST("/login/magic-link", rateLimitMiddleware, handlers.SendMagicLink)
+	auth.POST("/login/magic-link/consume", rateLimitMiddleware, handlers.LoginMagicLink)
+	auth.POST("/recovery-password", rateLimitMiddleware, handlers.SendRecoveryPasswordToken)
+	auth.POST("/reset-password", rateLimitMiddleware, handlers.ResetPassword)
+	auth.POST("/change-password", rateLimitMiddleware, handlers.ChangeRequiredPassword)
+	auth.POST("/verify-email", rateLimitMiddleware, handlers.VerifyEmail)
+	auth.POST("/verify-email/resend", rateLimitMiddleware, handlers.SendEmailVerificationToken)
+	auth.POST("/logout", authMiddleware, handlers.Logout)
 
 	auth.POST("/authorize", handlers.Authorize)
 	auth.POST("/refresh-access-token", handlers.RefreshAccessToken)
 
+	oauth := engine.Group("/oauth")
+	oauth.POST("/introspect", clientMiddleware, handlers.IntrospectToken)
+	oauth.POST("/revoke", publicClientMiddleware, handlers.RevokeToken)
+	oauth.GET("/authorize", handlers.AuthorizeClient)
+	oauth.POST("/session", rateLimitMiddleware, handlers.StartAuthorizationSession)
+	oauth.POST("/consent", handlers.Consent)
+	oauth.POST("/token", publicClientMiddleware, handlers.Token)
+
 	user := engine.Group("/v1/user")
 	user.Use(authMiddleware)
 	user.GET("/me", handlers.GetMe)
 	user.POST("/me", handlers.UpdateMe)
+	user.POST("/me/password", handlers.ChangeMyPassword)
 	user.DELETE("/me", handlers.DeleteMe)
+	user.GET("/me/sessions", handlers.ListMySessions)
+	user.POST("/me/sessions/revoke-others", handlers.RevokeMyOtherSessions)
+	user.DELETE("/me/sessions/:id", handlers.RevokeMySession)
+	user.POST("/me/mfa/totp", handlers.EnrollTOTP)
+	user.POST("/me/mfa/totp/confirm", handlers.ConfirmTOTP)
+	user.POST("/me/mfa/totp/disable", handlers.DisableTOTP)
+	user.POST("/me/mfa/recovery-codes", handlers.RegenerateRecoveryCodes)
+	user.POST("/me/phone/verify", handlers.VerifyPhone)
+	user.POST("/me/phone/verify/resend", handlers.SendPhoneVerificationCode)
+	user.POST("/me/webauthn/register/begin", handlers.BeginWebAuthnRegistration)
+	user.POST("/me/webauthn/register/finish", handlers.FinishWebAuthnRegistration)
+	user.GET("/me/webauthn/credentials", handlers.ListMyWebAuthnCredentials)
+	user.DELETE("/me/webauthn/credentials/:id", handlers.DeleteMyWebAuthnCredential)
+
+	admin := engine.Group("/v1/admin")
+	admin.Use(adminMiddleware)
+	admin.POST("/keys/rotate", handlers.RotateSigningKey)
+	admin.POST("/clients", handlers.CreateClient)
+	admin.GET("/users/:id/sessions", handlers.ListUserSessions)
+	admin.DELETE("/users/:id/sessions/:session_id", handlers.RevokeUserSession)
+	admin.POST("/users/:id/unlock", handlers.UnlockUser)
+	admin.POST("/users/:id/force-password-change", handlers.ForcePasswordChange)
 }
 
 func NewServer(
-	cfg config.AppSettings, authService handler.AuthenticationService, userService handler.UserService,
-) *http.Server {
+	cfg config.AppSettings,
+	authService handler.AuthenticationService,
+	userService handler.UserService,
+	keyRingService handler.KeyRingService,
+	clientService handler.ClientService,
+	rateLimitStore middleware.RateLimitStore,
+) (*http.Server, error) {
 	docs.SwaggerInfo.Title = config.ServiceName
 	docs.SwaggerInfo.Version = config.ServiceVersion
 
@@ -57,6 +116,12 @@ func NewServer(
 	}
 
 	engine := gin.New()
+
+	// The client address is read from the forwarded headers only when they're sent by a trusted proxy.
+	if err := engine.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		return nil, err
+	}
+
 	engine.Use(
 		customCors,
 		gin.Recovery(),
@@ -65,12 +130,12 @@ func NewServer(
 		otelgin.Middleware(cfg.TraceServiceName),
 	)
 
-	handler := handler.NewHandler(authService, userService)
+	handler := handler.NewHandler(authService, userService, keyRingService, clientService, cfg.OAuthConfig)
 
-	initRoutes(engine, handler)
+	initRoutes(engine, handler, cfg, rateLimitStore)
 
 	return &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.ServerPort),
 		Handler: engine,
-	}
+	}, nil
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type ClientRepository struct {
+	DB *gorm.DB
+}
+
+func NewClientRepository(db *gorm.DB) *ClientRepository {
+	return &ClientRepository{
+		DB: db,
+	}
+}
+
+func (cr ClientRepository) Get(ctx context.Context, id string) (entity.Client, error) {
+	var client entity.Client
+
+	tx := cr.DB.WithContext(ctx).First(&client, "id = ?", id)
+
+	return client, tx.Error
+}
+
+func (cr ClientRepository) Create(ctx context.Context, client entity.Client) error {
+	tx := cr.DB.WithContext(ctx).Create(&client)
+
+	return tx.Error
+}
@@ -10,7 +10,10 @@ import (
 )
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&entity.User{})
+	return db.AutoMigrate(
+		&entity.User{}, &entity.SigningKey{}, &entity.Client{}, &entity.WebAuthnCredential{},
+		&entity.PasswordHistory{},
+	)
 }
 
 func DBMigrate(dbInstance *gorm.DB, dbName string) error {
@@ -0,0 +1,9 @@
+CREATE TABLE "password_histories" (
+    "id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "password_hash" VARCHAR NOT NULL,
+    "created_at" TIMESTAMP NULL,
+    CONSTRAINT "password_histories_pk" PRIMARY KEY (id),
+    FOREIGN KEY ("user_id") REFERENCES users ("id") ON DELETE CASCADE
+);
+CREATE INDEX password_histories_user_id_idx ON "password_histories" USING btree (user_id, created_at);
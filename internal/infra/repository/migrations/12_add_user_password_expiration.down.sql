@@ -0,0 +1,2 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "must_change_password";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "password_changed_at";
@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "password_changed_at" TIMESTAMP NULL;
+ALTER TABLE "users" ADD COLUMN "must_change_password" BOOLEAN DEFAULT false;
+UPDATE "users" SET "password_changed_at" = "created_at";
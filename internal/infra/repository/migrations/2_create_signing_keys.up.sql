@@ -0,0 +1,10 @@
+CREATE TABLE "signing_keys" (
+    "id" VARCHAR NOT NULL,
+    "algorithm" VARCHAR NOT NULL,
+    "private_key" VARCHAR NOT NULL,
+    "activates_at" TIMESTAMP NOT NULL,
+    "retires_at" TIMESTAMP NULL,
+    "created_at" TIMESTAMP NULL,
+    CONSTRAINT "signing_keys_pk" PRIMARY KEY (id)
+);
+CREATE INDEX signing_keys_activates_at_idx ON "signing_keys" (activates_at);
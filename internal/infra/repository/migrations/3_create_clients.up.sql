@@ -0,0 +1,7 @@
+CREATE TABLE "clients" (
+    "id" VARCHAR NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "secret_hash" VARCHAR NOT NULL,
+    "created_at" TIMESTAMP NULL,
+    CONSTRAINT "clients_pk" PRIMARY KEY (id)
+);
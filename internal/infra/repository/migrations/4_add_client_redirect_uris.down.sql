@@ -0,0 +1,2 @@
+ALTER TABLE "clients" DROP COLUMN IF EXISTS "redirect_uris";
+ALTER TABLE "clients" DROP COLUMN IF EXISTS "public";
\ No newline at end of file
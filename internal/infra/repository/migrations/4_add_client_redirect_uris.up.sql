@@ -0,0 +1,2 @@
+ALTER TABLE "clients" ADD COLUMN "redirect_uris" TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE "clients" ADD COLUMN "public" BOOLEAN NOT NULL DEFAULT FALSE;
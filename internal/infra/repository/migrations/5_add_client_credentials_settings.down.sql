@@ -0,0 +1,2 @@
+ALTER TABLE "clients" DROP COLUMN IF EXISTS "allowed_scopes";
+ALTER TABLE "clients" DROP COLUMN IF EXISTS "token_lifetime";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "clients" ADD COLUMN "allowed_scopes" TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE "clients" ADD COLUMN "token_lifetime" INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_secret";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "totp_enabled";
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" VARCHAR NULL;
+ALTER TABLE "users" ADD COLUMN "totp_enabled" BOOLEAN DEFAULT false;
@@ -0,0 +1,12 @@
+CREATE TABLE "web_authn_credentials" (
+    "id" VARCHAR NOT NULL,
+    "user_id" uuid NOT NULL,
+    "name" VARCHAR NOT NULL,
+    "public_key" BYTEA NOT NULL,
+    "sign_count" BIGINT DEFAULT 0,
+    "created_at" TIMESTAMP NULL,
+    "last_used_at" TIMESTAMP NULL,
+    CONSTRAINT "web_authn_credentials_pk" PRIMARY KEY (id),
+    FOREIGN KEY ("user_id") REFERENCES users ("id") ON DELETE CASCADE
+);
+CREATE INDEX web_authn_credentials_user_id_idx ON "web_authn_credentials" USING btree (user_id);
@@ -0,0 +1 @@
+ALTER TABLE "users" ADD COLUMN "recovery_codes" TEXT NULL;
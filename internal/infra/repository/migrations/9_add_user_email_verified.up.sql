@@ -0,0 +1,2 @@
+ALTER TABLE "users" ADD COLUMN "email_verified" BOOLEAN DEFAULT false;
+UPDATE "users" SET "email_verified" = true;
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository struct {
+	DB *gorm.DB
+}
+
+func NewPasswordHistoryRepository(db *gorm.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		DB: db,
+	}
+}
+
+// ListByUser returns the last passwords of the user, the newest first.
+func (pr PasswordHistoryRepository) ListByUser(
+	ctx context.Context, userID uuid.UUID, limit int,
+) ([]entity.PasswordHistory, error) {
+	var history []entity.PasswordHistory
+
+	tx := pr.DB.WithContext(ctx).Order("created_at DESC").Limit(limit).Find(&history, "user_id = ?", userID)
+
+	return history, tx.Error
+}
+
+func (pr PasswordHistoryRepository) Create(ctx context.Context, entry entity.PasswordHistory) error {
+	tx := pr.DB.WithContext(ctx).Create(&entry)
+
+	return tx.Error
+}
+
+// Prune removes the passwords of the user older than the last ones to keep.
+func (pr PasswordHistoryRepository) Prune(ctx context.Context, userID uuid.UUID, keep int) error {
+	kept := pr.DB.Model(&entity.PasswordHistory{}).
+		Select("id").Where("user_id = ?", userID).Order("created_at DESC").Limit(keep)
+
+	tx := pr.DB.WithContext(ctx).Delete(&entity.PasswordHistory{}, "user_id = ? AND id NOT IN (?)", userID, kept)
+
+	return tx.Error
+}
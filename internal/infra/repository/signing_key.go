@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		DB: db,
+	}
+}
+
+func (kr SigningKeyRepository) List(ctx context.Context) ([]entity.SigningKey, error) {
+	var keys []entity.SigningKey
+
+	tx := kr.DB.WithContext(ctx).Order("activates_at desc").Find(&keys)
+
+	return keys, tx.Error
+}
+
+func (kr SigningKeyRepository) Create(ctx context.Context, key entity.SigningKey) error {
+	tx := kr.DB.WithContext(ctx).Create(&key)
+
+	return tx.Error
+}
+
+func (kr SigningKeyRepository) Update(ctx context.Context, key entity.SigningKey) error {
+	tx := kr.DB.WithContext(ctx).Save(key)
+
+	return tx.Error
+}
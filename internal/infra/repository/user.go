@@ -46,6 +46,18 @@ func (ur UserRepository) Update(ctx context.Context, user entity.User) error {
 	return tx.Error
 }
 
+// UpdateRecoveryCodes saves the recovery codes only when the stored ones are still the previous codes, so a code
+// consumed concurrently can't be used twice. It reports whether the codes were saved.
+func (ur UserRepository) UpdateRecoveryCodes(
+	ctx context.Context, user entity.User, previous entity.StringList,
+) (bool, error) {
+	tx := ur.DB.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND recovery_codes = ?", user.ID, previous).
+		Updates(map[string]any{"recovery_codes": user.RecoveryCodes, "updated_at": user.UpdatedAt})
+
+	return tx.RowsAffected == 1, tx.Error
+}
+
 func (ur UserRepository) DeleteByID(ctx context.Context, id uuid.UUID) error {
 	tx := ur.DB.WithContext(ctx).Delete(&entity.User{}, "id = ?", id)
 
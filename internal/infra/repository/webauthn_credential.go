@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"gorm.io/gorm"
+)
+
+type WebAuthnCredentialRepository struct {
+	DB *gorm.DB
+}
+
+func NewWebAuthnCredentialRepository(db *gorm.DB) *WebAuthnCredentialRepository {
+	return &WebAuthnCredentialRepository{
+		DB: db,
+	}
+}
+
+func (wr WebAuthnCredentialRepository) Get(ctx context.Context, id string) (entity.WebAuthnCredential, error) {
+	var credential entity.WebAuthnCredential
+
+	tx := wr.DB.WithContext(ctx).First(&credential, "id = ?", id)
+
+	return credential, tx.Error
+}
+
+func (wr WebAuthnCredentialRepository) ListByUser(
+	ctx context.Context, userID uuid.UUID,
+) ([]entity.WebAuthnCredential, error) {
+	var credentials []entity.WebAuthnCredential
+
+	tx := wr.DB.WithContext(ctx).Order("created_at").Find(&credentials, "user_id = ?", userID)
+
+	return credentials, tx.Error
+}
+
+func (wr WebAuthnCredentialRepository) Create(ctx context.Context, credential entity.WebAuthnCredential) error {
+	tx := wr.DB.WithContext(ctx).Create(&credential)
+
+	return tx.Error
+}
+
+func (wr WebAuthnCredentialRepository) Update(ctx context.Context, credential entity.WebAuthnCredential) error {
+	tx := wr.DB.WithContext(ctx).Save(credential)
+
+	return tx.Error
+}
+
+// Delete removes the credential only when it belongs to the user, it returns gorm.ErrRecordNotFound otherwise.
+func (wr WebAuthnCredentialRepository) Delete(ctx context.Context, userID uuid.UUID, id string) error {
+	tx := wr.DB.WithContext(ctx).Delete(&entity.WebAuthnCredential{}, "id = ? AND user_id = ?", id, userID)
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return tx.Error
+}
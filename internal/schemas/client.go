@@ -0,0 +1,9 @@
+package schemas
+
+type CreateClientPayload struct {
+	Name          string   `json:"name" binding:"required"`
+	RedirectURIs  []string `json:"redirect_uris"`
+	Public        bool     `json:"public"`
+	AllowedScopes []string `json:"allowed_scopes"`
+	TokenLifetime int      `json:"token_lifetime"`
+}
@@ -1,14 +1,55 @@
 package schemas
 
+import "time"
+
 type Login struct {
 	Email    string `json:"email" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device"`
+
+	// Filled from the request, they identify the session created by the login
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LoginResponse struct {
 	AccessToken  JwtToken `json:"access_token,omitempty"`
 	RefreshToken JwtToken `json:"refresh_token,omitempty"`
+	IDToken      string   `json:"id_token,omitempty"`
 	Message      string   `json:"message,omitempty"`
+
+	// Set when the account is locked by failed logins
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+
+	// Set instead of the tokens when the password expired or an admin forced its change
+	PasswordChangeToken string `json:"password_change_token,omitempty"`
+
+	// Set instead of the tokens when the user must verify a second factor
+	MFAChallenge    string                  `json:"mfa_challenge,omitempty"`
+	MFAMethods      []string                `json:"mfa_methods,omitempty"`
+	WebAuthnOptions *WebAuthnRequestOptions `json:"webauthn_options,omitempty"`
+}
+
+// LoginMFA verifies the second factor, the code is required by the totp method and the credential by the
+// webauthn one. The method defaults to totp.
+type LoginMFA struct {
+	Challenge  string             `json:"challenge" binding:"required"`
+	Method     string             `json:"method"`
+	Code       string             `json:"code"`
+	Credential *WebAuthnAssertion `json:"credential"`
+}
+
+type SendMagicLinkPayload struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type MagicLinkLogin struct {
+	Token  string `json:"token" binding:"required"`
+	Device string `json:"device"`
+
+	// Filled from the request, they identify the session created by the login
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type SendRecoveryPasswordPayload struct {
@@ -20,6 +61,45 @@ type RecoveryPassword struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ChangeRequiredPassword replaces the password with the token returned by the login.
+type ChangeRequiredPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type SendEmailVerificationPayload struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type VerifyPhonePayload struct {
+	Code string `json:"code" binding:"required"`
+}
+
 type AuthorizationPayload struct {
 	Token string `json:"token" binding:"required"`
 }
+
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes are shown only once, each one replaces the second factor on a single login.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TOTPCode struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ReAuthentication proves the user is present before a change of the factors, with the current password or a code
+// of the authenticator app when the user has one.
+type ReAuthentication struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
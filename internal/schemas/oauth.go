@@ -0,0 +1,45 @@
+package schemas
+
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	Nonce               string `form:"nonce"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+type TokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+
+	// Filled from the request, they identify the session created by the code exchange
+	IPAddress string `form:"-"`
+	UserAgent string `form:"-"`
+}
+
+// AuthorizationSessionRequest is posted by the login page, it exchanges the access token of the user for the
+// session cookie of the authorize endpoint.
+type AuthorizationSessionRequest struct {
+	AccessToken string `form:"access_token" binding:"required"`
+	ReturnTo    string `form:"return_to" binding:"required"`
+}
+
+// ConsentRequest is sent to the consent page, the scope is the one that will be granted if the user approves it.
+type ConsentRequest struct {
+	Challenge  string
+	ClientID   string
+	ClientName string
+	Scope      string
+}
+
+type Consent struct {
+	Challenge string `form:"consent_challenge" binding:"required"`
+	Approve   bool   `form:"approve"`
+}
@@ -1,8 +1,24 @@
 package schemas
 
+// UpdateUserPayload are the changes of the user, the empty fields are kept. The password is only set by the flows
+// that verify the ownership of the account, like the change password and the recovery.
 type UpdateUserPayload struct {
-	Name     string `json:"name" binding:"required"`
-	Phone    string `json:"phone" binding:"required"`
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Name     string `json:"name"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// UpdateProfilePayload are the changes of the profile sent by the user, the empty fields are kept. The current
+// password is only required to change the email.
+type UpdateProfilePayload struct {
+	Name            string `json:"name"`
+	Phone           string `json:"phone"`
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+}
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
@@ -2,11 +2,23 @@ package auth_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v4"
+	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
@@ -14,12 +26,20 @@ import (
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/services/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/services/user"
+	pkgauth "github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth/authtest"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/cache"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	testSecretKey = "my-test-secret-key"
+	testIssuer    = "https://auth.example.com"
+
+	testEncryptionKey = "my-test-encryption-key"
+	testRPID          = "auth.example.com"
+	testOrigin        = "https://auth.example.com"
 )
 
 type Sut struct {
@@ -30,7 +50,11 @@ type Sut struct {
 	eventChannel chan schemas.Event
 }
 
-func newSut() Sut {
+func newSut(options ...func(cfg *auth.Config)) Sut {
+	return newSutWithKeyRing(pkgauth.NewKeyRing(pkgauth.NewHMACKey("", testSecretKey)), options...)
+}
+
+func newSutWithKeyRing(keyRing *pkgauth.KeyRing, options ...func(cfg *auth.Config)) Sut {
 	eventChannel := make(chan schemas.Event)
 
 	db, err := database.NewSQLiteMemoryConnection()
@@ -48,10 +72,22 @@ func newSut() Sut {
 		panic(err)
 	}
 
+	cfg := auth.Config{
+		Issuer:         testIssuer,
+		EncryptionKey:  testEncryptionKey,
+		WebAuthnRPID:   testRPID,
+		WebAuthnOrigin: testOrigin,
+	}
+	for _, option := range options {
+		option(&cfg)
+	}
+
 	userRepository := repository.NewUserRepository(db)
-	userService := user.NewService(userRepository, eventChannel)
+	passwordHistoryRepository := repository.NewPasswordHistoryRepository(db)
+	userService := user.NewService(userRepository, passwordHistoryRepository, cfg.PasswordPolicy.Policy(), eventChannel)
 
-	service := auth.NewService(userService, cacheClient, testSecretKey, eventChannel)
+	credentialRepository := repository.NewWebAuthnCredentialRepository(db)
+	service := auth.NewService(userService, credentialRepository, cacheClient, keyRing, cfg, eventChannel)
 
 	return Sut{
 		service:      service,
@@ -156,6 +192,7 @@ func TestSiginUp(t *testing.T) {
 					existingUserEmail,
 					gofakeit.Phone(),
 					gofakeit.Password(true, true, true, true, true, 10),
+					pkgauth.DefaultPasswordPolicy(),
 				)
 				assert.Nil(t, err)
 
@@ -248,7 +285,7 @@ func TestLogin(t *testing.T) {
 
 			user, err := sut.service.SignUp(context.TODO(), signUp)
 			assert.NoError(t, err)
-			<-sut.eventChannel
+			drainSignUpEvents(sut)
 
 			// Action
 			response, err := sut.service.Login(context.TODO(), tc.payload)
@@ -260,9 +297,9 @@ func TestLogin(t *testing.T) {
 				assert.Equal(t, tc.expectedMessage, response.Message)
 			} else {
 				// Login
-				loggedUserID, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+				session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
 				assert.NoError(t, err)
-				assert.Equal(t, user.ID, loggedUserID)
+				assert.Equal(t, user.ID, session.UserID)
 
 				// Event
 				event := <-sut.eventChannel
@@ -273,6 +310,115 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginRehashPassword(t *testing.T) {
+	t.Parallel()
+
+	outdatedParams := pkgauth.DefaultArgon2Params()
+	outdatedParams.Memory = 8 * 1024
+
+	tests := []struct {
+		scenario         string
+		hasher           pkgauth.PasswordHasher
+		expectedRehashed bool
+	}{
+		{
+			scenario:         "when hash is bcrypt",
+			hasher:           pkgauth.NewBcryptHasher(bcrypt.MinCost),
+			expectedRehashed: true,
+		},
+		{
+			scenario:         "when hash is argon2id with outdated params",
+			hasher:           pkgauth.NewArgon2idHasher(outdatedParams),
+			expectedRehashed: true,
+		},
+		{
+			scenario:         "when hash is up to date",
+			hasher:           pkgauth.NewArgon2idHasher(pkgauth.DefaultArgon2Params()),
+			expectedRehashed: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+
+			hash, err := tc.hasher.Hash(payload.Password)
+			assert.NoError(t, err)
+
+			user.PasswordHash = hash
+			assert.NoError(t, sut.userRepo.Update(context.TODO(), *user))
+
+			// Action
+			login(t, sut, payload, "laptop")
+
+			// Assert
+			stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRehashed, stored.PasswordHash != hash)
+			assert.False(t, pkgauth.PasswordNeedsRehash(stored.PasswordHash))
+			assert.True(t, stored.ValidatePassword(payload.Password))
+		})
+	}
+}
+
+func TestLoginUpgradeLegacyPasswordHash(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		hash     string
+	}{
+		{
+			scenario: "when hash is django pbkdf2 sha256",
+			hash:     "pbkdf2_sha256$1000$seasalt$SWQeYfPvhvhtuV9OhCKeufOo3f2A9VB7jYT1LGbV1/w=",
+		},
+		{
+			scenario: "when hash is sha512 crypt",
+			hash: "$6$seasalt$sqA3BXb3DhQdiyyx9fGxaPakdIVmSpHNXIFYkAgweRorgL5s9JPDe3tOhOTZ7d8jeB4RVGWXRWGGdkKxCGoF" +
+				"7/",
+		},
+		{
+			scenario: "when hash is salted md5",
+			hash:     "md5$seasalt$9adca04dd1bcbe3619188fb11cbff586",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+
+			user.PasswordHash = tc.hash
+			assert.NoError(t, sut.userRepo.Update(context.TODO(), *user))
+
+			payload.Password = "my-password"
+
+			// Action
+			login(t, sut, payload, "laptop")
+
+			// Assert
+			stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+			assert.NoError(t, err)
+
+			algorithm, err := pkgauth.PasswordHashAlgorithm(stored.PasswordHash)
+			assert.NoError(t, err)
+			assert.Equal(t, pkgauth.PasswordHashArgon2id, algorithm)
+			assert.True(t, stored.ValidatePassword(payload.Password))
+		})
+	}
+}
+
 func TestValidateAccessToken(t *testing.T) {
 	t.Parallel()
 
@@ -287,7 +433,7 @@ func TestValidateAccessToken(t *testing.T) {
 
 	user, err := sut.service.SignUp(context.TODO(), signUp)
 	assert.NoError(t, err)
-	<-sut.eventChannel
+	drainSignUpEvents(sut)
 
 	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
 		Email:    signUp.Email,
@@ -312,7 +458,7 @@ func TestValidateAccessToken(t *testing.T) {
 		},
 		{
 			scenario:      "when token is not a acessToken",
-			expectedError: "Token not found: not authorized",
+			expectedError: "Invalid Token: not authorized",
 			accessToken:   loginResponse.RefreshToken.Token,
 		},
 	}
@@ -324,14 +470,14 @@ func TestValidateAccessToken(t *testing.T) {
 			t.Parallel()
 
 			// Action
-			tokenUserID, err := sut.service.ValidateAccessToken(context.TODO(), tc.accessToken)
+			session, err := sut.service.ValidateAccessToken(context.TODO(), tc.accessToken)
 
 			// Assert
 			if tc.expectedError != "" {
 				assert.EqualError(t, err, tc.expectedError)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tc.userID, tokenUserID)
+				assert.Equal(t, tc.userID, session.UserID)
 			}
 		})
 	}
@@ -351,7 +497,7 @@ func TestRefreshAccessToken(t *testing.T) {
 
 	_, err := sut.service.SignUp(context.TODO(), signUp)
 	assert.NoError(t, err)
-	<-sut.eventChannel
+	drainSignUpEvents(sut)
 
 	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
 		Email:    signUp.Email,
@@ -376,7 +522,7 @@ func TestRefreshAccessToken(t *testing.T) {
 		},
 		{
 			scenario:      "when token is not a refreshToken should return an error",
-			expectedError: "Token not found: not authorized",
+			expectedError: "Invalid Token: not authorized",
 			refreshToken:  loginResponse.AccessToken.Token,
 			accessToken:   loginResponse.AccessToken.Token,
 		},
@@ -419,12 +565,10 @@ func TestLogout(t *testing.T) {
 
 	tests := []struct {
 		scenario      string
-		userID        uuid.UUID
 		expectedError string
 	}{
 		{
-			scenario: "when logout is success, accessToken must be invalidated",
-			userID:   uuid.New(),
+			scenario: "when logout is success, access and refresh tokens must be invalidated",
 		},
 	}
 
@@ -437,25 +581,103 @@ func TestLogout(t *testing.T) {
 			// Arrange
 			sut := newSut()
 
-			accessToken, err := sut.service.GenerateToken(context.TODO(), tc.userID, auth.AccessTokenPrefix, time.Hour)
+			signUp := schemas.SignUp{
+				Name:     gofakeit.Name(),
+				Email:    gofakeit.Email(),
+				Phone:    gofakeit.Phone(),
+				Password: gofakeit.Password(true, true, true, true, true, 10),
+			}
+
+			_, err := sut.service.SignUp(context.TODO(), signUp)
+			assert.NoError(t, err)
+			drainSignUpEvents(sut)
+
+			loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+				Email:    signUp.Email,
+				Password: signUp.Password,
+			})
+			assert.NoError(t, err)
+
+			session, err := sut.service.ValidateAccessToken(context.TODO(), loginResponse.AccessToken.Token)
 			assert.NoError(t, err)
 
 			// Action
-			err = sut.service.Logout(context.TODO(), tc.userID)
+			err = sut.service.Logout(context.TODO(), session.UserID, session.ID)
 
 			// Assert
 			if tc.expectedError != "" {
 				assert.Error(t, err)
 				assert.EqualError(t, err, tc.expectedError)
 			} else {
-				_, err := sut.service.ValidateAccessToken(context.TODO(), accessToken.Token)
+				_, err := sut.service.ValidateAccessToken(context.TODO(), loginResponse.AccessToken.Token)
 				assert.Error(t, err)
 				assert.EqualError(t, err, "Token not found: not authorized")
+
+				_, err = sut.service.RefreshAccessToken(
+					context.TODO(), schemas.RefreshToken{JwtToken: loginResponse.RefreshToken},
+				)
+				assert.Error(t, err)
 			}
 		})
 	}
 }
 
+func TestLoginKeepsPreviousSessions(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+
+	signUp := schemas.SignUp{
+		Name:     gofakeit.Name(),
+		Email:    gofakeit.Email(),
+		Phone:    gofakeit.Phone(),
+		Password: gofakeit.Password(true, true, true, true, true, 10),
+	}
+
+	user, err := sut.service.SignUp(context.TODO(), signUp)
+	assert.NoError(t, err)
+	drainSignUpEvents(sut)
+
+	phoneLogin, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:     signUp.Email,
+		Password:  signUp.Password,
+		Device:    "phone",
+		IPAddress: gofakeit.IPv4Address(),
+		UserAgent: gofakeit.UserAgent(),
+	})
+	assert.NoError(t, err)
+
+	// Action
+	laptopLogin, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:     signUp.Email,
+		Password:  signUp.Password,
+		Device:    "laptop",
+		IPAddress: gofakeit.IPv4Address(),
+		UserAgent: gofakeit.UserAgent(),
+	})
+	assert.NoError(t, err)
+
+	// Assert
+	phoneSession, err := sut.service.ValidateAccessToken(context.TODO(), phoneLogin.AccessToken.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, phoneSession.UserID)
+	assert.Equal(t, "phone", phoneSession.Device)
+
+	laptopSession, err := sut.service.ValidateAccessToken(context.TODO(), laptopLogin.AccessToken.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, laptopSession.UserID)
+	assert.Equal(t, "laptop", laptopSession.Device)
+
+	assert.NotEqual(t, phoneSession.ID, laptopSession.ID)
+
+	_, err = sut.service.RefreshAccessToken(context.TODO(), schemas.RefreshToken{JwtToken: phoneLogin.RefreshToken})
+	assert.NoError(t, err)
+
+	_, err = sut.service.ValidateAccessToken(context.TODO(), laptopLogin.AccessToken.Token)
+	assert.NoError(t, err)
+}
+
 func TestSendRecoveryPasswordToken(t *testing.T) {
 	t.Parallel()
 
@@ -498,6 +720,7 @@ func TestSendRecoveryPasswordToken(t *testing.T) {
 					tc.existingUserEmail,
 					gofakeit.Phone(),
 					gofakeit.Password(true, true, true, true, true, 10),
+					pkgauth.DefaultPasswordPolicy(),
 				)
 				assert.NoError(t, err)
 
@@ -612,3 +835,3064 @@ func TestRecoveryPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateProfileChangesTheEmail(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	newEmail := gofakeit.Email()
+
+	_, noPasswordErr := sut.service.UpdateProfile(context.TODO(), user.ID, schemas.UpdateProfilePayload{Email: newEmail})
+
+	// Action
+	updated, err := sut.service.UpdateProfile(context.TODO(), user.ID, schemas.UpdateProfilePayload{
+		Email:           newEmail,
+		CurrentPassword: payload.Password,
+	})
+
+	// Assert
+	assert.ErrorIs(t, noPasswordErr, auth.ErrReAuthRequired)
+	assert.NoError(t, err)
+	assert.Equal(t, newEmail, updated.Email)
+	assert.False(t, updated.EmailVerified)
+
+	events := map[string]string{}
+	for len(events) < 3 {
+		event := <-sut.eventChannel
+		events[event.Action] = string(event.Data)
+	}
+
+	assert.Contains(t, events["email-changed"], user.Email, "the old address is notified")
+	assert.Contains(t, events["email-verification"], newEmail, "the new address must be verified")
+}
+
+func TestTokenClaims(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario         string
+		audience         string
+		token            func(response schemas.LoginResponse) string
+		expectedTokenUse string
+		expectedAudience string
+	}{
+		{
+			scenario:         "when it's an access token",
+			token:            func(response schemas.LoginResponse) string { return response.AccessToken.Token },
+			expectedTokenUse: "access",
+			expectedAudience: testIssuer,
+		},
+		{
+			scenario:         "when it's an access token with a configured audience",
+			audience:         "https://api.example.com",
+			token:            func(response schemas.LoginResponse) string { return response.AccessToken.Token },
+			expectedTokenUse: "access",
+			expectedAudience: "https://api.example.com",
+		},
+		{
+			scenario:         "when it's a refresh token",
+			audience:         "https://api.example.com",
+			token:            func(response schemas.LoginResponse) string { return response.RefreshToken.Token },
+			expectedTokenUse: "refresh",
+			expectedAudience: testIssuer,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(func(cfg *auth.Config) { cfg.TokenAudience = tc.audience })
+			_, payload := signUp(t, sut)
+			response, _ := login(t, sut, payload, "test")
+
+			var claims pkgauth.Claims
+
+			// Action
+			_, _, err := new(jwt.Parser).ParseUnverified(tc.token(response), &claims)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedTokenUse, claims.TokenUse)
+			assert.Equal(t, testIssuer, claims.Issuer)
+			assert.Equal(t, tc.expectedAudience, claims.Audience)
+		})
+	}
+}
+
+func TestTokensAreOnlyAcceptedForTheirUse(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+
+	token, err := sut.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+	assert.NoError(t, err)
+
+	key := fmt.Sprintf("%s-%s", auth.EmailVerificationTokenPrefix, user.ID)
+	assert.NoError(t, sut.cache.Set(context.TODO(), key, token.Token, time.Hour))
+
+	// Action
+	err = sut.service.VerifyEmail(context.TODO(), token.Token)
+
+	// Assert
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized, "a recovery token can't verify the email even when it's cached")
+}
+
+func TestTokensOfAnotherIssuerAreRejected(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	keyRing := pkgauth.NewKeyRing(pkgauth.NewHMACKey("", testSecretKey))
+	issuer := newSutWithKeyRing(keyRing, func(cfg *auth.Config) { cfg.Issuer = "https://other.example.com" })
+	sut := newSutWithKeyRing(keyRing)
+	user, _ := signUp(t, sut)
+
+	token, err := issuer.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+	assert.NoError(t, err)
+
+	key := fmt.Sprintf("%s-%s", auth.RecoveryTokenPrefix, user.ID)
+	assert.NoError(t, sut.cache.Set(context.TODO(), key, token.Token, time.Hour))
+
+	// Action
+	err = sut.service.RecoveryPassword(context.TODO(), token.Token, "recovered-password")
+
+	// Assert
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized)
+}
+
+func signUp(t *testing.T, sut Sut) (*entity.User, schemas.SignUp) {
+	t.Helper()
+
+	payload := schemas.SignUp{
+		Name:     gofakeit.Name(),
+		Email:    gofakeit.Email(),
+		Phone:    gofakeit.Phone(),
+		Password: gofakeit.Password(true, true, true, true, true, 10),
+	}
+
+	user, err := sut.service.SignUp(context.TODO(), payload)
+	assert.NoError(t, err)
+	drainSignUpEvents(sut)
+
+	return user, payload
+}
+
+// drainSignUpEvents consumes the user creation and the email verification events.
+func drainSignUpEvents(sut Sut) {
+	<-sut.eventChannel
+	<-sut.eventChannel
+}
+
+func login(t *testing.T, sut Sut, payload schemas.SignUp, device string) (schemas.LoginResponse, entity.Session) {
+	t.Helper()
+
+	response, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+		Device:   device,
+	})
+	assert.NoError(t, err)
+
+	session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+	assert.NoError(t, err)
+
+	return response, session
+}
+
+func TestListSessions(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	_, phoneSession := login(t, sut, payload, "phone")
+	_, laptopSession := login(t, sut, payload, "laptop")
+
+	// Action
+	sessions, err := sut.service.ListSessions(context.TODO(), user.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.ElementsMatch(t, []uuid.UUID{phoneSession.ID, laptopSession.ID}, []uuid.UUID{sessions[0].ID, sessions[1].ID})
+}
+
+func TestRevokeSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario      string
+		sessionID     uuid.UUID
+		expectedError error
+	}{
+		{
+			scenario:      "when session does not exist",
+			sessionID:     uuid.New(),
+			expectedError: auth.ErrSessionNotFound,
+		},
+		{
+			scenario: "when session exists, its tokens must be invalidated",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+
+			phoneLogin, phoneSession := login(t, sut, payload, "phone")
+			laptopLogin, _ := login(t, sut, payload, "laptop")
+
+			if tc.sessionID == uuid.Nil {
+				tc.sessionID = phoneSession.ID
+			}
+
+			// Action
+			err := sut.service.RevokeSession(context.TODO(), user.ID, tc.sessionID)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+
+				_, err = sut.service.ValidateAccessToken(context.TODO(), phoneLogin.AccessToken.Token)
+				assert.Error(t, err)
+
+				_, err = sut.service.RefreshAccessToken(
+					context.TODO(), schemas.RefreshToken{JwtToken: phoneLogin.RefreshToken},
+				)
+				assert.Error(t, err)
+
+				_, err = sut.service.ValidateAccessToken(context.TODO(), laptopLogin.AccessToken.Token)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	phoneLogin, _ := login(t, sut, payload, "phone")
+	tabletLogin, _ := login(t, sut, payload, "tablet")
+	laptopLogin, laptopSession := login(t, sut, payload, "laptop")
+
+	// Action
+	err := sut.service.RevokeOtherSessions(context.TODO(), user.ID, laptopSession.ID)
+
+	// Assert
+	assert.NoError(t, err)
+
+	_, err = sut.service.ValidateAccessToken(context.TODO(), phoneLogin.AccessToken.Token)
+	assert.Error(t, err)
+
+	_, err = sut.service.ValidateAccessToken(context.TODO(), tabletLogin.AccessToken.Token)
+	assert.Error(t, err)
+
+	_, err = sut.service.ValidateAccessToken(context.TODO(), laptopLogin.AccessToken.Token)
+	assert.NoError(t, err)
+
+	sessions, err := sut.service.ListSessions(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, laptopSession.ID, sessions[0].ID)
+}
+
+func TestRefreshTokenReuseRevokesTheFamily(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	firstLogin, session := login(t, sut, payload, "phone")
+
+	rotated, err := sut.service.RefreshAccessToken(
+		context.TODO(), schemas.RefreshToken{JwtToken: firstLogin.RefreshToken},
+	)
+	assert.NoError(t, err)
+
+	// Action
+	_, err = sut.service.RefreshAccessToken(context.TODO(), schemas.RefreshToken{JwtToken: firstLogin.RefreshToken})
+
+	// Assert
+	assert.EqualError(t, err, "Refresh token reused: not authorized")
+
+	_, err = sut.service.RefreshAccessToken(context.TODO(), schemas.RefreshToken{JwtToken: rotated.RefreshToken})
+	assert.Error(t, err)
+
+	_, err = sut.service.ValidateAccessToken(context.TODO(), rotated.AccessToken.Token)
+	assert.Error(t, err)
+
+	event := waitEvent(t, sut, "refresh-token-reuse")
+
+	var data map[string]string
+	assert.NoError(t, json.Unmarshal(event.Data, &data))
+	assert.Equal(t, user.ID.String(), data["user_id"])
+	assert.Equal(t, session.ID.String(), data["session_id"])
+}
+
+func TestIntrospectToken(t *testing.T) {
+	t.Parallel()
+
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	loginResponse, _ := login(t, sut, payload, "phone")
+	loggedOut, loggedOutSession := login(t, sut, payload, "laptop")
+
+	err := sut.service.Logout(context.TODO(), user.ID, loggedOutSession.ID)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario          string
+		token             string
+		tokenTypeHint     string
+		expectedActive    bool
+		expectedTokenType string
+	}{
+		{
+			scenario:          "when token is an access token",
+			token:             loginResponse.AccessToken.Token,
+			expectedActive:    true,
+			expectedTokenType: auth.TokenTypeAccessToken,
+		},
+		{
+			scenario:          "when token is a refresh token without hint",
+			token:             loginResponse.RefreshToken.Token,
+			expectedActive:    true,
+			expectedTokenType: auth.TokenTypeRefreshToken,
+		},
+		{
+			scenario:          "when hint does not match the token type",
+			token:             loginResponse.AccessToken.Token,
+			tokenTypeHint:     auth.TokenTypeRefreshToken,
+			expectedActive:    true,
+			expectedTokenType: auth.TokenTypeAccessToken,
+		},
+		{
+			scenario:       "when token was invalidated",
+			token:          loggedOut.AccessToken.Token,
+			expectedActive: false,
+		},
+		{
+			scenario:       "when token is invalid",
+			token:          "invalid-token",
+			expectedActive: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			introspection := sut.service.IntrospectToken(context.TODO(), tc.token, tc.tokenTypeHint)
+
+			// Assert
+			assert.Equal(t, tc.expectedActive, introspection.Active)
+			assert.Equal(t, tc.expectedTokenType, introspection.TokenType)
+
+			if tc.expectedActive {
+				assert.Equal(t, user.ID.String(), introspection.Subject)
+				assert.NotZero(t, introspection.ExpiresAt)
+				assert.NotZero(t, introspection.IssuedAt)
+			} else {
+				assert.Equal(t, schemas.Introspection{}, introspection)
+			}
+		})
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	t.Parallel()
+
+	client := entity.Client{
+		ID: uuid.NewString(), Name: "app", RedirectURIs: entity.StringList{"https://app.example.com/callback"},
+	}
+
+	tests := []struct {
+		scenario             string
+		clientID             string
+		tokenTypeHint        string
+		firstPartyToken      bool
+		revokeRefreshToken   bool
+		revokeInvalidToken   bool
+		expectedError        error
+		expectAccessRevoked  bool
+		expectRefreshRevoked bool
+	}{
+		{
+			scenario:            "when token is an access token only the access token is revoked",
+			clientID:            client.ID,
+			expectAccessRevoked: true,
+		},
+		{
+			scenario:             "when token is a refresh token the whole session is revoked",
+			clientID:             client.ID,
+			tokenTypeHint:        auth.TokenTypeRefreshToken,
+			revokeRefreshToken:   true,
+			expectAccessRevoked:  true,
+			expectRefreshRevoked: true,
+		},
+		{
+			scenario:             "when hint does not match the token type the token is still revoked",
+			clientID:             client.ID,
+			tokenTypeHint:        auth.TokenTypeAccessToken,
+			revokeRefreshToken:   true,
+			expectAccessRevoked:  true,
+			expectRefreshRevoked: true,
+		},
+		{
+			scenario:           "when token is invalid nothing is revoked",
+			clientID:           client.ID,
+			revokeInvalidToken: true,
+		},
+		{
+			scenario:           "when token was issued to another client",
+			clientID:           uuid.NewString(),
+			revokeRefreshToken: true,
+			expectedError:      auth.ErrClientMismatch,
+		},
+		{
+			scenario:           "when token wasn't issued to a client",
+			clientID:           client.ID,
+			firstPartyToken:    true,
+			revokeRefreshToken: true,
+			expectedError:      auth.ErrClientMismatch,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+
+			var response schemas.LoginResponse
+			if tc.firstPartyToken {
+				response, _ = login(t, sut, payload, "phone")
+			} else {
+				response = authorizeClient(t, sut, client, user.ID)
+			}
+
+			token := response.AccessToken.Token
+			if tc.revokeRefreshToken {
+				token = response.RefreshToken.Token
+			}
+
+			if tc.revokeInvalidToken {
+				token = "invalid-token"
+			}
+
+			// Action
+			err := sut.service.RevokeToken(context.TODO(), tc.clientID, token, tc.tokenTypeHint)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedError)
+
+			introspection := sut.service.IntrospectToken(context.TODO(), response.AccessToken.Token, "")
+			assert.Equal(t, tc.expectAccessRevoked, !introspection.Active)
+
+			introspection = sut.service.IntrospectToken(
+				context.TODO(), response.RefreshToken.Token, auth.TokenTypeRefreshToken,
+			)
+			assert.Equal(t, tc.expectRefreshRevoked, !introspection.Active)
+		})
+	}
+}
+
+// authorizeClient runs the authorization code flow, so the tokens are issued to the client.
+func authorizeClient(t *testing.T, sut Sut, client entity.Client, userID uuid.UUID) schemas.LoginResponse {
+	t.Helper()
+
+	verifier := strings.Repeat("v", 43)
+
+	code, err := sut.service.Authorize(context.TODO(), client, userID, schemas.AuthorizeRequest{
+		ResponseType:        auth.ResponseTypeCode,
+		RedirectURI:         client.RedirectURIs[0],
+		CodeChallenge:       pkgauth.NewCodeChallenge(verifier),
+		CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+	})
+	assert.NoError(t, err)
+
+	response, err := sut.service.ExchangeAuthorizationCode(context.TODO(), client, schemas.TokenRequest{
+		GrantType:    auth.GrantTypeAuthorizationCode,
+		Code:         code,
+		RedirectURI:  client.RedirectURIs[0],
+		CodeVerifier: verifier,
+	})
+	assert.NoError(t, err)
+
+	return response
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	client := entity.Client{
+		ID:            uuid.NewString(),
+		RedirectURIs:  entity.StringList{"https://app.example.com/callback"},
+		AllowedScopes: entity.StringList{"profile"},
+	}
+	challenge := pkgauth.NewCodeChallenge(strings.Repeat("v", 43))
+
+	tests := []struct {
+		scenario      string
+		request       schemas.AuthorizeRequest
+		expectedError error
+	}{
+		{
+			scenario: "when request is valid should return a code",
+			request: schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				RedirectURI:         client.RedirectURIs[0],
+				CodeChallenge:       challenge,
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			},
+		},
+		{
+			scenario: "when response type is not code",
+			request: schemas.AuthorizeRequest{
+				ResponseType:        "token",
+				CodeChallenge:       challenge,
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			},
+			expectedError: auth.ErrUnsupportedResponseType,
+		},
+		{
+			scenario:      "when code challenge is missing",
+			request:       schemas.AuthorizeRequest{ResponseType: auth.ResponseTypeCode},
+			expectedError: auth.ErrInvalidRequest,
+		},
+		{
+			scenario: "when code challenge method is plain",
+			request: schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				CodeChallenge:       challenge,
+				CodeChallengeMethod: "plain",
+			},
+			expectedError: auth.ErrInvalidRequest,
+		},
+		{
+			scenario: "when requested scope is not allowed for the client",
+			request: schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				RedirectURI:         client.RedirectURIs[0],
+				Scope:               "profile users:delete",
+				CodeChallenge:       challenge,
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			},
+			expectedError: auth.ErrInvalidScope,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+
+			// Action
+			code, err := sut.service.Authorize(context.TODO(), client, uuid.New(), tc.request)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, code)
+			}
+		})
+	}
+}
+
+func TestAuthorizationSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario              string
+		refreshToken          bool
+		endSession            bool
+		expectedStartError    error
+		expectedValidateError error
+	}{
+		{
+			scenario: "when access token is valid",
+		},
+		{
+			scenario:           "when token is not an access token",
+			refreshToken:       true,
+			expectedStartError: auth.ErrNotAuthorized,
+		},
+		{
+			scenario:              "when session ends the cookie stops working",
+			endSession:            true,
+			expectedValidateError: auth.ErrNotAuthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			_, payload := signUp(t, sut)
+			response, session := login(t, sut, payload, "browser")
+
+			token := response.AccessToken.Token
+			if tc.refreshToken {
+				token = response.RefreshToken.Token
+			}
+
+			// Action
+			secret, expiresAt, err := sut.service.StartAuthorizationSession(context.TODO(), token)
+
+			// Assert
+			if tc.expectedStartError != nil {
+				assert.ErrorIs(t, err, tc.expectedStartError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.WithinDuration(t, session.ExpiresAt, expiresAt, time.Second)
+
+			if tc.endSession {
+				assert.NoError(t, sut.service.Logout(context.TODO(), session.UserID, session.ID))
+			}
+
+			validated, err := sut.service.ValidateAuthorizationSession(context.TODO(), secret)
+			if tc.expectedValidateError != nil {
+				assert.ErrorIs(t, err, tc.expectedValidateError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, session.ID, validated.ID)
+			}
+		})
+	}
+}
+
+func TestConsent(t *testing.T) {
+	t.Parallel()
+
+	client := entity.Client{
+		ID:            uuid.NewString(),
+		Name:          "app",
+		RedirectURIs:  entity.StringList{"https://app.example.com/callback"},
+		AllowedScopes: entity.StringList{"profile", "email"},
+	}
+	request := schemas.AuthorizeRequest{
+		ResponseType:        auth.ResponseTypeCode,
+		ClientID:            client.ID,
+		RedirectURI:         client.RedirectURIs[0],
+		State:               "state",
+		CodeChallenge:       pkgauth.NewCodeChallenge(strings.Repeat("v", 43)),
+		CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+	}
+
+	tests := []struct {
+		scenario      string
+		otherUser     bool
+		reuse         bool
+		expectedError error
+	}{
+		{
+			scenario: "when user answers the consent",
+		},
+		{
+			scenario:      "when consent was requested to another user",
+			otherUser:     true,
+			expectedError: auth.ErrInvalidRequest,
+		},
+		{
+			scenario:      "when consent was already answered",
+			reuse:         true,
+			expectedError: auth.ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			userID := uuid.New()
+
+			consent, err := sut.service.RequestConsent(context.TODO(), client, userID, request)
+			assert.NoError(t, err)
+			assert.Equal(t, client.Name, consent.ClientName)
+			assert.Equal(t, "profile email", consent.Scope)
+
+			answeredBy := userID
+			if tc.otherUser {
+				answeredBy = uuid.New()
+			}
+
+			if tc.reuse {
+				_, err = sut.service.ConsumeConsent(context.TODO(), userID, consent.Challenge)
+				assert.NoError(t, err)
+			}
+
+			// Action
+			consented, err := sut.service.ConsumeConsent(context.TODO(), answeredBy, consent.Challenge)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, request, consented)
+			}
+		})
+	}
+}
+
+func TestRequestConsentValidatesTheRequest(t *testing.T) {
+	t.Parallel()
+
+	sut := newSut()
+	client := entity.Client{ID: uuid.NewString(), AllowedScopes: entity.StringList{"profile"}}
+
+	_, err := sut.service.RequestConsent(context.TODO(), client, uuid.New(), schemas.AuthorizeRequest{
+		ResponseType:        auth.ResponseTypeCode,
+		Scope:               "users:delete",
+		CodeChallenge:       pkgauth.NewCodeChallenge(strings.Repeat("v", 43)),
+		CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+	})
+	assert.ErrorIs(t, err, auth.ErrInvalidScope)
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	t.Parallel()
+
+	redirectURI := "https://app.example.com/callback"
+	verifier := strings.Repeat("v", 43)
+	client := entity.Client{
+		ID: uuid.NewString(), Name: "app", RedirectURIs: entity.StringList{redirectURI},
+		AllowedScopes: entity.StringList{"profile"},
+	}
+
+	tests := []struct {
+		scenario      string
+		client        entity.Client
+		redirectURI   string
+		codeVerifier  string
+		reuseCode     bool
+		expectedError error
+	}{
+		{
+			scenario:     "when code and verifier are valid should start a session for the client",
+			client:       client,
+			redirectURI:  redirectURI,
+			codeVerifier: verifier,
+		},
+		{
+			scenario:      "when verifier does not match the challenge",
+			client:        client,
+			redirectURI:   redirectURI,
+			codeVerifier:  strings.Repeat("x", 43),
+			expectedError: auth.ErrInvalidGrant,
+		},
+		{
+			scenario:      "when redirect uri does not match",
+			client:        client,
+			redirectURI:   "https://evil.example.com/callback",
+			codeVerifier:  verifier,
+			expectedError: auth.ErrInvalidGrant,
+		},
+		{
+			scenario:      "when code was issued to another client",
+			client:        entity.Client{ID: uuid.NewString()},
+			redirectURI:   redirectURI,
+			codeVerifier:  verifier,
+			expectedError: auth.ErrInvalidGrant,
+		},
+		{
+			scenario:      "when code was already used",
+			client:        client,
+			redirectURI:   redirectURI,
+			codeVerifier:  verifier,
+			reuseCode:     true,
+			expectedError: auth.ErrInvalidGrant,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, _ := signUp(t, sut)
+
+			code, err := sut.service.Authorize(context.TODO(), client, user.ID, schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				RedirectURI:         redirectURI,
+				Scope:               "profile",
+				CodeChallenge:       pkgauth.NewCodeChallenge(verifier),
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			})
+			assert.NoError(t, err)
+
+			request := schemas.TokenRequest{
+				GrantType:    auth.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectURI:  tc.redirectURI,
+				CodeVerifier: tc.codeVerifier,
+			}
+
+			if tc.reuseCode {
+				_, err = sut.service.ExchangeAuthorizationCode(context.TODO(), tc.client, request)
+				assert.NoError(t, err)
+			}
+
+			// Action
+			response, err := sut.service.ExchangeAuthorizationCode(context.TODO(), tc.client, request)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+
+				introspection := sut.service.IntrospectToken(context.TODO(), response.AccessToken.Token, "")
+				assert.True(t, introspection.Active)
+				assert.Equal(t, user.ID.String(), introspection.Subject)
+				assert.Equal(t, client.ID, introspection.ClientID)
+				assert.Equal(t, "profile", introspection.Scope)
+
+				_, err = sut.service.RefreshClientToken(context.TODO(), entity.Client{ID: "other"}, response.RefreshToken.Token)
+				assert.ErrorIs(t, err, auth.ErrInvalidGrant)
+
+				_, err = sut.service.RefreshAccessToken(
+					context.TODO(), schemas.RefreshToken{JwtToken: response.RefreshToken},
+				)
+				assert.ErrorIs(t, err, auth.ErrClientMismatch, "the first party refresh rejects the client sessions")
+
+				_, err = sut.service.RefreshClientToken(context.TODO(), client, response.RefreshToken.Token)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExchangeAuthorizationCodeChecksTheUser(t *testing.T) {
+	t.Parallel()
+
+	redirectURI := "https://app.example.com/callback"
+	verifier := strings.Repeat("v", 43)
+	client := entity.Client{ID: uuid.NewString(), Name: "app", RedirectURIs: entity.StringList{redirectURI}}
+
+	tests := []struct {
+		scenario string
+		options  []func(cfg *auth.Config)
+		arrange  func(t *testing.T, sut Sut, user *entity.User, payload schemas.SignUp)
+	}{
+		{
+			scenario: "when the user is inactive",
+			arrange: func(t *testing.T, sut Sut, user *entity.User, _ schemas.SignUp) {
+				t.Helper()
+
+				user.Active = false
+				assert.NoError(t, sut.userSvc.Save(context.TODO(), *user))
+			},
+		},
+		{
+			scenario: "when the email isn't verified",
+			options:  []func(cfg *auth.Config){func(cfg *auth.Config) { cfg.RequireEmailVerification = true }},
+		},
+		{
+			scenario: "when the account is locked",
+			options:  []func(cfg *auth.Config){withLockout(2, time.Minute)},
+			arrange: func(t *testing.T, sut Sut, _ *entity.User, payload schemas.SignUp) {
+				t.Helper()
+
+				lockAccount(t, sut, payload.Email, 2)
+			},
+		},
+		{
+			scenario: "when the password must be changed",
+			arrange: func(t *testing.T, sut Sut, user *entity.User, _ schemas.SignUp) {
+				t.Helper()
+
+				user.MustChangePassword = true
+				assert.NoError(t, sut.userSvc.Save(context.TODO(), *user))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(tc.options...)
+			user, payload := signUp(t, sut)
+
+			code, err := sut.service.Authorize(context.TODO(), client, user.ID, schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				RedirectURI:         redirectURI,
+				CodeChallenge:       pkgauth.NewCodeChallenge(verifier),
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			})
+			assert.NoError(t, err)
+
+			if tc.arrange != nil {
+				tc.arrange(t, sut, user, payload)
+			}
+
+			// Action
+			response, err := sut.service.ExchangeAuthorizationCode(context.TODO(), client, schemas.TokenRequest{
+				GrantType:    auth.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectURI:  redirectURI,
+				CodeVerifier: verifier,
+			})
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrInvalidGrant)
+			assert.Empty(t, response.AccessToken.Token)
+		})
+	}
+}
+
+func TestIssueClientToken(t *testing.T) {
+	t.Parallel()
+
+	client := entity.Client{
+		ID:            uuid.NewString(),
+		AllowedScopes: entity.StringList{"reports:read", "reports:write"},
+		TokenLifetime: 60,
+	}
+
+	tests := []struct {
+		scenario      string
+		client        entity.Client
+		scope         string
+		expectedScope string
+		expectedError error
+	}{
+		{
+			scenario:      "when scope is not requested every allowed scope is granted",
+			client:        client,
+			expectedScope: "reports:read reports:write",
+		},
+		{
+			scenario:      "when requested scope is allowed",
+			client:        client,
+			scope:         "reports:read",
+			expectedScope: "reports:read",
+		},
+		{
+			scenario:      "when requested scope is not allowed",
+			client:        client,
+			scope:         "reports:read users:delete",
+			expectedError: auth.ErrInvalidScope,
+		},
+		{
+			scenario:      "when client is public",
+			client:        entity.Client{ID: uuid.NewString(), Public: true},
+			expectedError: auth.ErrUnauthorizedClient,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+
+			// Action
+			response, err := sut.service.IssueClientToken(context.TODO(), tc.client, tc.scope)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Empty(t, response.RefreshToken.Token)
+			assert.LessOrEqual(t, response.AccessToken.ExpiresAt, time.Now().Add(time.Minute).Unix())
+
+			introspection := sut.service.IntrospectToken(context.TODO(), response.AccessToken.Token, "")
+			assert.True(t, introspection.Active)
+			assert.Equal(t, tc.client.ID, introspection.Subject)
+			assert.Equal(t, tc.client.ID, introspection.ClientID)
+			assert.Equal(t, tc.expectedScope, introspection.Scope)
+
+			_, err = sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+			assert.Error(t, err)
+
+			err = sut.service.RevokeToken(context.TODO(), uuid.NewString(), response.AccessToken.Token, "")
+			assert.ErrorIs(t, err, auth.ErrClientMismatch)
+
+			err = sut.service.RevokeToken(context.TODO(), tc.client.ID, response.AccessToken.Token, "")
+			assert.NoError(t, err)
+
+			introspection = sut.service.IntrospectToken(context.TODO(), response.AccessToken.Token, "")
+			assert.False(t, introspection.Active)
+		})
+	}
+}
+
+func TestOpenIDConnect(t *testing.T) {
+	t.Parallel()
+
+	redirectURI := "https://app.example.com/callback"
+	verifier := strings.Repeat("v", 43)
+	client := entity.Client{
+		ID: uuid.NewString(), Name: "app", RedirectURIs: entity.StringList{redirectURI},
+		AllowedScopes: entity.StringList{"openid", "profile", "email", "phone"},
+	}
+
+	tests := []struct {
+		scenario      string
+		scope         string
+		expectIDToken bool
+		expectedError error
+		expectedEmail bool
+		expectedName  bool
+		expectedPhone bool
+	}{
+		{
+			scenario:      "when openid and email scopes are requested",
+			scope:         "openid email",
+			expectIDToken: true,
+			expectedEmail: true,
+		},
+		{
+			scenario:      "when every profile scope is requested",
+			scope:         "openid profile email phone",
+			expectIDToken: true,
+			expectedEmail: true,
+			expectedName:  true,
+			expectedPhone: true,
+		},
+		{
+			scenario:      "when openid scope is not requested",
+			scope:         "profile",
+			expectedError: auth.ErrInsufficientScope,
+		},
+	}
+
+	keyRing, publicKey := newES256KeyRing(t)
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSutWithKeyRing(keyRing)
+			user, _ := signUp(t, sut)
+
+			code, err := sut.service.Authorize(context.TODO(), client, user.ID, schemas.AuthorizeRequest{
+				ResponseType:        auth.ResponseTypeCode,
+				RedirectURI:         redirectURI,
+				Scope:               tc.scope,
+				Nonce:               "my-nonce",
+				CodeChallenge:       pkgauth.NewCodeChallenge(verifier),
+				CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+			})
+			assert.NoError(t, err)
+
+			response, err := sut.service.ExchangeAuthorizationCode(context.TODO(), client, schemas.TokenRequest{
+				GrantType:    auth.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectURI:  redirectURI,
+				CodeVerifier: verifier,
+			})
+			assert.NoError(t, err)
+
+			session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+			assert.NoError(t, err)
+
+			// Action
+			info, err := sut.service.UserInfo(context.TODO(), session)
+
+			// Assert
+			assert.Equal(t, tc.expectIDToken, response.IDToken != "")
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), info.Subject)
+			assert.Equal(t, tc.expectedEmail, info.Email != "")
+			assert.Equal(t, tc.expectedName, info.Name != "")
+			assert.Equal(t, tc.expectedPhone, info.PhoneNumber != "")
+
+			var claims pkgauth.IDTokenClaims
+
+			_, err = jwt.ParseWithClaims(response.IDToken, &claims, func(*jwt.Token) (interface{}, error) {
+				return publicKey, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, testIssuer, claims.Issuer)
+			assert.Equal(t, client.ID, claims.Audience)
+			assert.Equal(t, "my-nonce", claims.Nonce)
+			assert.Equal(t, info, claims.UserInfo)
+		})
+	}
+}
+
+func TestOpenIDConnectRequiresAnAsymmetricKey(t *testing.T) {
+	t.Parallel()
+
+	sut := newSut()
+	client := entity.Client{
+		ID: uuid.NewString(), RedirectURIs: entity.StringList{"https://app.example.com/callback"},
+		AllowedScopes: entity.StringList{"openid"},
+	}
+
+	_, err := sut.service.Authorize(context.TODO(), client, uuid.New(), schemas.AuthorizeRequest{
+		ResponseType:        auth.ResponseTypeCode,
+		RedirectURI:         client.RedirectURIs[0],
+		Scope:               "openid",
+		CodeChallenge:       pkgauth.NewCodeChallenge(strings.Repeat("v", 43)),
+		CodeChallengeMethod: pkgauth.CodeChallengeMethodS256,
+	})
+	assert.ErrorIs(t, err, auth.ErrInvalidScope)
+}
+
+// newES256KeyRing returns a ring with an asymmetric key, the id tokens can't be signed with the HMAC key.
+func newES256KeyRing(t *testing.T) (*pkgauth.KeyRing, *ecdsa.PublicKey) {
+	t.Helper()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	assert.NoError(t, err)
+
+	key, err := pkgauth.ParseSigningKey(
+		"", pkgauth.AlgorithmES256, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	)
+	assert.NoError(t, err)
+
+	return pkgauth.NewKeyRing(key), &private.PublicKey
+}
+
+func TestOpenIDConfiguration(t *testing.T) {
+	t.Parallel()
+
+	keyRing, _ := newES256KeyRing(t)
+
+	tests := []struct {
+		scenario           string
+		sut                Sut
+		expectedAlgorithms []string
+		expectOpenID       bool
+	}{
+		{
+			scenario:           "when signing key is asymmetric",
+			sut:                newSutWithKeyRing(keyRing),
+			expectedAlgorithms: []string{pkgauth.AlgorithmES256},
+			expectOpenID:       true,
+		},
+		{
+			scenario:           "when signing key is symmetric openid is not supported",
+			sut:                newSut(),
+			expectedAlgorithms: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			configuration := tc.sut.service.OpenIDConfiguration()
+
+			// Assert
+			assert.Equal(t, testIssuer, configuration.Issuer)
+			assert.Equal(t, testIssuer+"/.well-known/jwks.json", configuration.JWKSURI)
+			assert.Equal(t, tc.expectedAlgorithms, configuration.IDTokenSigningAlgValuesSupported)
+			assert.Equal(t, tc.expectOpenID, hasString(configuration.ScopesSupported, auth.ScopeOpenID))
+		})
+	}
+}
+
+func hasString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// enrollTOTP confirms the enrollment with the code of the previous period, so the codes of the current
+// period are still unused when the test starts.
+func enrollTOTP(t *testing.T, sut Sut, userID uuid.UUID) (string, string) {
+	t.Helper()
+
+	enrollment, err := sut.service.EnrollTOTP(context.TODO(), userID)
+	assert.NoError(t, err)
+
+	code, err := pkgauth.TOTPCode(enrollment.Secret, time.Now().Add(-pkgauth.TOTPPeriod))
+	assert.NoError(t, err)
+
+	_, err = sut.service.ConfirmTOTP(context.TODO(), userID, code)
+	assert.NoError(t, err)
+
+	return enrollment.Secret, code
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+
+	// Action
+	enrollment, err := sut.service.EnrollTOTP(context.TODO(), user.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.TOTPEnabled)
+	assert.NotContains(t, stored.TOTPSecret, enrollment.Secret)
+
+	_, err = sut.service.ConfirmTOTP(context.TODO(), user.ID, "000000")
+	assert.ErrorIs(t, err, auth.ErrInvalidMFACode)
+
+	code, err := pkgauth.TOTPCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	recoveryCodes, err := sut.service.ConfirmTOTP(context.TODO(), user.ID, code)
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, pkgauth.RecoveryCodeCount)
+
+	_, err = sut.service.EnrollTOTP(context.TODO(), user.ID)
+	assert.ErrorIs(t, err, auth.ErrMFAAlreadyEnabled)
+}
+
+func TestLoginMFA(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	secret, usedCode := enrollTOTP(t, sut, user.ID)
+
+	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, loginResponse.AccessToken.Token)
+	assert.NotEmpty(t, loginResponse.MFAChallenge)
+	assert.Equal(t, []string{auth.MFAMethodTOTP, auth.MFAMethodRecoveryCode}, loginResponse.MFAMethods)
+
+	// The code used on the confirmation can't be replayed.
+	nextCode, err := pkgauth.TOTPCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	// Action
+	_, replayErr := sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: usedCode},
+	)
+	response, err := sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: nextCode},
+	)
+
+	// Assert
+	assert.ErrorIs(t, replayErr, auth.ErrInvalidMFACode)
+	assert.NoError(t, err)
+
+	session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+
+	_, err = sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: nextCode},
+	)
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized)
+}
+
+func TestLoginMFADropsChallengeAfterTooManyAttempts(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	secret, _ := enrollTOTP(t, sut, user.ID)
+
+	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+
+	// Action
+	for i := 0; i < 5; i++ {
+		_, err = sut.service.LoginMFA(
+			context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: "000000"},
+		)
+		assert.ErrorIs(t, err, auth.ErrInvalidMFACode)
+	}
+
+	// Assert
+	nextCode, err := pkgauth.TOTPCode(secret, time.Now().Add(pkgauth.TOTPPeriod))
+	assert.NoError(t, err)
+
+	_, err = sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: nextCode},
+	)
+	assert.ErrorIs(t, err, auth.ErrTooManyRequests)
+
+	_, err = sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: nextCode},
+	)
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the challenge is dropped")
+
+	loginResponse, err = sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+
+	_, err = sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: nextCode},
+	)
+	assert.ErrorIs(t, err, auth.ErrTooManyRequests, "a new challenge doesn't reset the attempts of the user")
+}
+
+func TestLoginMFAConcurrentAttempts(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	enrollTOTP(t, sut, user.ID)
+
+	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		invalid int
+	)
+
+	// Action
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := sut.service.LoginMFA(
+				context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: "000000"},
+			)
+
+			if errors.Is(err, auth.ErrInvalidMFACode) {
+				mu.Lock()
+				invalid++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 5, invalid, "only the allowed attempts check the code")
+}
+
+func TestLoginMFAAcceptsTheCodeOnlyOnceConcurrently(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	secret, _ := enrollTOTP(t, sut, user.ID)
+
+	code, err := pkgauth.TOTPCode(secret, time.Now().Add(pkgauth.TOTPPeriod))
+	assert.NoError(t, err)
+
+	challenges := make([]string, 5)
+	for i := range challenges {
+		loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+			Email:    payload.Email,
+			Password: payload.Password,
+		})
+		assert.NoError(t, err)
+
+		challenges[i] = loginResponse.MFAChallenge
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+
+	// Action
+	for _, challenge := range challenges {
+		wg.Add(1)
+
+		go func(challenge string) {
+			defer wg.Done()
+
+			_, err := sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{Challenge: challenge, Code: code})
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(challenge)
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, accepted)
+}
+
+func TestLoginMFAKeepsTheFailedLoginsUntilTheSecondFactor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(withLockout(2, time.Minute))
+	user, payload := signUp(t, sut)
+	secret, _ := enrollTOTP(t, sut, user.ID)
+
+	wrong := schemas.Login{Email: payload.Email, Password: "wrong-password"}
+	valid := schemas.Login{Email: payload.Email, Password: payload.Password}
+
+	_, err := sut.service.Login(context.TODO(), wrong)
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized)
+
+	loginResponse, err := sut.service.Login(context.TODO(), valid)
+	assert.NoError(t, err)
+
+	// Action
+	_, err = sut.service.Login(context.TODO(), wrong)
+	lockedErr := err
+
+	// Assert
+	assert.ErrorIs(t, lockedErr, auth.ErrAccountLocked, "the valid password doesn't reset the failed logins")
+
+	code, err := pkgauth.TOTPCode(secret, time.Now().Add(pkgauth.TOTPPeriod))
+	assert.NoError(t, err)
+
+	_, err = sut.service.LoginMFA(
+		context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: code},
+	)
+	assert.ErrorIs(t, err, auth.ErrAccountLocked)
+}
+
+func TestManageTOTPLimitsTheAttempts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		enabled  bool
+		action   func(sut Sut, userID uuid.UUID, code string) error
+	}{
+		{
+			scenario: "when confirming the enrollment",
+			action: func(sut Sut, userID uuid.UUID, code string) error {
+				_, err := sut.service.ConfirmTOTP(context.TODO(), userID, code)
+
+				return err
+			},
+		},
+		{
+			scenario: "when disabling the second factor",
+			enabled:  true,
+			action: func(sut Sut, userID uuid.UUID, code string) error {
+				return sut.service.DisableTOTP(context.TODO(), userID, code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, _ := signUp(t, sut)
+
+			var secret string
+			if tc.enabled {
+				secret, _ = enrollTOTP(t, sut, user.ID)
+			} else {
+				enrollment, err := sut.service.EnrollTOTP(context.TODO(), user.ID)
+				assert.NoError(t, err)
+
+				secret = enrollment.Secret
+			}
+
+			// Action
+			for i := 0; i < 5; i++ {
+				assert.ErrorIs(t, tc.action(sut, user.ID, "000000"), auth.ErrInvalidMFACode)
+			}
+
+			// Assert
+			code, err := pkgauth.TOTPCode(secret, time.Now())
+			assert.NoError(t, err)
+			assert.ErrorIs(t, tc.action(sut, user.ID, code), auth.ErrTooManyRequests)
+		})
+	}
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func registerWebAuthn(t *testing.T, sut Sut, userID uuid.UUID, password string) *authtest.Authenticator {
+	t.Helper()
+
+	authenticator, err := authtest.NewAuthenticator(testRPID, testOrigin)
+	assert.NoError(t, err)
+
+	options, err := sut.service.BeginWebAuthnRegistration(
+		context.TODO(), userID, schemas.ReAuthentication{CurrentPassword: password},
+	)
+	assert.NoError(t, err)
+
+	clientData, attestation := authenticator.Register(options.Challenge)
+
+	_, _, err = sut.service.FinishWebAuthnRegistration(context.TODO(), userID, schemas.WebAuthnRegistration{
+		Name:              "Laptop",
+		ClientDataJSON:    encode(clientData),
+		AttestationObject: encode(attestation),
+	})
+	assert.NoError(t, err)
+
+	return authenticator
+}
+
+func assertWebAuthn(authenticator *authtest.Authenticator, challenge string) schemas.WebAuthnAssertion {
+	clientData, authData, signature := authenticator.Assert(challenge)
+
+	return schemas.WebAuthnAssertion{
+		ID:                encode(authenticator.CredentialID),
+		ClientDataJSON:    encode(clientData),
+		AuthenticatorData: encode(authData),
+		Signature:         encode(signature),
+	}
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	reAuth := schemas.ReAuthentication{CurrentPassword: payload.Password}
+	authenticator, err := authtest.NewAuthenticator(testRPID, testOrigin)
+	assert.NoError(t, err)
+
+	clientData, attestation := authenticator.Register("not-started")
+	registration := schemas.WebAuthnRegistration{
+		ClientDataJSON:    encode(clientData),
+		AttestationObject: encode(attestation),
+	}
+
+	_, _, notStartedErr := sut.service.FinishWebAuthnRegistration(context.TODO(), user.ID, registration)
+
+	options, err := sut.service.BeginWebAuthnRegistration(context.TODO(), user.ID, reAuth)
+	assert.NoError(t, err)
+
+	clientData, attestation = authenticator.Register(options.Challenge)
+	registration = schemas.WebAuthnRegistration{
+		ClientDataJSON:    encode(clientData),
+		AttestationObject: encode(attestation),
+	}
+
+	// Action
+	credential, recoveryCodes, err := sut.service.FinishWebAuthnRegistration(context.TODO(), user.ID, registration)
+
+	// Assert
+	assert.ErrorIs(t, notStartedErr, auth.ErrInvalidWebAuthnCredential)
+	assert.NoError(t, err)
+	assert.Equal(t, encode(authenticator.CredentialID), credential.ID)
+	assert.Equal(t, "Security key", credential.Name)
+	assert.Len(t, recoveryCodes, pkgauth.RecoveryCodeCount)
+	assert.Equal(t, encode(user.ID[:]), options.User.ID)
+	assert.Equal(t, testRPID, options.RelyingParty.ID)
+
+	_, _, err = sut.service.FinishWebAuthnRegistration(context.TODO(), user.ID, registration)
+	assert.ErrorIs(t, err, auth.ErrInvalidWebAuthnCredential, "the challenge must be used only once")
+
+	options, err = sut.service.BeginWebAuthnRegistration(context.TODO(), user.ID, reAuth)
+	assert.NoError(t, err)
+	assert.Equal(t, credential.ID, options.ExcludeCredentials[0].ID)
+
+	clientData, attestation = authenticator.Register(options.Challenge)
+	_, _, err = sut.service.FinishWebAuthnRegistration(context.TODO(), user.ID, schemas.WebAuthnRegistration{
+		ClientDataJSON:    encode(clientData),
+		AttestationObject: encode(attestation),
+	})
+	assert.ErrorIs(t, err, auth.ErrWebAuthnCredentialAlreadyRegistered)
+
+	credentials, err := sut.service.ListWebAuthnCredentials(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, credentials, 1)
+
+	other, otherPayload := signUp(t, sut)
+	err = sut.service.DeleteWebAuthnCredential(
+		context.TODO(), other.ID, credential.ID, schemas.ReAuthentication{CurrentPassword: otherPayload.Password},
+	)
+	assert.ErrorIs(t, err, auth.ErrWebAuthnCredentialNotFound)
+
+	err = sut.service.DeleteWebAuthnCredential(context.TODO(), user.ID, credential.ID, reAuth)
+	assert.NoError(t, err)
+
+	credentials, err = sut.service.ListWebAuthnCredentials(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, credentials)
+}
+
+func TestWebAuthnRegistrationRequiresReAuthentication(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario    string
+		reAuth      func(password, code string) schemas.ReAuthentication
+		enableTOTP  bool
+		expectedErr error
+	}{
+		{
+			scenario:    "when nothing is sent",
+			reAuth:      func(string, string) schemas.ReAuthentication { return schemas.ReAuthentication{} },
+			expectedErr: auth.ErrReAuthRequired,
+		},
+		{
+			scenario: "when the password is wrong",
+			reAuth: func(string, string) schemas.ReAuthentication {
+				return schemas.ReAuthentication{CurrentPassword: "wrong-password"}
+			},
+			expectedErr: auth.ErrInvalidPassword,
+		},
+		{
+			scenario: "when the totp code is wrong",
+			reAuth: func(string, string) schemas.ReAuthentication {
+				return schemas.ReAuthentication{Code: "000000"}
+			},
+			enableTOTP:  true,
+			expectedErr: auth.ErrInvalidMFACode,
+		},
+		{
+			scenario: "when the totp code is sent without totp enabled",
+			reAuth: func(string, string) schemas.ReAuthentication {
+				return schemas.ReAuthentication{Code: "000000"}
+			},
+			expectedErr: auth.ErrReAuthRequired,
+		},
+		{
+			scenario: "when the password is valid",
+			reAuth: func(password, _ string) schemas.ReAuthentication {
+				return schemas.ReAuthentication{CurrentPassword: password}
+			},
+		},
+		{
+			scenario: "when the totp code is valid",
+			reAuth: func(_, code string) schemas.ReAuthentication {
+				return schemas.ReAuthentication{Code: code}
+			},
+			enableTOTP: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+
+			var code string
+
+			if tc.enableTOTP {
+				secret, _ := enrollTOTP(t, sut, user.ID)
+
+				var err error
+				code, err = pkgauth.TOTPCode(secret, time.Now())
+				assert.NoError(t, err)
+			}
+
+			reAuth := tc.reAuth(payload.Password, code)
+
+			// Action
+			_, beginErr := sut.service.BeginWebAuthnRegistration(context.TODO(), user.ID, reAuth)
+			deleteErr := sut.service.DeleteWebAuthnCredential(context.TODO(), user.ID, "credential", reAuth)
+
+			// Assert
+			assert.ErrorIs(t, beginErr, tc.expectedErr)
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, deleteErr, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestRecoveryPasswordConsumesTheToken(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+
+	token, err := sut.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+	assert.NoError(t, err)
+
+	rejectedErr := sut.service.RecoveryPassword(context.TODO(), token.Token, "abc")
+
+	// Action
+	err = sut.service.RecoveryPassword(context.TODO(), token.Token, "recovered-password")
+
+	// Assert
+	assert.Error(t, rejectedErr)
+	assert.NoError(t, err, "the token is restored when the new password is rejected")
+
+	err = sut.service.RecoveryPassword(context.TODO(), token.Token, "another-password")
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the token is only used once")
+}
+
+func TestRecoveryPasswordConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+
+	token, err := sut.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+	assert.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+	)
+
+	// Action
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			err := sut.service.RecoveryPassword(context.TODO(), token.Token, fmt.Sprintf("recovered-password-%d", i))
+			if err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, successes)
+}
+
+func TestRecoveryPasswordRevokesWebAuthnCredentials(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	authenticator := registerWebAuthn(t, sut, user.ID, payload.Password)
+
+	token, err := sut.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+	assert.NoError(t, err)
+
+	// Action
+	err = sut.service.RecoveryPassword(context.TODO(), token.Token, "recovered-password")
+
+	// Assert
+	assert.NoError(t, err)
+
+	event := waitEvent(t, sut, "webauthn-credentials-revoked")
+	assert.Contains(t, string(event.Data), encode(authenticator.CredentialID))
+
+	credentials, err := sut.service.ListWebAuthnCredentials(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, credentials)
+
+	stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, stored.RecoveryCodes, "the recovery codes go away with the last second factor")
+}
+
+func TestWebAuthnLogin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario     string
+		userVerified bool
+		reuse        bool
+		expectedErr  error
+	}{
+		{
+			scenario:     "when the authenticator verified the user should start a session",
+			userVerified: true,
+		},
+		{
+			scenario:    "when the authenticator didn't verify the user should return an error",
+			expectedErr: auth.ErrInvalidWebAuthnCredential,
+		},
+		{
+			scenario:     "when the challenge was already used should return an error",
+			userVerified: true,
+			reuse:        true,
+			expectedErr:  auth.ErrNotAuthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+			authenticator := registerWebAuthn(t, sut, user.ID, payload.Password)
+			authenticator.UserVerified = tc.userVerified
+
+			options, err := sut.service.BeginWebAuthnLogin(context.TODO())
+			assert.NoError(t, err)
+			assert.Empty(t, options.AllowCredentials)
+
+			loginPayload := schemas.WebAuthnLogin{
+				Challenge:  options.Challenge,
+				Credential: assertWebAuthn(authenticator, options.Challenge),
+			}
+			loginPayload.Credential.UserHandle = encode(user.ID[:])
+
+			if tc.reuse {
+				_, err := sut.service.FinishWebAuthnLogin(context.TODO(), loginPayload)
+				assert.NoError(t, err)
+
+				loginPayload.Credential = assertWebAuthn(authenticator, options.Challenge)
+			}
+
+			// Action
+			response, err := sut.service.FinishWebAuthnLogin(context.TODO(), loginPayload)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+
+			if tc.expectedErr == nil {
+				session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+				assert.NoError(t, err)
+				assert.Equal(t, user.ID, session.UserID)
+			}
+		})
+	}
+}
+
+func TestLoginMFAWithWebAuthn(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	authenticator := registerWebAuthn(t, sut, user.ID, payload.Password)
+
+	loginResponse, err := sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, loginResponse.AccessToken.Token)
+	assert.Equal(t, []string{auth.MFAMethodWebAuthn, auth.MFAMethodRecoveryCode}, loginResponse.MFAMethods)
+	assert.Equal(t, encode(authenticator.CredentialID), loginResponse.WebAuthnOptions.AllowCredentials[0].ID)
+
+	challenge := loginResponse.WebAuthnOptions.Challenge
+
+	// A clone of the authenticator signs with a counter that was already seen.
+	cloned := *authenticator
+	assertion := assertWebAuthn(authenticator, challenge)
+
+	// Action
+	_, totpErr := sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge: loginResponse.MFAChallenge,
+		Code:      "000000",
+	})
+	response, err := sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge:  loginResponse.MFAChallenge,
+		Method:     auth.MFAMethodWebAuthn,
+		Credential: &assertion,
+	})
+
+	// Assert
+	assert.ErrorIs(t, totpErr, auth.ErrMFANotEnabled)
+	assert.NoError(t, err)
+
+	session, err := sut.service.ValidateAccessToken(context.TODO(), response.AccessToken.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+
+	loginResponse, err = sut.service.Login(context.TODO(), schemas.Login{
+		Email:    payload.Email,
+		Password: payload.Password,
+	})
+	assert.NoError(t, err)
+
+	clonedAssertion := assertWebAuthn(&cloned, loginResponse.WebAuthnOptions.Challenge)
+	_, err = sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge:  loginResponse.MFAChallenge,
+		Method:     auth.MFAMethodWebAuthn,
+		Credential: &clonedAssertion,
+	})
+	assert.ErrorIs(t, err, auth.ErrInvalidWebAuthnCredential)
+}
+
+func TestLoginMFAWithRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	enrollment, err := sut.service.EnrollTOTP(context.TODO(), user.ID)
+	assert.NoError(t, err)
+
+	code, err := pkgauth.TOTPCode(enrollment.Secret, time.Now())
+	assert.NoError(t, err)
+
+	recoveryCodes, err := sut.service.ConfirmTOTP(context.TODO(), user.ID, code)
+	assert.NoError(t, err)
+
+	login := func() schemas.LoginResponse {
+		response, err := sut.service.Login(context.TODO(), schemas.Login{
+			Email:    payload.Email,
+			Password: payload.Password,
+		})
+		assert.NoError(t, err)
+
+		return response
+	}
+
+	// Action
+	response, err := sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge: login().MFAChallenge,
+		Method:    auth.MFAMethodRecoveryCode,
+		Code:      recoveryCodes[0],
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken.Token)
+
+	for {
+		event := <-sut.eventChannel
+		if event.Action != "recovery-code-used" {
+			continue
+		}
+
+		var data map[string]string
+		assert.NoError(t, json.Unmarshal(event.Data, &data))
+		assert.Equal(t, user.ID.String(), data["user_id"])
+		assert.Equal(t, "9", data["remaining_codes"])
+
+		break
+	}
+
+	_, err = sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge: login().MFAChallenge,
+		Method:    auth.MFAMethodRecoveryCode,
+		Code:      recoveryCodes[0],
+	})
+	assert.ErrorIs(t, err, auth.ErrInvalidMFACode, "a recovery code must be used only once")
+
+	regenerated, err := sut.service.RegenerateRecoveryCodes(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, regenerated, pkgauth.RecoveryCodeCount)
+
+	_, err = sut.service.LoginMFA(context.TODO(), schemas.LoginMFA{
+		Challenge: login().MFAChallenge,
+		Method:    auth.MFAMethodRecoveryCode,
+		Code:      recoveryCodes[1],
+	})
+	assert.ErrorIs(t, err, auth.ErrInvalidMFACode, "the previous codes must stop working")
+}
+
+func TestRecoveryCodesAreDroppedWithTheLastSecondFactor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+	secret, _ := enrollTOTP(t, sut, user.ID)
+
+	code, err := pkgauth.TOTPCode(secret, time.Now().Add(pkgauth.TOTPPeriod))
+	assert.NoError(t, err)
+
+	// Action
+	err = sut.service.DisableTOTP(context.TODO(), user.ID, code)
+
+	// Assert
+	assert.NoError(t, err)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, stored.RecoveryCodes)
+
+	_, err = sut.service.RegenerateRecoveryCodes(context.TODO(), user.ID)
+	assert.ErrorIs(t, err, auth.ErrMFANotEnabled)
+}
+
+func TestEmailVerification(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(func(cfg *auth.Config) { cfg.RequireEmailVerification = true })
+
+	payload := schemas.SignUp{
+		Name:     gofakeit.Name(),
+		Email:    gofakeit.Email(),
+		Phone:    gofakeit.Phone(),
+		Password: gofakeit.Password(true, true, true, true, true, 10),
+	}
+
+	user, err := sut.service.SignUp(context.TODO(), payload)
+	assert.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+
+	var token string
+
+	for token == "" {
+		event := <-sut.eventChannel
+		if event.Action != "email-verification" {
+			continue
+		}
+
+		var data struct {
+			User              map[string]string `json:"user"`
+			VerificationToken string            `json:"verification_token"`
+		}
+		assert.NoError(t, json.Unmarshal(event.Data, &data))
+		assert.Equal(t, user.Email, data.User["email"])
+
+		token = data.VerificationToken
+	}
+
+	credentials := schemas.Login{Email: payload.Email, Password: payload.Password}
+
+	response, loginErr := sut.service.Login(context.TODO(), credentials)
+	resendErr := sut.service.SendEmailVerificationToken(
+		context.TODO(), schemas.SendEmailVerificationPayload{Email: payload.Email},
+	)
+
+	// Action
+	err = sut.service.VerifyEmail(context.TODO(), token)
+
+	// Assert
+	assert.ErrorIs(t, loginErr, auth.ErrEmailNotVerified)
+	assert.Equal(t, "Email is not verified", response.Message)
+	assert.ErrorIs(t, resendErr, auth.ErrTooManyRequests)
+	assert.NoError(t, err)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.EmailVerified)
+
+	err = sut.service.VerifyEmail(context.TODO(), token)
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the token must be used only once")
+
+	err = sut.service.SendEmailVerificationToken(
+		context.TODO(), schemas.SendEmailVerificationPayload{Email: payload.Email},
+	)
+	assert.ErrorIs(t, err, auth.ErrEmailVerified)
+
+	response, err = sut.service.Login(context.TODO(), credentials)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken.Token)
+}
+
+func TestEmailTokensAreBoundToTheEmail(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		token    func(t *testing.T, sut Sut, payload schemas.SignUp) string
+		action   func(sut Sut, token string) error
+	}{
+		{
+			scenario: "when the email is verified",
+			token: func(t *testing.T, sut Sut, payload schemas.SignUp) string {
+				t.Helper()
+
+				user, err := sut.service.SignUp(context.TODO(), payload)
+				assert.NoError(t, err)
+
+				var data struct {
+					VerificationToken string `json:"verification_token"`
+				}
+				assert.NoError(t, json.Unmarshal(waitEvent(t, sut, "email-verification").Data, &data))
+
+				_, err = sut.userSvc.Update(context.TODO(), user.ID, schemas.UpdateUserPayload{Email: gofakeit.Email()})
+				assert.NoError(t, err)
+
+				return data.VerificationToken
+			},
+			action: func(sut Sut, token string) error {
+				return sut.service.VerifyEmail(context.TODO(), token)
+			},
+		},
+		{
+			scenario: "when the magic link is used",
+			token: func(t *testing.T, sut Sut, payload schemas.SignUp) string {
+				t.Helper()
+
+				user, err := sut.service.SignUp(context.TODO(), payload)
+				assert.NoError(t, err)
+				drainSignUpEvents(sut)
+
+				token := sendMagicLink(t, sut, payload.Email)
+
+				_, err = sut.userSvc.Update(context.TODO(), user.ID, schemas.UpdateUserPayload{Email: gofakeit.Email()})
+				assert.NoError(t, err)
+
+				return token
+			},
+			action: func(sut Sut, token string) error {
+				_, err := sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token})
+
+				return err
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			payload := schemas.SignUp{
+				Name:     gofakeit.Name(),
+				Email:    gofakeit.Email(),
+				Phone:    gofakeit.Phone(),
+				Password: gofakeit.Password(true, true, true, true, true, 10),
+			}
+			token := tc.token(t, sut, payload)
+
+			// Action
+			err := tc.action(sut, token)
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the token was sent to the previous email")
+		})
+	}
+}
+
+func TestSignUpWhenTheVerificationCantBeSent(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSutWithKeyRing(pkgauth.NewKeyRing())
+
+	// Action
+	user, err := sut.service.SignUp(context.TODO(), schemas.SignUp{
+		Name:     gofakeit.Name(),
+		Email:    gofakeit.Email(),
+		Phone:    gofakeit.Phone(),
+		Password: gofakeit.Password(true, true, true, true, true, 10),
+	})
+
+	// Assert
+	assert.NoError(t, err)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, stored.Email)
+}
+
+// sendPhoneVerificationCode returns the code published for the sms sender.
+func sendPhoneVerificationCode(t *testing.T, sut Sut, userID uuid.UUID) string {
+	t.Helper()
+
+	err := sut.service.SendPhoneVerificationCode(context.TODO(), userID)
+	assert.NoError(t, err)
+
+	event := <-sut.eventChannel
+	assert.Equal(t, "phone-verification", event.Action)
+
+	var data struct {
+		User             map[string]string `json:"user"`
+		VerificationCode string            `json:"verification_code"`
+	}
+	assert.NoError(t, json.Unmarshal(event.Data, &data))
+	assert.Equal(t, userID.String(), data.User["id"])
+
+	return data.VerificationCode
+}
+
+func TestPhoneVerification(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, _ := signUp(t, sut)
+
+	code := sendPhoneVerificationCode(t, sut, user.ID)
+
+	resendErr := sut.service.SendPhoneVerificationCode(context.TODO(), user.ID)
+	wrongCodeErr := sut.service.VerifyPhone(context.TODO(), user.ID, wrongPhoneCode(code))
+
+	// Action
+	err := sut.service.VerifyPhone(context.TODO(), user.ID, code)
+
+	// Assert
+	assert.Regexp(t, "^[0-9]{6}$", code)
+	assert.ErrorIs(t, resendErr, auth.ErrTooManyRequests)
+	assert.ErrorIs(t, wrongCodeErr, auth.ErrInvalidPhoneCode)
+	assert.NoError(t, err)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.PhoneVerified())
+
+	err = sut.service.VerifyPhone(context.TODO(), user.ID, code)
+	assert.ErrorIs(t, err, auth.ErrPhoneVerified)
+
+	err = sut.service.SendPhoneVerificationCode(context.TODO(), user.ID)
+	assert.ErrorIs(t, err, auth.ErrPhoneVerified)
+}
+
+func TestPhoneVerificationRejectsCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		arrange  func(t *testing.T, sut Sut, userID uuid.UUID, code string)
+	}{
+		{
+			scenario: "when there were too many wrong attempts",
+			arrange: func(t *testing.T, sut Sut, userID uuid.UUID, code string) {
+				t.Helper()
+
+				for i := 0; i < 5; i++ {
+					err := sut.service.VerifyPhone(context.TODO(), userID, wrongPhoneCode(code))
+					assert.ErrorIs(t, err, auth.ErrInvalidPhoneCode)
+				}
+			},
+		},
+		{
+			scenario: "when there were too many concurrent wrong attempts",
+			arrange: func(t *testing.T, sut Sut, userID uuid.UUID, code string) {
+				t.Helper()
+
+				var wg sync.WaitGroup
+
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						err := sut.service.VerifyPhone(context.TODO(), userID, wrongPhoneCode(code))
+						assert.ErrorIs(t, err, auth.ErrInvalidPhoneCode)
+					}()
+				}
+
+				wg.Wait()
+			},
+		},
+		{
+			scenario: "when the phone changed after the code was sent",
+			arrange: func(t *testing.T, sut Sut, userID uuid.UUID, code string) {
+				t.Helper()
+
+				_, err := sut.userSvc.Update(context.TODO(), userID, schemas.UpdateUserPayload{Phone: "5511912345678"})
+				assert.NoError(t, err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, _ := signUp(t, sut)
+
+			code := sendPhoneVerificationCode(t, sut, user.ID)
+			tc.arrange(t, sut, user.ID, code)
+
+			// Action
+			err := sut.service.VerifyPhone(context.TODO(), user.ID, code)
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrInvalidPhoneCode)
+
+			stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+			assert.NoError(t, err)
+			assert.False(t, stored.PhoneVerified())
+		})
+	}
+}
+
+func wrongPhoneCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+
+	return "000000"
+}
+
+// sendMagicLink returns the login token published for the email sender.
+func sendMagicLink(t *testing.T, sut Sut, email string) string {
+	t.Helper()
+
+	err := sut.service.SendMagicLink(context.TODO(), schemas.SendMagicLinkPayload{Email: email})
+	assert.NoError(t, err)
+
+	event := <-sut.eventChannel
+	assert.Equal(t, "magic-link", event.Action)
+
+	var data struct {
+		LoginToken string `json:"login_token"`
+	}
+	assert.NoError(t, json.Unmarshal(event.Data, &data))
+
+	return data.LoginToken
+}
+
+func TestLoginMagicLink(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(func(cfg *auth.Config) { cfg.RequireEmailVerification = true })
+	user, payload := signUp(t, sut)
+
+	token := sendMagicLink(t, sut, payload.Email)
+
+	// Action
+	response, err := sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token, Device: "phone"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.AccessToken.Token)
+	assert.NotEmpty(t, response.RefreshToken.Token)
+
+	loginEvent := <-sut.eventChannel
+	assert.Equal(t, "login", loginEvent.Action)
+
+	stored, err := sut.userSvc.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.EmailVerified, "the token was delivered to the email")
+
+	response, err = sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token})
+	assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the token must be used only once")
+	assert.Empty(t, response.AccessToken.Token)
+}
+
+func TestLoginMagicLinkConsumesTheTokenOnce(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	_, payload := signUp(t, sut)
+
+	token := sendMagicLink(t, sut, payload.Email)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		logins int
+	)
+
+	// Action
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			response, err := sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token})
+			if err == nil && response.AccessToken.Token != "" {
+				mu.Lock()
+				logins++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	assert.Equal(t, 1, logins)
+	waitEvent(t, sut, "login")
+}
+
+func TestLoginMagicLinkRequiresSecondFactor(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	enrollTOTP(t, sut, user.ID)
+
+	token := sendMagicLink(t, sut, payload.Email)
+
+	// Action
+	response, err := sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, response.AccessToken.Token)
+	assert.NotEmpty(t, response.MFAChallenge)
+	assert.Contains(t, response.MFAMethods, auth.MFAMethodTOTP)
+}
+
+func withLockout(attempts int, duration time.Duration) func(cfg *auth.Config) {
+	return func(cfg *auth.Config) {
+		cfg.LoginMaxAttempts = attempts
+		cfg.LoginLockoutDuration = duration
+		cfg.LoginMaxLockoutDuration = duration * 4
+	}
+}
+
+// lockAccount sends wrong passwords until the account is locked.
+func lockAccount(t *testing.T, sut Sut, email string, attempts int) schemas.LoginResponse {
+	t.Helper()
+
+	wrong := schemas.Login{Email: email, Password: "wrong-password"}
+
+	for i := 1; i < attempts; i++ {
+		response, err := sut.service.Login(context.TODO(), wrong)
+		assert.ErrorIs(t, err, auth.ErrNotAuthorized)
+		assert.Equal(t, "Invalid password", response.Message)
+	}
+
+	response, err := sut.service.Login(context.TODO(), wrong)
+	assert.ErrorIs(t, err, auth.ErrAccountLocked)
+
+	event := <-sut.eventChannel
+	assert.Equal(t, "login-locked", event.Action)
+
+	return response
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		unlock   func(t *testing.T, sut Sut, user *entity.User)
+	}{
+		{
+			scenario: "when an admin unlocks the account",
+			unlock: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				assert.NoError(t, sut.service.UnlockAccount(context.TODO(), user.ID))
+			},
+		},
+		{
+			scenario: "when the user recovers the password",
+			unlock: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				token, err := sut.service.GenerateToken(context.TODO(), user.ID, auth.RecoveryTokenPrefix, time.Hour)
+				assert.NoError(t, err)
+
+				assert.NoError(t, sut.service.RecoveryPassword(context.TODO(), token.Token, "recovered-password"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(withLockout(3, time.Minute))
+			user, payload := signUp(t, sut)
+
+			locked := lockAccount(t, sut, payload.Email, 3)
+
+			response, lockedErr := sut.service.Login(context.TODO(), schemas.Login{
+				Email:    payload.Email,
+				Password: payload.Password,
+			})
+
+			// Action
+			tc.unlock(t, sut, user)
+
+			// Assert
+			assert.Equal(t, "Account is locked", locked.Message)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), *locked.LockedUntil, time.Second)
+			assert.ErrorIs(t, lockedErr, auth.ErrAccountLocked, "the right password is rejected while locked")
+			assert.Empty(t, response.AccessToken.Token)
+
+			response, err := sut.service.Login(context.TODO(), schemas.Login{Email: payload.Email, Password: "wrong"})
+			assert.ErrorIs(t, err, auth.ErrNotAuthorized, "the failed attempts are reset")
+			assert.Equal(t, "Invalid password", response.Message)
+		})
+	}
+}
+
+func TestLoginLockoutGrowsExponentially(t *testing.T) {
+	t.Parallel()
+
+	lockout := time.Millisecond * 200
+
+	tests := []struct {
+		scenario           string
+		maxLockout         time.Duration
+		expectedSecondLock time.Duration
+	}{
+		{
+			scenario:           "when the lockout is under the max duration",
+			maxLockout:         lockout * 4,
+			expectedSecondLock: lockout * 2,
+		},
+		{
+			scenario:           "when the lockout reaches the max duration",
+			maxLockout:         lockout,
+			expectedSecondLock: lockout,
+		},
+		{
+			scenario:           "when there is no max duration",
+			expectedSecondLock: lockout * 2,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(withLockout(2, lockout), func(cfg *auth.Config) { cfg.LoginMaxLockoutDuration = tc.maxLockout })
+			_, payload := signUp(t, sut)
+
+			first := lockAccount(t, sut, payload.Email, 2)
+			time.Sleep(time.Until(*first.LockedUntil))
+
+			// Action
+			second := lockAccount(t, sut, payload.Email, 2)
+
+			// Assert
+			assert.WithinDuration(t, time.Now().Add(tc.expectedSecondLock), *second.LockedUntil, lockout/2)
+		})
+	}
+}
+
+func TestLoginLockoutIsSaturated(t *testing.T) {
+	t.Parallel()
+
+	year := 365 * 24 * time.Hour
+
+	tests := []struct {
+		scenario   string
+		maxLockout time.Duration
+	}{
+		{
+			scenario: "when there is no max duration",
+		},
+		{
+			scenario:   "when the max duration is longer than a year",
+			maxLockout: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(withLockout(2, time.Hour), func(cfg *auth.Config) { cfg.LoginMaxLockoutDuration = tc.maxLockout })
+			user, payload := signUp(t, sut)
+
+			key := fmt.Sprintf("login-lockouts-%s", user.ID)
+			assert.NoError(t, sut.cache.Set(context.TODO(), key, "100", time.Hour))
+
+			// Action
+			locked := lockAccount(t, sut, payload.Email, 2)
+
+			// Assert
+			assert.WithinDuration(t, time.Now().Add(year), *locked.LockedUntil, time.Second)
+
+			lockouts, err := sut.cache.Get(context.TODO(), key)
+			assert.NoError(t, err)
+			assert.Equal(t, "101", lockouts, "the lockouts are remembered")
+		})
+	}
+}
+
+func TestLoginLockoutCountsConcurrentAttempts(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(withLockout(3, time.Minute))
+	_, payload := signUp(t, sut)
+
+	var wg sync.WaitGroup
+
+	// Action
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, _ = sut.service.Login(context.TODO(), schemas.Login{Email: payload.Email, Password: "wrong-password"})
+		}()
+	}
+
+	wg.Wait()
+
+	// Assert
+	response, err := sut.service.Login(context.TODO(), schemas.Login{Email: payload.Email, Password: payload.Password})
+	assert.ErrorIs(t, err, auth.ErrAccountLocked)
+	assert.Empty(t, response.AccessToken.Token)
+}
+
+func TestLoginPasswordChangeRequired(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		maxAge   time.Duration
+		arrange  func(t *testing.T, sut Sut, user *entity.User)
+	}{
+		{
+			scenario: "when an admin forced the change",
+			arrange: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				assert.NoError(t, sut.service.ForcePasswordChange(context.TODO(), user.ID))
+			},
+		},
+		{
+			scenario: "when password is older than the max age",
+			maxAge:   time.Hour * 24 * 90,
+			arrange: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				user.PasswordChangedAt = time.Now().Add(-time.Hour * 24 * 91)
+				assert.NoError(t, sut.userRepo.Update(context.TODO(), *user))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(func(cfg *auth.Config) {
+				cfg.PasswordMaxAge = tc.maxAge
+			})
+			user, payload := signUp(t, sut)
+			tc.arrange(t, sut, user)
+
+			// Action
+			response, err := sut.service.Login(context.TODO(), schemas.Login{
+				Email:    payload.Email,
+				Password: payload.Password,
+			})
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, "Password change required", response.Message)
+			assert.NotEmpty(t, response.PasswordChangeToken)
+			assert.Empty(t, response.AccessToken.Token)
+			assert.Empty(t, response.RefreshToken.Token)
+
+			// The token only changes the password
+			_, err = sut.service.ValidateAccessToken(context.TODO(), response.PasswordChangeToken)
+			assert.Error(t, err)
+
+			newPassword := gofakeit.Password(true, true, true, true, true, 12)
+			err = sut.service.ChangeRequiredPassword(context.TODO(), schemas.ChangeRequiredPassword{
+				Token:    response.PasswordChangeToken,
+				Password: newPassword,
+			})
+			assert.NoError(t, err)
+			<-sut.eventChannel
+
+			err = sut.service.ChangeRequiredPassword(context.TODO(), schemas.ChangeRequiredPassword{
+				Token:    response.PasswordChangeToken,
+				Password: gofakeit.Password(true, true, true, true, true, 12),
+			})
+			assert.Error(t, err)
+
+			payload.Password = newPassword
+			response, _ = login(t, sut, payload, "laptop")
+			assert.Empty(t, response.PasswordChangeToken)
+		})
+	}
+}
+
+func TestChangeRequiredPasswordFollowsPolicy(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+	assert.NoError(t, sut.service.ForcePasswordChange(context.TODO(), user.ID))
+
+	response, err := sut.service.Login(context.TODO(), schemas.Login{Email: payload.Email, Password: payload.Password})
+	assert.NoError(t, err)
+
+	// Action
+	err = sut.service.ChangeRequiredPassword(context.TODO(), schemas.ChangeRequiredPassword{
+		Token:    response.PasswordChangeToken,
+		Password: "abc",
+	})
+
+	// Assert
+	var validationErr *entity.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.MustChangePassword)
+}
+
+func TestPasswordChangeRequiresSecondFactor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		login    func(t *testing.T, sut Sut, payload schemas.SignUp) (schemas.LoginResponse, error)
+	}{
+		{
+			scenario: "when the user logs in with the password",
+			login: func(t *testing.T, sut Sut, payload schemas.SignUp) (schemas.LoginResponse, error) {
+				t.Helper()
+
+				return sut.service.Login(context.TODO(), schemas.Login{Email: payload.Email, Password: payload.Password})
+			},
+		},
+		{
+			scenario: "when the user logs in with a magic link",
+			login: func(t *testing.T, sut Sut, payload schemas.SignUp) (schemas.LoginResponse, error) {
+				t.Helper()
+
+				token := sendMagicLink(t, sut, payload.Email)
+
+				return sut.service.LoginMagicLink(context.TODO(), schemas.MagicLinkLogin{Token: token})
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+			user, payload := signUp(t, sut)
+			secret, _ := enrollTOTP(t, sut, user.ID)
+			assert.NoError(t, sut.service.ForcePasswordChange(context.TODO(), user.ID))
+
+			loginResponse, err := tc.login(t, sut, payload)
+			assert.NoError(t, err)
+
+			code, err := pkgauth.TOTPCode(secret, time.Now())
+			assert.NoError(t, err)
+
+			// Action
+			response, err := sut.service.LoginMFA(
+				context.TODO(), schemas.LoginMFA{Challenge: loginResponse.MFAChallenge, Code: code},
+			)
+
+			// Assert
+			assert.NotEmpty(t, loginResponse.MFAChallenge)
+			assert.Empty(t, loginResponse.PasswordChangeToken, "the token is only issued after the second factor")
+
+			assert.NoError(t, err)
+			assert.Equal(t, "Password change required", response.Message)
+			assert.NotEmpty(t, response.PasswordChangeToken)
+			assert.Empty(t, response.AccessToken.Token)
+		})
+	}
+}
+
+func TestPasswordChangeRequiredEndsTheSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		maxAge   time.Duration
+		arrange  func(t *testing.T, sut Sut, user *entity.User)
+	}{
+		{
+			scenario: "when an admin forced the change",
+			arrange: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				assert.NoError(t, sut.service.ForcePasswordChange(context.TODO(), user.ID))
+			},
+		},
+		{
+			scenario: "when password got older than the max age",
+			maxAge:   time.Hour * 24 * 90,
+			arrange: func(t *testing.T, sut Sut, user *entity.User) {
+				t.Helper()
+
+				stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+				assert.NoError(t, err)
+
+				stored.PasswordChangedAt = time.Now().Add(-time.Hour * 24 * 91)
+				assert.NoError(t, sut.userRepo.Update(context.TODO(), stored))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(func(cfg *auth.Config) {
+				cfg.PasswordMaxAge = tc.maxAge
+			})
+			user, payload := signUp(t, sut)
+			loginResponse, _ := login(t, sut, payload, "laptop")
+			tc.arrange(t, sut, user)
+
+			// Action
+			response, err := sut.service.RefreshAccessToken(
+				context.TODO(), schemas.RefreshToken{JwtToken: loginResponse.RefreshToken},
+			)
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrNotAuthorized)
+			assert.Empty(t, response.AccessToken.Token)
+
+			_, err = sut.service.ValidateAccessToken(context.TODO(), loginResponse.AccessToken.Token)
+			assert.Error(t, err, "the session ended")
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+	user, payload := signUp(t, sut)
+
+	_, currentSession := login(t, sut, payload, "laptop")
+	login(t, sut, payload, "phone")
+
+	newPassword := gofakeit.Password(true, true, true, true, true, 12)
+
+	// Action
+	err := sut.service.ChangePassword(context.TODO(), user.ID, currentSession.ID, schemas.ChangePasswordPayload{
+		CurrentPassword: payload.Password,
+		NewPassword:     newPassword,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+
+	event := waitEvent(t, sut, "password-changed")
+	assert.Equal(t, "authentication", event.Service)
+
+	var data struct {
+		User      map[string]string `json:"user"`
+		SessionID string            `json:"session_id"`
+	}
+	assert.NoError(t, json.Unmarshal(event.Data, &data))
+	assert.Equal(t, user.ID.String(), data.User["id"])
+	assert.Equal(t, currentSession.ID.String(), data.SessionID)
+
+	sessions, err := sut.service.ListSessions(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, currentSession.ID, sessions[0].ID)
+
+	stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.ValidatePassword(newPassword))
+}
+
+func TestChangePasswordErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario      string
+		options       []func(cfg *auth.Config)
+		attempts      int
+		current       func(payload schemas.SignUp) string
+		newPassword   string
+		expectedError error
+	}{
+		{
+			scenario:      "when current password is wrong",
+			attempts:      1,
+			current:       func(schemas.SignUp) string { return "wrong-password" },
+			newPassword:   gofakeit.Password(true, true, true, true, true, 12),
+			expectedError: auth.ErrInvalidPassword,
+		},
+		{
+			scenario:      "when wrong current passwords reach the lockout",
+			options:       []func(cfg *auth.Config){withLockout(3, time.Minute)},
+			attempts:      3,
+			current:       func(schemas.SignUp) string { return "wrong-password" },
+			newPassword:   gofakeit.Password(true, true, true, true, true, 12),
+			expectedError: auth.ErrAccountLocked,
+		},
+		{
+			scenario:    "when new password violates the policy",
+			attempts:    1,
+			current:     func(payload schemas.SignUp) string { return payload.Password },
+			newPassword: "abc",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(tc.options...)
+			user, payload := signUp(t, sut)
+
+			_, currentSession := login(t, sut, payload, "laptop")
+			login(t, sut, payload, "phone")
+
+			// Action
+			var err error
+			for i := 0; i < tc.attempts; i++ {
+				err = sut.service.ChangePassword(context.TODO(), user.ID, currentSession.ID, schemas.ChangePasswordPayload{
+					CurrentPassword: tc.current(payload),
+					NewPassword:     tc.newPassword,
+				})
+			}
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				var validationErr *entity.ValidationError
+				assert.ErrorAs(t, err, &validationErr)
+			}
+
+			sessions, err := sut.service.ListSessions(context.TODO(), user.ID)
+			assert.NoError(t, err)
+			assert.Len(t, sessions, 2)
+
+			stored, err := sut.userRepo.Get(context.TODO(), user.ID)
+			assert.NoError(t, err)
+			assert.True(t, stored.ValidatePassword(payload.Password))
+		})
+	}
+}
+
+// waitEvent skips the events of the other actions, like the logins of the arrange.
+func waitEvent(t *testing.T, sut Sut, action string) schemas.Event {
+	t.Helper()
+
+	timeout := time.After(time.Second * 5)
+
+	for {
+		select {
+		case event := <-sut.eventChannel:
+			if event.Action == action {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("event %s wasn't sent", action)
+
+			return schemas.Event{}
+		}
+	}
+}
+
+func TestLongestTokenLifetime(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Hour*24, auth.LongestTokenLifetime(time.Hour))
+	assert.Equal(t, time.Hour*48, auth.LongestTokenLifetime(time.Hour*48))
+}
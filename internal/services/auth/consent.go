@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	authorizationSessionKeyPrefix = "authorization-session"
+	authorizationSessionSize      = 32
+
+	consentChallengeKeyPrefix = "consent-challenge"
+	consentChallengeDuration  = time.Minute * 10
+	consentChallengeSize      = 32
+)
+
+// authorizationSession binds the session cookie of the authorize endpoint to a session of the user, so the
+// cookie stops working when the session ends.
+type authorizationSession struct {
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id"`
+}
+
+// consentChallenge keeps the authorization request while the user approves it.
+type consentChallenge struct {
+	UserID  uuid.UUID                `json:"user_id"`
+	Request schemas.AuthorizeRequest `json:"request"`
+}
+
+func authorizationSessionKey(secret string) string {
+	return fmt.Sprintf("%s-%s", authorizationSessionKeyPrefix, secret)
+}
+
+func consentChallengeKey(challenge string) string {
+	return fmt.Sprintf("%s-%s", consentChallengeKeyPrefix, challenge)
+}
+
+// StartAuthorizationSession exchanges the access token of a first party session for the secret of the session
+// cookie used by the authorize endpoint, it expires with the session.
+func (s Service) StartAuthorizationSession(ctx context.Context, accessToken string) (string, time.Time, error) {
+	ctx, span := trace.NewSpan(ctx, "start-authorization-session")
+	defer span.End()
+
+	session, err := s.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if session.ClientID != "" {
+		return "", time.Time{}, errors.Wrap(ErrNotAuthorized, "sessions of the clients can't authorize other clients")
+	}
+
+	secret, err := auth.GenerateSecret(authorizationSessionSize)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	data, err := json.Marshal(authorizationSession{UserID: session.UserID, SessionID: session.ID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = s.cacheService.Set(ctx, authorizationSessionKey(secret), string(data), time.Until(session.ExpiresAt))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return secret, session.ExpiresAt, nil
+}
+
+// ValidateAuthorizationSession returns the session of the user bound to the session cookie.
+func (s Service) ValidateAuthorizationSession(ctx context.Context, secret string) (entity.Session, error) {
+	ctx, span := trace.NewSpan(ctx, "validate-authorization-session")
+	defer span.End()
+
+	data, _ := s.cacheService.Get(ctx, authorizationSessionKey(secret))
+	if secret == "" || data == "" {
+		return entity.Session{}, errors.Wrap(ErrNotAuthorized, "authorization session not found")
+	}
+
+	var authorization authorizationSession
+	if err := json.Unmarshal([]byte(data), &authorization); err != nil {
+		return entity.Session{}, err
+	}
+
+	return s.getSession(ctx, authorization.UserID, authorization.SessionID)
+}
+
+// RequestConsent validates the authorization request and keeps it until the user approves or denies it with the
+// returned challenge.
+func (s Service) RequestConsent(
+	ctx context.Context, client entity.Client, userID uuid.UUID, request schemas.AuthorizeRequest,
+) (schemas.ConsentRequest, error) {
+	ctx, span := trace.NewSpan(ctx, "request-consent")
+	defer span.End()
+
+	scope, err := s.validateAuthorizeRequest(client, request)
+	if err != nil {
+		return schemas.ConsentRequest{}, err
+	}
+
+	challenge, err := auth.GenerateSecret(consentChallengeSize)
+	if err != nil {
+		return schemas.ConsentRequest{}, err
+	}
+
+	data, err := json.Marshal(consentChallenge{UserID: userID, Request: request})
+	if err != nil {
+		return schemas.ConsentRequest{}, err
+	}
+
+	if err := s.cacheService.Set(ctx, consentChallengeKey(challenge), string(data), consentChallengeDuration); err != nil {
+		return schemas.ConsentRequest{}, err
+	}
+
+	return schemas.ConsentRequest{
+		Challenge:  challenge,
+		ClientID:   client.ID,
+		ClientName: client.Name,
+		Scope:      scope,
+	}, nil
+}
+
+// ConsumeConsent returns the authorization request of the challenge, it can be answered only once and only by
+// the user it was requested to.
+func (s Service) ConsumeConsent(
+	ctx context.Context, userID uuid.UUID, challenge string,
+) (schemas.AuthorizeRequest, error) {
+	ctx, span := trace.NewSpan(ctx, "consume-consent")
+	defer span.End()
+
+	data, _ := s.cacheService.GetDel(ctx, consentChallengeKey(challenge))
+	if challenge == "" || data == "" {
+		return schemas.AuthorizeRequest{}, errors.Wrap(ErrInvalidRequest, "consent challenge not found")
+	}
+
+	var consent consentChallenge
+	if err := json.Unmarshal([]byte(data), &consent); err != nil {
+		return schemas.AuthorizeRequest{}, err
+	}
+
+	if consent.UserID != userID {
+		return schemas.AuthorizeRequest{}, errors.Wrap(ErrInvalidRequest, "consent was requested to another user")
+	}
+
+	return consent.Request, nil
+}
@@ -5,4 +5,30 @@ import "errors"
 var (
 	ErrNotAuthorized      = errors.New("not authorized")
 	ErrEmailIsAlreadyUsed = errors.New("the email is already being used")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrClientMismatch     = errors.New("token was issued to another client")
+	ErrInvalidMFACode     = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled  = errors.New("mfa is already enabled")
+	ErrMFANotEnabled      = errors.New("mfa is not enabled")
+	ErrEmailNotVerified   = errors.New("email is not verified")
+	ErrEmailVerified      = errors.New("email is already verified")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrAccountLocked      = errors.New("account is locked")
+	ErrPhoneVerified      = errors.New("phone is already verified")
+	ErrInvalidPhoneCode   = errors.New("invalid phone verification code")
+	ErrInvalidPassword    = errors.New("invalid current password")
+	ErrReAuthRequired     = errors.New("the current password or a totp code is required")
+
+	ErrInvalidWebAuthnCredential           = errors.New("invalid webauthn credential")
+	ErrWebAuthnCredentialNotFound          = errors.New("webauthn credential not found")
+	ErrWebAuthnCredentialAlreadyRegistered = errors.New("webauthn credential is already registered")
+
+	// OAuth errors, the handlers translate them to the error codes of RFC 6749.
+	ErrInvalidRequest          = errors.New("invalid request")
+	ErrInvalidGrant            = errors.New("invalid grant")
+	ErrUnsupportedResponseType = errors.New("unsupported response type")
+	ErrUnauthorizedClient      = errors.New("unauthorized client")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInsufficientScope       = errors.New("insufficient scope")
+	ErrAccessDenied            = errors.New("the user denied the authorization")
 )
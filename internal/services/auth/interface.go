@@ -6,29 +6,76 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 )
 
+type Config = config.AuthConfig
+
 type TokenPrefix string
 
 const (
-	AccessTokenPrefix       TokenPrefix = "acess-token"
-	RefreshAcessTokenPrefix TokenPrefix = "refresh-acess-token"
-	RecoveryTokenPrefix     TokenPrefix = "recovery-token"
+	AccessTokenPrefix            TokenPrefix = "acess-token"
+	RefreshAcessTokenPrefix      TokenPrefix = "refresh-acess-token"
+	RecoveryTokenPrefix          TokenPrefix = "recovery-token"
+	EmailVerificationTokenPrefix TokenPrefix = "email-verification-token"
+	MagicLinkTokenPrefix         TokenPrefix = "magic-link-token"
+	PasswordChangeTokenPrefix    TokenPrefix = "password-change-token"
+	ClientAccessTokenPrefix      TokenPrefix = "client-access-token"
 )
 
+var tokenUses = map[TokenPrefix]string{
+	AccessTokenPrefix:            "access",
+	RefreshAcessTokenPrefix:      "refresh",
+	RecoveryTokenPrefix:          "recovery",
+	EmailVerificationTokenPrefix: "email_verification",
+	MagicLinkTokenPrefix:         "magic_link",
+	PasswordChangeTokenPrefix:    "password_change",
+	ClientAccessTokenPrefix:      "client_access",
+}
+
+// TokenUse is the token_use claim of the tokens of the prefix. Only the access and client_access tokens are
+// meant for the resource servers, the others are only accepted by this service.
+func (p TokenPrefix) TokenUse() string {
+	return tokenUses[p]
+}
+
 type UserService interface {
 	Get(ctx context.Context, id uuid.UUID) (user entity.User, err error)
 	GetByEmail(ctx context.Context, email string) (user entity.User, err error)
 	Create(ctx context.Context, user entity.User) error
 	Update(ctx context.Context, id uuid.UUID, payload schemas.UpdateUserPayload) (*entity.User, error)
+	UpdateProfile(ctx context.Context, id uuid.UUID, payload schemas.UpdateProfilePayload) (*entity.User, error)
+	Save(ctx context.Context, user entity.User) error
+	SaveRecoveryCodes(ctx context.Context, user entity.User, previous entity.StringList) (bool, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+type WebAuthnCredentialRepository interface {
+	Get(ctx context.Context, id string) (entity.WebAuthnCredential, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]entity.WebAuthnCredential, error)
+	Create(ctx context.Context, credential entity.WebAuthnCredential) error
+	Update(ctx context.Context, credential entity.WebAuthnCredential) error
+	Delete(ctx context.Context, userID uuid.UUID, id string) error
+}
+
 type CacheService interface {
 	HSet(ctx context.Context, key string, values ...any) error
 	Set(ctx context.Context, key, value string, expiration time.Duration) error
 	HSetExp(ctx context.Context, key string, expiration time.Duration, values ...any) error
+	HGet(ctx context.Context, key, field string) (string, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HDel(ctx context.Context, key string, fields ...string) error
 	Get(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, key string) error
+	GetDel(ctx context.Context, key string) (string, error)
+	SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error)
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
+}
+
+type KeyRing interface {
+	SigningKey() (auth.SigningKey, error)
+	Lookup(kid string) (auth.SigningKey, error)
+	JWKS() auth.JWKS
 }
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// Token types as defined by RFC 7009, they're used as token_type_hint and as the introspected token_type.
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+)
+
+var tokenTypePrefixes = map[string]TokenPrefix{
+	TokenTypeAccessToken:  AccessTokenPrefix,
+	TokenTypeRefreshToken: RefreshAcessTokenPrefix,
+}
+
+// tokenTypes returns the order the token types are tried, the hint only changes which one is tried first.
+func tokenTypes(tokenTypeHint string) []string {
+	if tokenTypeHint == TokenTypeRefreshToken {
+		return []string{TokenTypeRefreshToken, TokenTypeAccessToken}
+	}
+
+	return []string{TokenTypeAccessToken, TokenTypeRefreshToken}
+}
+
+// IntrospectToken describes the token as RFC 7662, any token that can't be used anymore is just inactive.
+func (s Service) IntrospectToken(ctx context.Context, token, tokenTypeHint string) schemas.Introspection {
+	ctx, span := trace.NewSpan(ctx, "introspect-token")
+	defer span.End()
+
+	for _, tokenType := range tokenTypes(tokenTypeHint) {
+		_, claims, err := s.validateToken(ctx, token, tokenTypePrefixes[tokenType])
+		if err != nil {
+			continue
+		}
+
+		return schemas.Introspection{
+			Active:    true,
+			Subject:   claims.Subject,
+			ExpiresAt: claims.ExpiresAt,
+			IssuedAt:  claims.IssuedAt,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			TokenType: tokenType,
+		}
+	}
+
+	if claims, err := s.validateClientToken(ctx, token); err == nil {
+		return schemas.Introspection{
+			Active:    true,
+			Subject:   claims.Subject,
+			ExpiresAt: claims.ExpiresAt,
+			IssuedAt:  claims.IssuedAt,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			TokenType: TokenTypeAccessToken,
+		}
+	}
+
+	return schemas.Introspection{Active: false}
+}
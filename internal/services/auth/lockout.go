@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	// Failed logins since the last lockout, they expire with the lockout duration.
+	loginAttemptsKeyPrefix = "login-attempts"
+	// Time until the account is unlocked, the key expires with the lockout.
+	loginLockoutKeyPrefix = "login-lockout"
+	// Times the account was locked, they make each new lockout longer than the previous one.
+	loginLockoutsKeyPrefix = "login-lockouts"
+)
+
+// Upper bound of the lockouts when there is no max duration, the doubling would overflow the time.Duration.
+const maxLoginLockoutDuration = 365 * 24 * time.Hour
+
+func loginAttemptsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", loginAttemptsKeyPrefix, userID.String())
+}
+
+func loginLockoutKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", loginLockoutKeyPrefix, userID.String())
+}
+
+func loginLockoutsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", loginLockoutsKeyPrefix, userID.String())
+}
+
+// UnlockAccount removes the lockout and the failed logins of the user.
+func (s Service) UnlockAccount(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := trace.NewSpan(ctx, "unlock-account")
+	defer span.End()
+
+	for _, key := range []string{loginLockoutKey(userID), loginLockoutsKey(userID), loginAttemptsKey(userID)} {
+		if err := s.cacheService.Del(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loginLockedUntil returns when the lockout of the account ends, the time is zero when it isn't locked.
+func (s Service) loginLockedUntil(ctx context.Context, userID uuid.UUID) time.Time {
+	data, _ := s.cacheService.Get(ctx, loginLockoutKey(userID))
+
+	lockedUntil, err := time.Parse(time.RFC3339Nano, data)
+	if err != nil || !time.Now().Before(lockedUntil) {
+		return time.Time{}
+	}
+
+	return lockedUntil
+}
+
+// registerFailedLogin counts the wrong password and locks the account when the attempts reach the limit, the
+// returned time is zero while the account isn't locked.
+func (s Service) registerFailedLogin(ctx context.Context, user entity.User) (time.Time, error) {
+	if s.config.LoginMaxAttempts <= 0 {
+		return time.Time{}, nil
+	}
+
+	attempts, err := s.cacheService.Incr(ctx, loginAttemptsKey(user.ID), s.config.LoginLockoutDuration)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if attempts < int64(s.config.LoginMaxAttempts) {
+		return time.Time{}, nil
+	}
+
+	// Only the request that reached the limit locks the account, the concurrent ones get its lockout.
+	if attempts > int64(s.config.LoginMaxAttempts) {
+		return s.loginLockedUntil(ctx, user.ID), nil
+	}
+
+	data, _ := s.cacheService.Get(ctx, loginLockoutsKey(user.ID))
+	lockouts, _ := strconv.Atoi(data)
+	lockouts++
+
+	duration := s.lockoutDuration(lockouts)
+	lockedUntil := time.Now().Add(duration)
+
+	if err := s.cacheService.Set(
+		ctx, loginLockoutKey(user.ID), lockedUntil.Format(time.RFC3339Nano), duration,
+	); err != nil {
+		return time.Time{}, err
+	}
+
+	// The lockouts are remembered long enough to make the next lockout longer.
+	if err := s.cacheService.Set(
+		ctx, loginLockoutsKey(user.ID), strconv.Itoa(lockouts), s.maxLockoutDuration()+duration,
+	); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := s.cacheService.Del(ctx, loginAttemptsKey(user.ID)); err != nil {
+		return time.Time{}, err
+	}
+
+	go s.sendEvent("login-locked", map[string]string{
+		"user_id":         user.ID.String(),
+		"failed_attempts": strconv.FormatInt(attempts, 10),
+		"lockouts":        strconv.Itoa(lockouts),
+		"locked_until":    lockedUntil.Format(time.RFC3339Nano),
+	})
+
+	return lockedUntil, nil
+}
+
+// lockoutDuration doubles the configured duration for each previous lockout, up to the max duration.
+func (s Service) lockoutDuration(lockouts int) time.Duration {
+	maxDuration := s.maxLockoutDuration()
+	duration := s.config.LoginLockoutDuration
+
+	for i := 1; i < lockouts && duration < maxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > maxDuration {
+		return maxDuration
+	}
+
+	return duration
+}
+
+// maxLockoutDuration is the configured max duration, it's saturated at a year when it's unbounded or longer.
+func (s Service) maxLockoutDuration() time.Duration {
+	if s.config.LoginMaxLockoutDuration <= 0 || s.config.LoginMaxLockoutDuration > maxLoginLockoutDuration {
+		return maxLoginLockoutDuration
+	}
+
+	return s.config.LoginMaxLockoutDuration
+}
+
+func lockedLoginResponse(lockedUntil time.Time) schemas.LoginResponse {
+	return schemas.LoginResponse{
+		Message:     "Account is locked",
+		LockedUntil: &lockedUntil,
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const magicLinkTokenDuration = time.Minute * 15
+
+// SendMagicLink sends a login token to the email of the user, the previous one stops working.
+func (s Service) SendMagicLink(ctx context.Context, payload schemas.SendMagicLinkPayload) error {
+	ctx, span := trace.NewSpan(ctx, "send-magic-link")
+	defer span.End()
+
+	user, err := s.userService.GetByEmail(ctx, payload.Email)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.generateEmailToken(ctx, user, MagicLinkTokenPrefix, magicLinkTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	go s.sendEvent("magic-link", map[string]any{
+		"user":        map[string]string{"id": user.ID.String(), "name": user.Name, "email": user.Email},
+		"login_token": token.Token,
+		"expires_at":  token.ExpiresAt,
+	})
+
+	return nil
+}
+
+// LoginMagicLink replaces the password by the token sent to the email, the token is invalidated on the first use
+// and the second factor is still required when the user has one.
+func (s Service) LoginMagicLink(ctx context.Context, payload schemas.MagicLinkLogin) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "login-magic-link")
+	defer span.End()
+
+	userID, claims, err := s.consumeToken(ctx, payload.Token, MagicLinkTokenPrefix)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid token"}, err
+	}
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid token"}, err
+	}
+
+	if claims.Email != user.Email {
+		return schemas.LoginResponse{Message: "Invalid token"},
+			errors.Wrap(ErrNotAuthorized, "the token was sent to another email")
+	}
+
+	// The token was delivered to the email, so the user owns it.
+	if !user.EmailVerified {
+		user.VerifyEmail()
+
+		if err := s.userService.Save(ctx, user); err != nil {
+			return schemas.LoginResponse{Message: "Error on verify email"}, err
+		}
+	}
+
+	if response, err := s.verifyLoginAllowed(user); err != nil {
+		return response, err
+	}
+
+	return s.continueLogin(ctx, user, schemas.Login{
+		Email:     user.Email,
+		Device:    payload.Device,
+		IPAddress: payload.IPAddress,
+		UserAgent: payload.UserAgent,
+	})
+}
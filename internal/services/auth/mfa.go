@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	MFAMethodTOTP = "totp"
+
+	mfaChallengeKeyPrefix = "mfa-challenge"
+	mfaChallengeDuration  = time.Minute * 5
+	mfaChallengeSize      = 32
+	mfaChallengeAttempts  = 5
+
+	// The periods used are kept while their codes are still accepted, so they can't be replayed.
+	totpUsedKeyPrefix = "totp-used"
+	totpUsedPeriods   = 3
+	totpUsedDuration  = auth.TOTPPeriod * totpUsedPeriods
+
+	// The second factors sent by the user are counted together, on login or to manage them, so the limit can't be
+	// bypassed by starting new challenges.
+	mfaAttemptsKeyPrefix = "mfa-attempts"
+)
+
+func mfaChallengeKey(token string) string {
+	return fmt.Sprintf("%s-%s", mfaChallengeKeyPrefix, token)
+}
+
+func totpUsedKey(userID uuid.UUID, counter uint64) string {
+	return fmt.Sprintf("%s-%s-%d", totpUsedKeyPrefix, userID.String(), counter)
+}
+
+func mfaAttemptsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", mfaAttemptsKeyPrefix, userID.String())
+}
+
+// EnrollTOTP generates a new secret for the user, it's only required on login after being confirmed.
+func (s Service) EnrollTOTP(ctx context.Context, userID uuid.UUID) (schemas.TOTPEnrollment, error) {
+	ctx, span := trace.NewSpan(ctx, "enroll-totp")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return schemas.TOTPEnrollment{}, err
+	}
+
+	if user.TOTPEnabled {
+		return schemas.TOTPEnrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return schemas.TOTPEnrollment{}, err
+	}
+
+	encryptedSecret, err := auth.Encrypt(s.config.EncryptionKey, []byte(secret))
+	if err != nil {
+		return schemas.TOTPEnrollment{}, err
+	}
+
+	user.SetTOTPSecret(encryptedSecret)
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return schemas.TOTPEnrollment{}, err
+	}
+
+	return schemas.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(config.ServiceName, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the second factor once the user proves the authenticator app generates valid codes.
+// When it's the first second factor of the user the recovery codes are generated and returned.
+func (s Service) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ctx, span := trace.NewSpan(ctx, "confirm-totp")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, errors.Wrap(ErrMFANotEnabled, "totp enrollment was not started")
+	}
+
+	if err := s.verifyTOTPAttempt(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	mfaWasEnabled, err := s.mfaEnabled(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var recoveryCodes []string
+
+	if !mfaWasEnabled {
+		if recoveryCodes, err = user.GenerateRecoveryCodes(); err != nil {
+			return nil, err
+		}
+	}
+
+	user.EnableTOTP()
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTOTP requires a valid code, a stolen access token alone can't remove the second factor.
+func (s Service) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	ctx, span := trace.NewSpan(ctx, "disable-totp")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.verifyTOTPAttempt(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.DisableTOTP()
+
+	if err := s.disableRecoveryCodes(ctx, &user); err != nil {
+		return err
+	}
+
+	return s.userService.Save(ctx, user)
+}
+
+// LoginMFA exchanges the challenge returned by the password step and a valid second factor for the session
+// tokens.
+func (s Service) LoginMFA(ctx context.Context, payload schemas.LoginMFA) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "login-mfa")
+	defer span.End()
+
+	challenge, err := s.getMFAChallenge(ctx, payload.Challenge)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid challenge"}, err
+	}
+
+	user, err := s.userService.Get(ctx, challenge.UserID)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid challenge"}, err
+	}
+
+	if response, err := s.verifyLoginAllowed(user); err != nil {
+		return response, err
+	}
+
+	// The failed logins aren't reset by the password, the account can be locked while the challenge is pending.
+	if lockedUntil := s.loginLockedUntil(ctx, user.ID); !lockedUntil.IsZero() {
+		return lockedLoginResponse(lockedUntil), ErrAccountLocked
+	}
+
+	if err := s.registerMFAAttempt(ctx, user, payload.Challenge); err != nil {
+		return schemas.LoginResponse{Message: "Too many invalid codes"}, err
+	}
+
+	if err := s.verifySecondFactor(ctx, user, challenge, payload); err != nil {
+		return schemas.LoginResponse{Message: "Invalid second factor"}, err
+	}
+
+	if err := s.cacheService.Del(ctx, mfaChallengeKey(payload.Challenge)); err != nil {
+		return schemas.LoginResponse{Message: "Error on verify code"}, err
+	}
+
+	if err := s.cacheService.Del(ctx, mfaAttemptsKey(user.ID)); err != nil {
+		return schemas.LoginResponse{Message: "Error on verify code"}, err
+	}
+
+	return s.completeLogin(ctx, user, challenge.Device, challenge.IPAddress, challenge.UserAgent)
+}
+
+// verifySecondFactor checks the code or the webauthn assertion sent for the challenge.
+func (s Service) verifySecondFactor(
+	ctx context.Context, user entity.User, challenge entity.MFAChallenge, payload schemas.LoginMFA,
+) error {
+	switch payload.Method {
+	case "", MFAMethodTOTP:
+		if !user.TOTPEnabled {
+			return ErrMFANotEnabled
+		}
+
+		return s.verifyTOTP(ctx, user, payload.Code)
+
+	case MFAMethodWebAuthn:
+		if challenge.WebAuthnChallenge == "" || payload.Credential == nil {
+			return ErrInvalidWebAuthnCredential
+		}
+
+		ceremony := s.webAuthnCeremony(challenge.WebAuthnChallenge, false)
+		_, err := s.verifyWebAuthnAssertion(ctx, user.ID, ceremony, *payload.Credential)
+
+		return err
+
+	case MFAMethodRecoveryCode:
+		return s.useRecoveryCode(ctx, user, payload.Code)
+
+	default:
+		return errors.Wrap(ErrInvalidRequest, "unsupported mfa method")
+	}
+}
+
+// startMFAChallenge returns the challenge to be completed with one of the factors of the user, when the user
+// has webauthn credentials the options to sign with them are returned too.
+func (s Service) startMFAChallenge(
+	ctx context.Context, user entity.User, credentials []entity.WebAuthnCredential, payload schemas.Login,
+) (schemas.LoginResponse, error) {
+	token, err := auth.GenerateSecret(mfaChallengeSize)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Error on create mfa challenge"}, err
+	}
+
+	challenge := entity.MFAChallenge{
+		UserID:    user.ID,
+		Device:    payload.Device,
+		IPAddress: payload.IPAddress,
+		UserAgent: payload.UserAgent,
+		ExpiresAt: time.Now().Add(mfaChallengeDuration),
+	}
+
+	response := schemas.LoginResponse{
+		Message:      "MFA required",
+		MFAChallenge: token,
+	}
+
+	if user.TOTPEnabled {
+		response.MFAMethods = append(response.MFAMethods, MFAMethodTOTP)
+	}
+
+	if len(credentials) > 0 {
+		challenge.WebAuthnChallenge, err = auth.GenerateSecret(webAuthnChallengeSize)
+		if err != nil {
+			return schemas.LoginResponse{Message: "Error on create mfa challenge"}, err
+		}
+
+		options := s.webAuthnRequestOptions(
+			challenge.WebAuthnChallenge, credentials, webAuthnUserVerificationPreferred,
+		)
+
+		response.MFAMethods = append(response.MFAMethods, MFAMethodWebAuthn)
+		response.WebAuthnOptions = &options
+	}
+
+	if len(user.RecoveryCodes) > 0 {
+		response.MFAMethods = append(response.MFAMethods, MFAMethodRecoveryCode)
+	}
+
+	if err := s.saveMFAChallenge(ctx, token, challenge); err != nil {
+		return schemas.LoginResponse{Message: "Error on create mfa challenge"}, err
+	}
+
+	return response, nil
+}
+
+func (s Service) saveMFAChallenge(ctx context.Context, token string, challenge entity.MFAChallenge) error {
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	return s.cacheService.Set(ctx, mfaChallengeKey(token), string(data), time.Until(challenge.ExpiresAt))
+}
+
+func (s Service) getMFAChallenge(ctx context.Context, token string) (entity.MFAChallenge, error) {
+	data, _ := s.cacheService.Get(ctx, mfaChallengeKey(token))
+	if data == "" {
+		return entity.MFAChallenge{}, errors.Wrap(ErrNotAuthorized, "MFA challenge not found")
+	}
+
+	var challenge entity.MFAChallenge
+	if err := json.Unmarshal([]byte(data), &challenge); err != nil {
+		return entity.MFAChallenge{}, err
+	}
+
+	if challenge.IsExpired() {
+		return entity.MFAChallenge{}, errors.Wrap(ErrNotAuthorized, "MFA challenge expired")
+	}
+
+	return challenge, nil
+}
+
+// registerMFAAttempt drops the challenge after too many wrong codes, the password must be verified again.
+// registerMFAAttempt counts the attempt before the second factor is checked, so concurrent guesses can't go over
+// the limit. The challenge is dropped once the limit is reached and the login must start again.
+func (s Service) registerMFAAttempt(ctx context.Context, user entity.User, token string) error {
+	attempts, err := s.cacheService.Incr(ctx, mfaAttemptsKey(user.ID), mfaChallengeDuration)
+	if err != nil {
+		return err
+	}
+
+	if attempts > mfaChallengeAttempts {
+		if err := s.cacheService.Del(ctx, mfaChallengeKey(token)); err != nil {
+			return err
+		}
+
+		return errors.Wrap(ErrTooManyRequests, "too many invalid second factors")
+	}
+
+	return nil
+}
+
+// verifyTOTPAttempt counts the attempt before checking the code, so concurrent guesses can't go over the limit. A
+// stolen access token alone can't be used to guess the code.
+func (s Service) verifyTOTPAttempt(ctx context.Context, user entity.User, code string) error {
+	attempts, err := s.cacheService.Incr(ctx, mfaAttemptsKey(user.ID), mfaChallengeDuration)
+	if err != nil {
+		return err
+	}
+
+	if attempts > mfaChallengeAttempts {
+		return errors.Wrap(ErrTooManyRequests, "too many invalid totp codes")
+	}
+
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return err
+	}
+
+	return s.cacheService.Del(ctx, mfaAttemptsKey(user.ID))
+}
+
+func (s Service) verifyTOTP(ctx context.Context, user entity.User, code string) error {
+	secret, err := auth.Decrypt(s.config.EncryptionKey, user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	counter, ok := auth.ValidateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	return s.useTOTPCounter(ctx, user.ID, counter)
+}
+
+// useTOTPCounter claims the period of the code with SETNX, so the same code is accepted only once even by concurrent
+// requests. The codes of the periods before it are rejected too once a newer one was used.
+func (s Service) useTOTPCounter(ctx context.Context, userID uuid.UUID, counter uint64) error {
+	for later := counter + 1; later < counter+totpUsedPeriods; later++ {
+		if used, _ := s.cacheService.Get(ctx, totpUsedKey(userID, later)); used != "" {
+			return ErrInvalidMFACode
+		}
+	}
+
+	claimed, err := s.cacheService.SetNX(ctx, totpUsedKey(userID, counter), "1", totpUsedDuration)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return ErrInvalidMFACode
+	}
+
+	for previous := counter - 1; previous+totpUsedPeriods > counter && previous < counter; previous-- {
+		if err := s.cacheService.Set(ctx, totpUsedKey(userID, previous), "1", totpUsedDuration); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
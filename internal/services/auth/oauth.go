@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	ResponseTypeCode = "code"
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	authorizationCodeKeyPrefix = "authorization-code"
+	authorizationCodeDuration  = time.Minute
+	authorizationCodeSize      = 32
+)
+
+func authorizationCodeKey(code string) string {
+	return fmt.Sprintf("%s-%s", authorizationCodeKeyPrefix, code)
+}
+
+// clientTokenKey is where the tokens issued to clients are cached, a client can have many valid tokens
+// so they're indexed by the token id.
+func clientTokenKey(tokenID string) string {
+	return fmt.Sprintf("%s-%s", ClientAccessTokenPrefix, tokenID)
+}
+
+// Authorize issues an authorization code for the user, the redirect uri must have been validated against the
+// client before, so errors can be sent back to it. Only the scopes allowed for the client can be granted.
+func (s Service) Authorize(
+	ctx context.Context, client entity.Client, userID uuid.UUID, request schemas.AuthorizeRequest,
+) (string, error) {
+	ctx, span := trace.NewSpan(ctx, "authorize")
+	defer span.End()
+
+	scope, err := s.validateAuthorizeRequest(client, request)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := auth.GenerateSecret(authorizationCodeSize)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(entity.AuthorizationCode{
+		ClientID:            client.ID,
+		UserID:              userID,
+		RedirectURI:         request.RedirectURI,
+		Scope:               scope,
+		Nonce:               request.Nonce,
+		CodeChallenge:       request.CodeChallenge,
+		CodeChallengeMethod: request.CodeChallengeMethod,
+		ExpiresAt:           time.Now().Add(authorizationCodeDuration),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.cacheService.Set(ctx, authorizationCodeKey(code), string(data), authorizationCodeDuration); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// validateAuthorizeRequest returns the scope granted to the client for the request.
+func (s Service) validateAuthorizeRequest(client entity.Client, request schemas.AuthorizeRequest) (string, error) {
+	if request.ResponseType != ResponseTypeCode {
+		return "", ErrUnsupportedResponseType
+	}
+
+	if request.CodeChallenge == "" {
+		return "", errors.Wrap(ErrInvalidRequest, "code_challenge is required")
+	}
+
+	if request.CodeChallengeMethod != auth.CodeChallengeMethodS256 {
+		return "", errors.Wrap(ErrInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	scope, ok := client.GrantScope(request.Scope)
+	if !ok {
+		return "", errors.Wrap(ErrInvalidScope, "requested scope is not allowed for the client")
+	}
+
+	if hasScope(scope, ScopeOpenID) && !s.openIDEnabled() {
+		return "", errors.Wrap(ErrInvalidScope, "openid requires an asymmetric signing key")
+	}
+
+	return scope, nil
+}
+
+// ExchangeAuthorizationCode starts a session for the user that authorized the client. The code is removed
+// before being checked, so it can't be used again even if the exchange fails.
+func (s Service) ExchangeAuthorizationCode(
+	ctx context.Context, client entity.Client, request schemas.TokenRequest,
+) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "exchange-authorization-code")
+	defer span.End()
+
+	code, err := s.consumeAuthorizationCode(ctx, request.Code)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid authorization code"}, err
+	}
+
+	switch {
+	case code.IsExpired():
+		err = errors.Wrap(ErrInvalidGrant, "authorization code expired")
+	case code.ClientID != client.ID:
+		err = errors.Wrap(ErrInvalidGrant, "authorization code was issued to another client")
+	case code.RedirectURI != request.RedirectURI:
+		err = errors.Wrap(ErrInvalidGrant, "redirect_uri does not match the authorization request")
+	case !code.VerifyCodeVerifier(request.CodeVerifier):
+		err = errors.Wrap(ErrInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid authorization code"}, err
+	}
+
+	user, err := s.userService.Get(ctx, code.UserID)
+	if err != nil {
+		return schemas.LoginResponse{Message: "User is inactive"}, errors.Wrap(ErrInvalidGrant, "user not found")
+	}
+
+	if response, err := s.verifyClientLoginAllowed(ctx, user); err != nil {
+		return response, err
+	}
+
+	session := entity.NewSession(user.ID, client.Name, request.IPAddress, request.UserAgent, config.SessionTime)
+	session.ClientID = client.ID
+	session.Scope = code.Scope
+
+	response, err := s.startSession(ctx, session)
+	if err != nil {
+		return response, err
+	}
+
+	if hasScope(session.Scope, ScopeOpenID) {
+		response.IDToken, err = s.generateIDToken(user, session, code.Nonce)
+		if err != nil {
+			return schemas.LoginResponse{Message: "Error on generate id token"}, err
+		}
+	}
+
+	go s.sendEvent(
+		"login", map[string]string{
+			"user_id":    user.ID.String(),
+			"session_id": session.ID.String(),
+			"client_id":  client.ID,
+			"logged_at":  time.Now().Format(time.RFC3339Nano),
+		},
+	)
+
+	return response, nil
+}
+
+// verifyClientLoginAllowed applies the checks of the first party login to the sessions started by the clients, the
+// account may have been locked or disabled after the code was issued.
+func (s Service) verifyClientLoginAllowed(ctx context.Context, user entity.User) (schemas.LoginResponse, error) {
+	if response, err := s.verifyLoginAllowed(user); err != nil {
+		return response, errors.Wrap(ErrInvalidGrant, err.Error())
+	}
+
+	if lockedUntil := s.loginLockedUntil(ctx, user.ID); !lockedUntil.IsZero() {
+		return lockedLoginResponse(lockedUntil), errors.Wrap(ErrInvalidGrant, ErrAccountLocked.Error())
+	}
+
+	if user.PasswordChangeRequired(s.config.PasswordMaxAge) {
+		return schemas.LoginResponse{Message: "Password change required"},
+			errors.Wrap(ErrInvalidGrant, "password change required")
+	}
+
+	return schemas.LoginResponse{}, nil
+}
+
+// RefreshClientToken rotates the tokens of a session started by the client.
+func (s Service) RefreshClientToken(
+	ctx context.Context, client entity.Client, refreshToken string,
+) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "refresh-client-token")
+	defer span.End()
+
+	response, err := s.refreshSession(ctx, refreshToken, client.ID)
+	if errors.Is(err, ErrNotAuthorized) || errors.Is(err, ErrClientMismatch) {
+		return response, errors.Wrap(ErrInvalidGrant, err.Error())
+	}
+
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// IssueClientToken issues an access token to the client itself, its subject is the client instead of an user.
+// No refresh token is issued, the client can always request a new token with its credentials.
+func (s Service) IssueClientToken(
+	ctx context.Context, client entity.Client, scope string,
+) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "issue-client-token")
+	defer span.End()
+
+	if client.Public {
+		return schemas.LoginResponse{Message: "Public clients can't request tokens for themselves"},
+			errors.Wrap(ErrUnauthorizedClient, "public clients can't use the client credentials grant")
+	}
+
+	grantedScope, ok := client.GrantScope(scope)
+	if !ok {
+		return schemas.LoginResponse{Message: "Invalid scope"},
+			errors.Wrap(ErrInvalidScope, "requested scope is not allowed for the client")
+	}
+
+	claims := auth.Claims{
+		ID:       uuid.NewString(),
+		Subject:  client.ID,
+		ClientID: client.ID,
+		Scope:    grantedScope,
+	}
+
+	token, err := s.issueToken(
+		ctx, claims, ClientAccessTokenPrefix, clientTokenKey(claims.ID), client.TokenDuration(tokenDuration),
+	)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Error on generate access token"}, err
+	}
+
+	return schemas.LoginResponse{AccessToken: token}, nil
+}
+
+func (s Service) validateClientToken(ctx context.Context, token string) (auth.Claims, error) {
+	claims, err := s.parseToken(token, ClientAccessTokenPrefix)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	cachedToken, _ := s.cacheService.Get(ctx, clientTokenKey(claims.ID))
+	if cachedToken != token {
+		return auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Token not found")
+	}
+
+	return claims, nil
+}
+
+func (s Service) consumeAuthorizationCode(ctx context.Context, code string) (entity.AuthorizationCode, error) {
+	data, _ := s.cacheService.GetDel(ctx, authorizationCodeKey(code))
+	if code == "" || data == "" {
+		return entity.AuthorizationCode{}, errors.Wrap(ErrInvalidGrant, "authorization code not found")
+	}
+
+	var authorizationCode entity.AuthorizationCode
+	if err := json.Unmarshal([]byte(data), &authorizationCode); err != nil {
+		return entity.AuthorizationCode{}, err
+	}
+
+	return authorizationCode, nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+func hasScope(scope, value string) bool {
+	for _, item := range strings.Fields(scope) {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// openIDEnabled tells if id tokens can be issued, the clients can only verify them when they're signed by an
+// asymmetric key published in the JWKS.
+func (s Service) openIDEnabled() bool {
+	signingKey, err := s.keyRing.SigningKey()
+
+	return err == nil && !signingKey.IsSymmetric()
+}
+
+// userInfo returns the claims allowed by the scope. Sessions started by the login have no scope, the user
+// is the one asking so every claim is returned.
+func userInfo(user entity.User, scope string) schemas.UserInfo {
+	info := schemas.UserInfo{Subject: user.ID.String()}
+	unrestricted := scope == ""
+
+	if unrestricted || hasScope(scope, ScopeProfile) {
+		info.Name = user.Name
+	}
+
+	if unrestricted || hasScope(scope, ScopeEmail) {
+		info.Email = user.Email
+		info.EmailVerified = &user.EmailVerified
+	}
+
+	if unrestricted || hasScope(scope, ScopePhone) {
+		phoneVerified := user.PhoneVerified()
+
+		info.PhoneNumber = user.Phone
+		info.PhoneNumberVerified = &phoneVerified
+	}
+
+	return info
+}
+
+func (s Service) generateIDToken(user entity.User, session entity.Session, nonce string) (string, error) {
+	signingKey, err := s.keyRing.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := auth.IDTokenClaims{
+		Issuer:   s.config.Issuer,
+		Audience: session.ClientID,
+		Nonce:    nonce,
+		UserInfo: userInfo(user, session.Scope),
+	}
+
+	return auth.GenerateIDToken(signingKey, claims, tokenDuration)
+}
+
+// UserInfo returns the claims about the owner of the session, sessions started by oauth clients must have
+// requested the openid scope.
+func (s Service) UserInfo(ctx context.Context, session entity.Session) (schemas.UserInfo, error) {
+	ctx, span := trace.NewSpan(ctx, "user-info")
+	defer span.End()
+
+	if session.Scope != "" && !hasScope(session.Scope, ScopeOpenID) {
+		return schemas.UserInfo{}, errors.Wrap(ErrInsufficientScope, "openid scope is required")
+	}
+
+	user, err := s.userService.Get(ctx, session.UserID)
+	if err != nil {
+		return schemas.UserInfo{}, err
+	}
+
+	return userInfo(user, session.Scope), nil
+}
+
+// OpenIDConfiguration is the discovery document, the endpoints are relative to the configured issuer.
+func (s Service) OpenIDConfiguration() schemas.OpenIDConfiguration {
+	issuer := s.config.Issuer
+
+	scopes := []string{ScopeProfile, ScopeEmail, ScopePhone}
+	algorithms := []string{}
+
+	if signingKey, err := s.keyRing.SigningKey(); err == nil && s.openIDEnabled() {
+		scopes = append([]string{ScopeOpenID}, scopes...)
+		algorithms = append(algorithms, signingKey.Algorithm)
+	}
+
+	return schemas.OpenIDConfiguration{
+		Issuer:                 issuer,
+		AuthorizationEndpoint:  issuer + "/oauth/authorize",
+		TokenEndpoint:          issuer + "/oauth/token",
+		UserInfoEndpoint:       issuer + "/userinfo",
+		JWKSURI:                issuer + "/.well-known/jwks.json",
+		RevocationEndpoint:     issuer + "/oauth/revoke",
+		IntrospectionEndpoint:  issuer + "/oauth/introspect",
+		ScopesSupported:        scopes,
+		ResponseTypesSupported: []string{ResponseTypeCode},
+		GrantTypesSupported: []string{
+			GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{auth.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "nonce", "name", "email", "email_verified", "phone_number",
+			"phone_number_verified",
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const passwordChangeTokenDuration = time.Minute * 15
+
+// startPasswordChange replaces the session of the login by a token that only changes the password, the user logs
+// in again with the new one.
+func (s Service) startPasswordChange(ctx context.Context, user entity.User) (schemas.LoginResponse, error) {
+	token, err := s.GenerateToken(ctx, user.ID, PasswordChangeTokenPrefix, passwordChangeTokenDuration)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Error on create password change token"}, err
+	}
+
+	return schemas.LoginResponse{
+		Message:             "Password change required",
+		PasswordChangeToken: token.Token,
+	}, nil
+}
+
+// ChangeRequiredPassword sets the new password with the token returned by the login, the new password must follow
+// the policy and the history like any other change.
+func (s Service) ChangeRequiredPassword(ctx context.Context, payload schemas.ChangeRequiredPassword) error {
+	ctx, span := trace.NewSpan(ctx, "change-required-password")
+	defer span.End()
+
+	userID, _, err := s.validateToken(ctx, payload.Token, PasswordChangeTokenPrefix)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.userService.Update(ctx, userID, schemas.UpdateUserPayload{Password: payload.Password})
+	if err != nil {
+		return err
+	}
+
+	return s.invalidateToken(ctx, userID, PasswordChangeTokenPrefix)
+}
+
+// ForcePasswordChange makes the next login of the user return a password change token instead of the session, the
+// current sessions are ended so they can't be used until the password changes.
+func (s Service) ForcePasswordChange(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := trace.NewSpan(ctx, "force-password-change")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user.ForcePasswordChange()
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return err
+	}
+
+	// No session has the nil id, so every session of the user ends.
+	return s.RevokeOtherSessions(ctx, userID, uuid.Nil)
+}
+
+// ChangePassword replaces the password of the logged user, the current one is required so a stolen access token
+// alone can't take over the account. The wrong passwords count to the login lockout and the other sessions are
+// revoked once the password changes.
+func (s Service) ChangePassword(
+	ctx context.Context, userID, sessionID uuid.UUID, payload schemas.ChangePasswordPayload,
+) error {
+	ctx, span := trace.NewSpan(ctx, "change-password")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyCurrentPassword(ctx, user, payload.CurrentPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.userService.Update(ctx, userID, schemas.UpdateUserPayload{Password: payload.NewPassword}); err != nil {
+		return err
+	}
+
+	if err := s.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	go s.sendEvent("password-changed", map[string]any{
+		"user":       map[string]string{"id": user.ID.String(), "name": user.Name, "email": user.Email},
+		"session_id": sessionID.String(),
+		"changed_at": time.Now(),
+	})
+
+	return nil
+}
+
+// verifyCurrentPassword checks the password of a logged user, the wrong passwords count to the login lockout.
+func (s Service) verifyCurrentPassword(ctx context.Context, user entity.User, password string) error {
+	if lockedUntil := s.loginLockedUntil(ctx, user.ID); !lockedUntil.IsZero() {
+		return ErrAccountLocked
+	}
+
+	if !user.ValidatePassword(password) {
+		lockedUntil, err := s.registerFailedLogin(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		if !lockedUntil.IsZero() {
+			return ErrAccountLocked
+		}
+
+		return ErrInvalidPassword
+	}
+
+	return s.cacheService.Del(ctx, loginAttemptsKey(user.ID))
+}
+
+// reAuthenticate requires the current password, or a totp code when the user has it enabled, before the factors of
+// the user change, so a stolen access token alone can't add or remove them.
+func (s Service) reAuthenticate(ctx context.Context, user entity.User, payload schemas.ReAuthentication) error {
+	switch {
+	case payload.Code != "" && user.TOTPEnabled:
+		return s.verifyTOTPAttempt(ctx, user, payload.Code)
+	case payload.CurrentPassword != "":
+		return s.verifyCurrentPassword(ctx, user, payload.CurrentPassword)
+	default:
+		return ErrReAuthRequired
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/logger"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+	validators "github.com/uesleicarvalhoo/go-auth-service/pkg/utils/validator"
+)
+
+// UpdateProfile applies the changes of the profile sent by the user. The email is where the password is recovered,
+// so changing it requires the current password, the old address is notified and the new one must be verified.
+func (s Service) UpdateProfile(
+	ctx context.Context, userID uuid.UUID, payload schemas.UpdateProfilePayload,
+) (*entity.User, error) {
+	ctx, span := trace.NewSpan(ctx, "update-profile")
+	defer span.End()
+
+	current, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := payload.Email != "" && normalizeEmail(payload.Email) != current.Email
+	if emailChanged {
+		if payload.CurrentPassword == "" {
+			return nil, ErrReAuthRequired
+		}
+
+		if err := s.verifyCurrentPassword(ctx, current, payload.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.userService.UpdateProfile(ctx, userID, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if !emailChanged {
+		return user, nil
+	}
+
+	go s.sendEvent("email-changed", map[string]any{
+		"user":       map[string]string{"id": user.ID.String(), "name": user.Name, "email": current.Email},
+		"new_email":  user.Email,
+		"changed_at": time.Now(),
+	})
+
+	// The email was already changed, the user can ask for the verification again when it can't be sent.
+	if err := s.sendEmailVerificationToken(ctx, *user); err != nil {
+		trace.AddSpanError(span, err)
+		logger.Error("Couldn't send the verification of the new email, ", err)
+	}
+
+	return user, nil
+}
+
+// normalizeEmail compares the emails as they're stored, the invalid ones are rejected by the update.
+func normalizeEmail(email string) string {
+	if normalized, err := validators.NormalizeEmail(email); err == nil {
+		return normalized
+	}
+
+	return email
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const MFAMethodRecoveryCode = "recovery_code"
+
+// mfaEnabled tells if the login of the user requires a second factor.
+func (s Service) mfaEnabled(ctx context.Context, user entity.User) (bool, error) {
+	if user.TOTPEnabled {
+		return true, nil
+	}
+
+	credentials, err := s.credentialRepository.ListByUser(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return len(credentials) > 0, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, the previous ones stop working.
+func (s Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	ctx, span := trace.NewSpan(ctx, "regenerate-recovery-codes")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.mfaEnabled(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !enabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	codes, err := user.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode consumes the code, the user is notified since it usually means a lost device. The codes are only
+// saved when nobody consumed one meanwhile, so the same code can't be used by concurrent logins.
+func (s Service) useRecoveryCode(ctx context.Context, user entity.User, code string) error {
+	previous := append(entity.StringList(nil), user.RecoveryCodes...)
+
+	if !user.ConsumeRecoveryCode(code) {
+		return ErrInvalidMFACode
+	}
+
+	saved, err := s.userService.SaveRecoveryCodes(ctx, user, previous)
+	if err != nil {
+		return err
+	}
+
+	if !saved {
+		return ErrInvalidMFACode
+	}
+
+	go s.sendEvent("recovery-code-used", map[string]string{
+		"user_id":         user.ID.String(),
+		"remaining_codes": strconv.Itoa(len(user.RecoveryCodes)),
+		"used_at":         time.Now().Format(time.RFC3339Nano),
+	})
+
+	return nil
+}
+
+// disableRecoveryCodes drops the recovery codes once the last second factor is removed.
+func (s Service) disableRecoveryCodes(ctx context.Context, user *entity.User) error {
+	enabled, err := s.mfaEnabled(ctx, *user)
+	if err != nil || enabled {
+		return err
+	}
+
+	user.ClearRecoveryCodes()
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+// RevokeToken invalidates the token as RFC 7009. Revoking a refresh token ends its session, so the access
+// tokens derived from it are revoked too. Invalid or already revoked tokens aren't an error, there's nothing
+// left to revoke. Tokens can only be revoked by the client they were issued to, so the first party tokens, that
+// have no client, can't be revoked by the clients.
+func (s Service) RevokeToken(ctx context.Context, clientID, token, tokenTypeHint string) error {
+	ctx, span := trace.NewSpan(ctx, "revoke-token")
+	defer span.End()
+
+	for _, tokenType := range tokenTypes(tokenTypeHint) {
+		userID, claims, err := s.validateToken(ctx, token, tokenTypePrefixes[tokenType])
+		if err != nil {
+			continue
+		}
+
+		if claims.ClientID != clientID {
+			return ErrClientMismatch
+		}
+
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			return nil
+		}
+
+		if tokenType == TokenTypeRefreshToken {
+			return s.endSession(ctx, userID, sessionID)
+		}
+
+		return s.invalidateToken(ctx, sessionID, AccessTokenPrefix)
+	}
+
+	if claims, err := s.validateClientToken(ctx, token); err == nil {
+		if claims.ClientID != clientID {
+			return ErrClientMismatch
+		}
+
+		return s.cacheService.Del(ctx, clientTokenKey(claims.ID))
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/logger"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
 )
 
@@ -20,32 +21,86 @@ const (
 	recoveryPasswordTokenDuration = time.Hour * 24
 )
 
+// LongestTokenLifetime is the lifetime of the longest lived token signed by the key ring, the tokens of the
+// clients can live up to their max lifetime.
+func LongestTokenLifetime(maxClientTokenLifetime time.Duration) time.Duration {
+	longest := maxClientTokenLifetime
+
+	for _, duration := range []time.Duration{
+		tokenDuration, config.SessionTime, recoveryPasswordTokenDuration, emailVerificationTokenDuration,
+		magicLinkTokenDuration, passwordChangeTokenDuration,
+	} {
+		if duration > longest {
+			longest = duration
+		}
+	}
+
+	return longest
+}
+
 type Service struct {
-	secretKey    string
-	eventChannel chan schemas.Event
-	userService  UserService
-	cacheService CacheService
+	config               Config
+	keyRing              KeyRing
+	eventChannel         chan schemas.Event
+	userService          UserService
+	cacheService         CacheService
+	credentialRepository WebAuthnCredentialRepository
 }
 
-func NewService(userSvc UserService, cacheSvc CacheService, secretKey string, eventCh chan schemas.Event) *Service {
+func NewService(
+	userSvc UserService,
+	credentialRepository WebAuthnCredentialRepository,
+	cacheSvc CacheService,
+	keyRing KeyRing,
+	cfg Config,
+	eventCh chan schemas.Event,
+) *Service {
 	return &Service{
-		eventChannel: eventCh,
-		userService:  userSvc,
-		cacheService: cacheSvc,
+		config:               cfg,
+		keyRing:              keyRing,
+		eventChannel:         eventCh,
+		userService:          userSvc,
+		cacheService:         cacheSvc,
+		credentialRepository: credentialRepository,
 	}
 }
 
+// GenerateToken issues a token bound to the user, only the last one generated for the prefix is valid.
 func (s Service) GenerateToken(
 	ctx context.Context, userID uuid.UUID, prefix TokenPrefix, duration time.Duration,
 ) (schemas.JwtToken, error) {
-	token, err := auth.GenerateJwtToken(s.secretKey, userID, duration)
+	return s.issueToken(ctx, auth.Claims{Subject: userID.String()}, prefix, tokenKey(prefix, userID), duration)
+}
+
+// generateEmailToken issues a token sent to the email of the user, it's bound to the address so it stops working
+// when the email is changed.
+func (s Service) generateEmailToken(
+	ctx context.Context, user entity.User, prefix TokenPrefix, duration time.Duration,
+) (schemas.JwtToken, error) {
+	claims := auth.Claims{Subject: user.ID.String(), Email: user.Email}
+
+	return s.issueToken(ctx, claims, prefix, tokenKey(prefix, user.ID), duration)
+}
+
+// issueToken signs the claims bound to the prefix with the token_use, iss and aud claims and caches the token.
+func (s Service) issueToken(
+	ctx context.Context, claims auth.Claims, prefix TokenPrefix, key string, duration time.Duration,
+) (schemas.JwtToken, error) {
+	signingKey, err := s.keyRing.SigningKey()
 	if err != nil {
 		return schemas.JwtToken{}, err
 	}
 
-	err = s.cacheService.Set(
-		ctx, fmt.Sprintf("%s-%s", prefix, userID.String()), token.Token, duration,
-	)
+	claims.TokenUse = prefix.TokenUse()
+	claims.Issuer = s.config.Issuer
+	claims.Audience = s.tokenAudience(prefix)
+
+	token, err := auth.GenerateJwtToken(signingKey, claims, duration)
+	if err != nil {
+		return schemas.JwtToken{}, err
+	}
+
+	err = s.cacheService.Set(ctx, key, token.Token, duration)
 	if err != nil {
 		return schemas.JwtToken{}, err
 	}
@@ -53,34 +108,119 @@ func (s Service) GenerateToken(
 	return token, nil
 }
 
-func (s Service) validateToken(ctx context.Context, token string, prefix TokenPrefix) (uuid.UUID, error) {
+// tokenKey is where the valid token is cached, id is the session id for session tokens or the user id otherwise.
+func tokenKey(prefix TokenPrefix, id uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", prefix, id.String())
+}
+
+// tokenAudience is the aud claim of the tokens of the prefix, the access tokens are meant for the resource servers
+// and the others only for this service.
+func (s Service) tokenAudience(prefix TokenPrefix) string {
+	if (prefix == AccessTokenPrefix || prefix == ClientAccessTokenPrefix) && s.config.TokenAudience != "" {
+		return s.config.TokenAudience
+	}
+
+	return s.config.Issuer
+}
+
+// parseToken verifies the signature and that the token was issued by this service for the prefix.
+func (s Service) parseToken(token string, prefix TokenPrefix) (auth.Claims, error) {
+	claims, err := auth.ValidateJwtToken(token, s.keyRing)
+	if err != nil {
+		return auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
+	}
+
+	if claims.TokenUse != prefix.TokenUse() || claims.Issuer != s.config.Issuer ||
+		claims.Audience != s.tokenAudience(prefix) {
+		return auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
+	}
+
+	return claims, nil
+}
+
+func (s Service) validateToken(ctx context.Context, token string, prefix TokenPrefix) (uuid.UUID, auth.Claims, error) {
 	ctx, span := trace.NewSpan(ctx, "validate-token")
 	defer span.End()
 
-	userID, err := auth.ValidateJwtToken(token, s.secretKey)
+	claims, err := s.parseToken(token, prefix)
 	if err != nil {
-		return uuid.UUID{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
+		return uuid.UUID{}, auth.Claims{}, err
 	}
 
-	cachedToken, _ := s.cacheService.Get(ctx, fmt.Sprintf("%s-%s", prefix, userID.String()))
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
+	}
+
+	key := tokenKey(prefix, userID)
+	if claims.SessionID != "" {
+		key = fmt.Sprintf("%s-%s", prefix, claims.SessionID)
+	}
+
+	cachedToken, _ := s.cacheService.Get(ctx, key)
 	if cachedToken == "" || cachedToken != "" && cachedToken != token {
-		return uuid.UUID{}, errors.Wrap(ErrNotAuthorized, "Token not found")
+		return uuid.UUID{}, auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Token not found")
 	}
 
 	user, err := s.userService.Get(ctx, userID)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, auth.Claims{}, err
+	}
+
+	return user.ID, claims, nil
+}
+
+// consumeToken validates the token and removes it from the cache at once, so it's accepted only on the first use
+// even when it's sent concurrently.
+func (s Service) consumeToken(
+	ctx context.Context, token string, prefix TokenPrefix,
+) (uuid.UUID, auth.Claims, error) {
+	ctx, span := trace.NewSpan(ctx, "consume-token")
+	defer span.End()
+
+	claims, err := s.parseToken(token, prefix)
+	if err != nil {
+		return uuid.UUID{}, auth.Claims{}, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.UUID{}, auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
 	}
 
-	return user.ID, nil
+	cachedToken, _ := s.cacheService.GetDel(ctx, tokenKey(prefix, userID))
+	if cachedToken == "" || cachedToken != token {
+		return uuid.UUID{}, auth.Claims{}, errors.Wrap(ErrNotAuthorized, "Token not found")
+	}
+
+	return userID, claims, nil
+}
+
+// restoreToken puts back a consumed token when the request failed, so the user can retry it with other input. A
+// token generated in the meantime isn't replaced.
+func (s Service) restoreToken(
+	ctx context.Context, userID uuid.UUID, prefix TokenPrefix, token string, claims auth.Claims,
+) {
+	ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+	if ttl <= 0 {
+		return
+	}
+
+	if _, err := s.cacheService.SetNX(ctx, tokenKey(prefix, userID), token, ttl); err != nil {
+		logger.Error("Couldn't restore the token, ", err)
+	}
 }
 
-func (s Service) invalidateToken(ctx context.Context, userID uuid.UUID, prefix TokenPrefix) error {
-	return s.cacheService.Del(ctx, fmt.Sprintf("%s-%s", prefix, userID.String()))
+func (s Service) invalidateToken(ctx context.Context, id uuid.UUID, prefix TokenPrefix) error {
+	return s.cacheService.Del(ctx, tokenKey(prefix, id))
 }
 
-func (s Service) ValidateAccessToken(ctx context.Context, token string) (uuid.UUID, error) {
-	return s.validateToken(ctx, token, AccessTokenPrefix)
+func (s Service) JWKS() auth.JWKS {
+	return s.keyRing.JWKS()
+}
+
+func (s Service) ValidateAccessToken(ctx context.Context, token string) (entity.Session, error) {
+	return s.validateSessionToken(ctx, token, AccessTokenPrefix)
 }
 
 func (s Service) SignUp(ctx context.Context, payload schemas.SignUp) (*entity.User, error) {
@@ -91,7 +231,9 @@ func (s Service) SignUp(ctx context.Context, payload schemas.SignUp) (*entity.Us
 		return nil, ErrEmailIsAlreadyUsed
 	}
 
-	user, err := entity.NewUser(payload.Name, payload.Email, payload.Phone, payload.Password)
+	user, err := entity.NewUser(
+		payload.Name, payload.Email, payload.Phone, payload.Password, s.config.PasswordPolicy.Policy(),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +243,12 @@ func (s Service) SignUp(ctx context.Context, payload schemas.SignUp) (*entity.Us
 		return nil, err
 	}
 
+	// The user is already created, the verification can be sent again when it fails.
+	if err := s.sendEmailVerificationToken(ctx, user); err != nil {
+		trace.AddSpanError(span, err)
+		logger.Error("Couldn't send the email verification, ", err)
+	}
+
 	return &user, nil
 }
 
@@ -115,37 +263,116 @@ func (s Service) Login(ctx context.Context, payload schemas.Login) (schemas.Logi
 		}, err
 	}
 
+	if lockedUntil := s.loginLockedUntil(ctx, user.ID); !lockedUntil.IsZero() {
+		return lockedLoginResponse(lockedUntil), ErrAccountLocked
+	}
+
 	if !user.ValidatePassword(payload.Password) {
+		lockedUntil, err := s.registerFailedLogin(ctx, user)
+		if err != nil {
+			return schemas.LoginResponse{
+				Message: "Error on verify password",
+			}, err
+		}
+
+		if !lockedUntil.IsZero() {
+			return lockedLoginResponse(lockedUntil), ErrAccountLocked
+		}
+
 		return schemas.LoginResponse{
 			Message: "Invalid password",
 		}, ErrNotAuthorized
 	}
 
+	// The new hash is best effort, the login goes on with the valid password when it can't be saved.
+	if err := s.rehashPassword(ctx, &user, payload.Password); err != nil {
+		trace.AddSpanError(span, err)
+		logger.Error("Couldn't rehash the password, ", err)
+	}
+
+	if response, err := s.verifyLoginAllowed(user); err != nil {
+		return response, err
+	}
+
+	return s.continueLogin(ctx, user, payload)
+}
+
+// rehashPassword upgrades the stored hash when the hasher or its costs changed since the password was set.
+func (s Service) rehashPassword(ctx context.Context, user *entity.User, password string) error {
+	rehashed, err := user.RehashPassword(password)
+	if err != nil || !rehashed {
+		return err
+	}
+
+	return s.userService.Save(ctx, *user)
+}
+
+// continueLogin starts the session once the first factor was verified, or the mfa challenge when the user has a
+// second factor.
+func (s Service) continueLogin(
+	ctx context.Context, user entity.User, payload schemas.Login,
+) (schemas.LoginResponse, error) {
+	credentials, err := s.credentialRepository.ListByUser(ctx, user.ID)
+	if err != nil {
+		return schemas.LoginResponse{
+			Message: "Error on verify second factor",
+		}, err
+	}
+
+	if user.TOTPEnabled || len(credentials) > 0 {
+		return s.startMFAChallenge(ctx, user, credentials, payload)
+	}
+
+	return s.completeLogin(ctx, user, payload.Device, payload.IPAddress, payload.UserAgent)
+}
+
+// verifyLoginAllowed checks the state of the account that blocks every kind of login.
+func (s Service) verifyLoginAllowed(user entity.User) (schemas.LoginResponse, error) {
 	if !user.Active {
 		return schemas.LoginResponse{
 			Message: "User is inactive",
 		}, ErrNotAuthorized
 	}
 
-	accessToken, err := s.GenerateToken(ctx, user.ID, AccessTokenPrefix, tokenDuration)
-	if err != nil {
+	if s.config.RequireEmailVerification && !user.EmailVerified {
 		return schemas.LoginResponse{
-			Message: "Error on generate access token",
-		}, err
+			Message: "Email is not verified",
+		}, ErrEmailNotVerified
+	}
+
+	return schemas.LoginResponse{}, nil
+}
+
+// completeLogin starts the session once every required factor was verified, or returns the password change token
+// when the password must be changed, so the token is never issued before the second factor.
+func (s Service) completeLogin(
+	ctx context.Context, user entity.User, device, ipAddress, userAgent string,
+) (schemas.LoginResponse, error) {
+	// The failed logins are only forgotten once every factor was verified, a valid password alone doesn't reset them.
+	if err := s.cacheService.Del(ctx, loginAttemptsKey(user.ID)); err != nil {
+		return schemas.LoginResponse{Message: "Error on verify password"}, err
+	}
+
+	if user.PasswordChangeRequired(s.config.PasswordMaxAge) {
+		return s.startPasswordChange(ctx, user)
 	}
 
-	refreshToken, err := s.GenerateToken(ctx, user.ID, RefreshAcessTokenPrefix, config.SessionTime)
+	session := entity.NewSession(user.ID, device, ipAddress, userAgent, config.SessionTime)
+
+	response, err := s.startSession(ctx, session)
 	if err != nil {
-		return schemas.LoginResponse{
-			Message: "Error on generate refresh access token",
-		}, err
+		return response, err
 	}
 
 	go s.sendEvent(
-		"login", map[string]string{"user_id": user.ID.String(), "logged_at": time.Now().Format(time.RFC3339Nano)},
+		"login", map[string]string{
+			"user_id":    user.ID.String(),
+			"session_id": session.ID.String(),
+			"logged_at":  time.Now().Format(time.RFC3339Nano),
+		},
 	)
 
-	return schemas.LoginResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+	return response, nil
 }
 
 func (s Service) RefreshAccessToken(
@@ -154,35 +381,68 @@ func (s Service) RefreshAccessToken(
 	ctx, span := trace.NewSpan(ctx, "refresh-token")
 	defer span.End()
 
-	userID, err := s.validateToken(ctx, refreshToken.Token, RefreshAcessTokenPrefix)
-	if err != nil {
+	return s.refreshSession(ctx, refreshToken.Token, "")
+}
+
+// refreshSession renews the session of the refresh token and rotates its tokens, the session must have been started
+// by the client, or by the first party login when the client id is empty.
+func (s Service) refreshSession(ctx context.Context, refreshToken, clientID string) (schemas.LoginResponse, error) {
+	if err := s.detectRefreshTokenReuse(ctx, refreshToken); err != nil {
 		return schemas.LoginResponse{
 			Message: "Invalid token",
 		}, err
 	}
 
-	newAccessToken, err := s.GenerateToken(ctx, userID, AccessTokenPrefix, tokenDuration)
+	session, err := s.validateSessionToken(ctx, refreshToken, RefreshAcessTokenPrefix)
 	if err != nil {
 		return schemas.LoginResponse{
-			Message: "Error on generate access token",
+			Message: "Invalid token",
 		}, err
 	}
 
-	newRefreshToken, err := s.GenerateToken(ctx, userID, RefreshAcessTokenPrefix, config.SessionTime)
+	if session.ClientID != clientID {
+		return schemas.LoginResponse{
+			Message: "Invalid token",
+		}, ErrClientMismatch
+	}
+
+	user, err := s.userService.Get(ctx, session.UserID)
 	if err != nil {
 		return schemas.LoginResponse{
-			Message: "Error on generate refresh access token",
+			Message: "Invalid token",
+		}, err
+	}
+
+	// The session isn't renewed past the expiration of the password, the user logs in again to change it.
+	if user.PasswordChangeRequired(s.config.PasswordMaxAge) {
+		if err := s.endSession(ctx, session.UserID, session.ID); err != nil {
+			return schemas.LoginResponse{
+				Message: "Error on end session",
+			}, err
+		}
+
+		return schemas.LoginResponse{
+			Message: "Password change required",
+		}, errors.Wrap(ErrNotAuthorized, "password change required")
+	}
+
+	session.Renew(config.SessionTime)
+
+	if err := s.saveSession(ctx, session); err != nil {
+		return schemas.LoginResponse{
+			Message: "Error on renew session",
 		}, err
 	}
 
-	return schemas.LoginResponse{AccessToken: newAccessToken, RefreshToken: newRefreshToken}, nil
+	return s.generateSessionTokens(ctx, session)
 }
 
-func (s Service) Logout(ctx context.Context, id uuid.UUID) error {
+// Logout ends the session, both its access and refresh tokens are invalidated.
+func (s Service) Logout(ctx context.Context, userID, sessionID uuid.UUID) error {
 	ctx, span := trace.NewSpan(ctx, "logout")
 	defer span.End()
 
-	return s.invalidateToken(ctx, id, AccessTokenPrefix)
+	return s.endSession(ctx, userID, sessionID)
 }
 
 func (s Service) SendRecoveryPasswordToken(ctx context.Context, payload schemas.SendRecoveryPasswordPayload) error {
@@ -212,17 +472,31 @@ func (s Service) RecoveryPassword(ctx context.Context, token, password string) e
 	ctx, span := trace.NewSpan(ctx, "recovery-password")
 	defer span.End()
 
-	userID, err := s.validateToken(ctx, token, RecoveryTokenPrefix)
+	// The token is consumed before the update, so concurrent requests can't reset the password twice.
+	userID, claims, err := s.consumeToken(ctx, token, RecoveryTokenPrefix)
 	if err != nil {
 		return err
 	}
 
 	_, err = s.userService.Update(ctx, userID, schemas.UpdateUserPayload{Password: password})
 	if err != nil {
+		s.restoreToken(ctx, userID, RecoveryTokenPrefix, token, claims)
+
 		return err
 	}
 
-	return s.invalidateToken(ctx, userID, RecoveryTokenPrefix)
+	// The user proved the ownership of the email, the failed logins don't block the new password.
+	if err := s.UnlockAccount(ctx, userID); err != nil {
+		return err
+	}
+
+	// Whoever reset the password may have registered a passkey with a stolen session, they're revoked so the user
+	// registers them again.
+	if err := s.revokeWebAuthnCredentials(ctx, userID); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (s Service) sendEvent(action string, data interface{}) {
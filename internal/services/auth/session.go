@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	sessionsKeyPrefix = "sessions"
+	// Last seen is only persisted once per interval, so validating a token doesn't always write to the cache.
+	sessionTouchInterval = time.Minute
+)
+
+// sessionsKey is the hash holding every session of the user, indexed by the session id.
+func sessionsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", sessionsKeyPrefix, userID.String())
+}
+
+func (s Service) saveSession(ctx context.Context, session entity.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return s.cacheService.HSetExp(
+		ctx, sessionsKey(session.UserID), config.SessionTime, session.ID.String(), string(data),
+	)
+}
+
+func (s Service) getSession(ctx context.Context, userID, sessionID uuid.UUID) (entity.Session, error) {
+	data, _ := s.cacheService.HGet(ctx, sessionsKey(userID), sessionID.String())
+	if data == "" {
+		return entity.Session{}, errors.Wrap(ErrNotAuthorized, "Session not found")
+	}
+
+	var session entity.Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return entity.Session{}, err
+	}
+
+	if session.IsExpired() {
+		return entity.Session{}, errors.Wrap(ErrNotAuthorized, "Session expired")
+	}
+
+	return session, nil
+}
+
+// startSession registers the session and issues its first pair of tokens.
+func (s Service) startSession(ctx context.Context, session entity.Session) (schemas.LoginResponse, error) {
+	if err := s.saveSession(ctx, session); err != nil {
+		return schemas.LoginResponse{
+			Message: "Error on create session",
+		}, err
+	}
+
+	return s.generateSessionTokens(ctx, session)
+}
+
+func (s Service) generateSessionTokens(ctx context.Context, session entity.Session) (schemas.LoginResponse, error) {
+	accessToken, err := s.generateSessionToken(ctx, session, AccessTokenPrefix, tokenDuration)
+	if err != nil {
+		return schemas.LoginResponse{
+			Message: "Error on generate access token",
+		}, err
+	}
+
+	refreshToken, err := s.generateSessionToken(ctx, session, RefreshAcessTokenPrefix, config.SessionTime)
+	if err != nil {
+		return schemas.LoginResponse{
+			Message: "Error on generate refresh access token",
+		}, err
+	}
+
+	return schemas.LoginResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s Service) generateSessionToken(
+	ctx context.Context, session entity.Session, prefix TokenPrefix, duration time.Duration,
+) (schemas.JwtToken, error) {
+	claims := auth.Claims{
+		Subject:   session.UserID.String(),
+		SessionID: session.ID.String(),
+		Scope:     session.Scope,
+		ClientID:  session.ClientID,
+	}
+
+	return s.issueToken(ctx, claims, prefix, tokenKey(prefix, session.ID), duration)
+}
+
+// validateSessionToken validates a token bound to a session and registers the session activity.
+func (s Service) validateSessionToken(ctx context.Context, token string, prefix TokenPrefix) (entity.Session, error) {
+	userID, claims, err := s.validateToken(ctx, token, prefix)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return entity.Session{}, errors.Wrap(ErrNotAuthorized, "Invalid Token")
+	}
+
+	session, err := s.getSession(ctx, userID, sessionID)
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		session.Touch()
+
+		if err := s.saveSession(ctx, session); err != nil {
+			return entity.Session{}, err
+		}
+	}
+
+	return session, nil
+}
+
+// detectRefreshTokenReuse checks if the refresh token was already rotated. The refresh tokens of a session
+// belong to the same family and only the last one issued is valid, presenting a superseded one means it
+// was probably stolen, so the whole family is revoked and both the attacker and the user must login again.
+func (s Service) detectRefreshTokenReuse(ctx context.Context, token string) error {
+	claims, err := s.parseToken(token, RefreshAcessTokenPrefix)
+	if err != nil {
+		return nil
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil
+	}
+
+	currentToken, _ := s.cacheService.Get(ctx, tokenKey(RefreshAcessTokenPrefix, sessionID))
+	if currentToken == "" || currentToken == token {
+		return nil
+	}
+
+	if _, err := s.getSession(ctx, userID, sessionID); err != nil {
+		return nil
+	}
+
+	if err := s.endSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	go s.sendEvent("refresh-token-reuse", map[string]string{
+		"user_id":     userID.String(),
+		"session_id":  sessionID.String(),
+		"token_id":    claims.ID,
+		"detected_at": time.Now().Format(time.RFC3339Nano),
+	})
+
+	return errors.Wrap(ErrNotAuthorized, "Refresh token reused")
+}
+
+// ListSessions returns the active sessions of the user, the most recently used first.
+func (s Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]entity.Session, error) {
+	ctx, span := trace.NewSpan(ctx, "list-sessions")
+	defer span.End()
+
+	data, err := s.cacheService.HGetAll(ctx, sessionsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.Session, 0, len(data))
+	expired := make([]string, 0)
+
+	for id, value := range data {
+		var session entity.Session
+		if err := json.Unmarshal([]byte(value), &session); err != nil {
+			return nil, err
+		}
+
+		if session.IsExpired() {
+			expired = append(expired, id)
+
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if len(expired) > 0 {
+		if err := s.cacheService.HDel(ctx, sessionsKey(userID), expired...); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession ends the session, invalidating its access and refresh tokens.
+func (s Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := trace.NewSpan(ctx, "revoke-session")
+	defer span.End()
+
+	if data, _ := s.cacheService.HGet(ctx, sessionsKey(userID), sessionID.String()); data == "" {
+		return ErrSessionNotFound
+	}
+
+	return s.endSession(ctx, userID, sessionID)
+}
+
+// RevokeOtherSessions ends every session of the user except the current one.
+func (s Service) RevokeOtherSessions(ctx context.Context, userID, currentSessionID uuid.UUID) error {
+	ctx, span := trace.NewSpan(ctx, "revoke-other-sessions")
+	defer span.End()
+
+	sessions, err := s.ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID == currentSessionID {
+			continue
+		}
+
+		if err := s.endSession(ctx, userID, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s Service) endSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.invalidateToken(ctx, sessionID, AccessTokenPrefix); err != nil {
+		return err
+	}
+
+	if err := s.invalidateToken(ctx, sessionID, RefreshAcessTokenPrefix); err != nil {
+		return err
+	}
+
+	return s.cacheService.HDel(ctx, sessionsKey(userID), sessionID.String())
+}
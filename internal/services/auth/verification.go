@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	emailVerificationTokenDuration = time.Hour * 24
+
+	// A new verification email is only sent once per interval, so the endpoint can't be used to flood inboxes.
+	emailVerificationSentKeyPrefix  = "email-verification-sent"
+	emailVerificationResendInterval = time.Minute
+
+	phoneVerificationKeyPrefix      = "phone-verification"
+	phoneVerificationCodeDigits     = 6
+	phoneVerificationDuration       = time.Minute * 10
+	phoneVerificationAttempts       = 5
+	phoneVerificationAttemptsPrefix = "phone-verification-attempts"
+	phoneVerificationSentKeyPrefix  = "phone-verification-sent"
+	phoneVerificationResendInterval = time.Minute
+)
+
+func emailVerificationSentKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", emailVerificationSentKeyPrefix, userID.String())
+}
+
+func phoneVerificationKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", phoneVerificationKeyPrefix, userID.String())
+}
+
+func phoneVerificationAttemptsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", phoneVerificationAttemptsPrefix, userID.String())
+}
+
+func phoneVerificationSentKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", phoneVerificationSentKeyPrefix, userID.String())
+}
+
+// SendEmailVerificationToken sends a new verification token to the user, the previous one stops working.
+func (s Service) SendEmailVerificationToken(ctx context.Context, payload schemas.SendEmailVerificationPayload) error {
+	ctx, span := trace.NewSpan(ctx, "send-email-verification-token")
+	defer span.End()
+
+	user, err := s.userService.GetByEmail(ctx, payload.Email)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerified {
+		return ErrEmailVerified
+	}
+
+	if sent, _ := s.cacheService.Get(ctx, emailVerificationSentKey(user.ID)); sent != "" {
+		return ErrTooManyRequests
+	}
+
+	return s.sendEmailVerificationToken(ctx, user)
+}
+
+func (s Service) sendEmailVerificationToken(ctx context.Context, user entity.User) error {
+	token, err := s.generateEmailToken(ctx, user, EmailVerificationTokenPrefix, emailVerificationTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	sentAt := time.Now().Format(time.RFC3339Nano)
+	if err := s.cacheService.Set(
+		ctx, emailVerificationSentKey(user.ID), sentAt, emailVerificationResendInterval,
+	); err != nil {
+		return err
+	}
+
+	go s.sendEvent("email-verification", map[string]any{
+		"user":               map[string]string{"id": user.ID.String(), "name": user.Name, "email": user.Email},
+		"verification_token": token.Token,
+		"expires_at":         token.ExpiresAt,
+	})
+
+	return nil
+}
+
+// VerifyEmail consumes the token sent to the user and marks the email as verified, the token only verifies the
+// address it was sent to.
+func (s Service) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := trace.NewSpan(ctx, "verify-email")
+	defer span.End()
+
+	userID, claims, err := s.validateToken(ctx, token, EmailVerificationTokenPrefix)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if claims.Email != user.Email {
+		return errors.Wrap(ErrNotAuthorized, "the token was sent to another email")
+	}
+
+	user.VerifyEmail()
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return err
+	}
+
+	return s.invalidateToken(ctx, userID, EmailVerificationTokenPrefix)
+}
+
+// SendPhoneVerificationCode sends a new code by SMS to the phone of the user, the previous one stops working.
+func (s Service) SendPhoneVerificationCode(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := trace.NewSpan(ctx, "send-phone-verification-code")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneVerified() {
+		return ErrPhoneVerified
+	}
+
+	if sent, _ := s.cacheService.Get(ctx, phoneVerificationSentKey(user.ID)); sent != "" {
+		return ErrTooManyRequests
+	}
+
+	code, err := auth.GenerateNumericCode(phoneVerificationCodeDigits)
+	if err != nil {
+		return err
+	}
+
+	verification := entity.PhoneVerification{
+		UserID:    user.ID,
+		Phone:     user.Phone,
+		Code:      code,
+		ExpiresAt: time.Now().Add(phoneVerificationDuration),
+	}
+
+	if err := s.savePhoneVerification(ctx, verification); err != nil {
+		return err
+	}
+
+	if err := s.cacheService.Del(ctx, phoneVerificationAttemptsKey(user.ID)); err != nil {
+		return err
+	}
+
+	sentAt := time.Now().Format(time.RFC3339Nano)
+	if err := s.cacheService.Set(
+		ctx, phoneVerificationSentKey(user.ID), sentAt, phoneVerificationResendInterval,
+	); err != nil {
+		return err
+	}
+
+	go s.sendEvent("phone-verification", map[string]any{
+		"user":              map[string]string{"id": user.ID.String(), "name": user.Name, "phone": user.Phone},
+		"verification_code": code,
+		"expires_at":        verification.ExpiresAt,
+	})
+
+	return nil
+}
+
+// VerifyPhone consumes the code sent to the user, the code is dropped after too many wrong attempts.
+func (s Service) VerifyPhone(ctx context.Context, userID uuid.UUID, code string) error {
+	ctx, span := trace.NewSpan(ctx, "verify-phone")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.PhoneVerified() {
+		return ErrPhoneVerified
+	}
+
+	verification, err := s.getPhoneVerification(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// The code was sent to a number that is no longer the phone of the user.
+	if verification.Phone != user.Phone {
+		if err := s.dropPhoneVerification(ctx, userID); err != nil {
+			return err
+		}
+
+		return errors.Wrap(ErrInvalidPhoneCode, "phone changed after the code was sent")
+	}
+
+	// The attempt is counted before the code is compared, so concurrent requests can't guess more codes.
+	attempts, err := s.cacheService.Incr(ctx, phoneVerificationAttemptsKey(userID), time.Until(verification.ExpiresAt))
+	if err != nil {
+		return err
+	}
+
+	if attempts > phoneVerificationAttempts {
+		if err := s.dropPhoneVerification(ctx, userID); err != nil {
+			return err
+		}
+
+		return errors.Wrap(ErrInvalidPhoneCode, "too many wrong attempts")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code), []byte(verification.Code)) != 1 {
+		if attempts == phoneVerificationAttempts {
+			if err := s.dropPhoneVerification(ctx, userID); err != nil {
+				return err
+			}
+		}
+
+		return ErrInvalidPhoneCode
+	}
+
+	user.VerifyPhone()
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return err
+	}
+
+	return s.dropPhoneVerification(ctx, userID)
+}
+
+func (s Service) dropPhoneVerification(ctx context.Context, userID uuid.UUID) error {
+	if err := s.cacheService.Del(ctx, phoneVerificationKey(userID)); err != nil {
+		return err
+	}
+
+	return s.cacheService.Del(ctx, phoneVerificationAttemptsKey(userID))
+}
+
+func (s Service) savePhoneVerification(ctx context.Context, verification entity.PhoneVerification) error {
+	data, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+
+	return s.cacheService.Set(
+		ctx, phoneVerificationKey(verification.UserID), string(data), time.Until(verification.ExpiresAt),
+	)
+}
+
+func (s Service) getPhoneVerification(ctx context.Context, userID uuid.UUID) (entity.PhoneVerification, error) {
+	data, _ := s.cacheService.Get(ctx, phoneVerificationKey(userID))
+	if data == "" {
+		return entity.PhoneVerification{}, errors.Wrap(ErrInvalidPhoneCode, "phone verification not found")
+	}
+
+	var verification entity.PhoneVerification
+	if err := json.Unmarshal([]byte(data), &verification); err != nil {
+		return entity.PhoneVerification{}, err
+	}
+
+	if verification.IsExpired() {
+		return entity.PhoneVerification{}, errors.Wrap(ErrInvalidPhoneCode, "phone verification expired")
+	}
+
+	return verification, nil
+}
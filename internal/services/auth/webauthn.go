@@ -0,0 +1,376 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+const (
+	MFAMethodWebAuthn = "webauthn"
+
+	webAuthnRegistrationKeyPrefix = "webauthn-registration"
+	webAuthnLoginKeyPrefix        = "webauthn-login"
+	webAuthnChallengeSize         = 32
+	webAuthnCeremonyDuration      = time.Minute * 5
+	webAuthnCredentialType        = "public-key"
+
+	webAuthnUserVerificationRequired  = "required"
+	webAuthnUserVerificationPreferred = "preferred"
+	webAuthnResidentKeyPreferred      = "preferred"
+	webAuthnAttestationNone           = "none"
+)
+
+func webAuthnRegistrationKey(userID uuid.UUID) string {
+	return fmt.Sprintf("%s-%s", webAuthnRegistrationKeyPrefix, userID.String())
+}
+
+func webAuthnLoginKey(challenge string) string {
+	return fmt.Sprintf("%s-%s", webAuthnLoginKeyPrefix, challenge)
+}
+
+func (s Service) webAuthnCeremony(challenge string, requireUserVerification bool) auth.WebAuthnCeremony {
+	return auth.WebAuthnCeremony{
+		RPID:                    s.config.WebAuthnRPID,
+		Origin:                  s.config.WebAuthnOrigin,
+		Challenge:               challenge,
+		RequireUserVerification: requireUserVerification,
+	}
+}
+
+// BeginWebAuthnRegistration returns the options to create a new credential once the user re-authenticates, the
+// challenge is valid for a few minutes and only the last one generated for the user is accepted.
+func (s Service) BeginWebAuthnRegistration(
+	ctx context.Context, userID uuid.UUID, payload schemas.ReAuthentication,
+) (schemas.WebAuthnCreationOptions, error) {
+	ctx, span := trace.NewSpan(ctx, "begin-webauthn-registration")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return schemas.WebAuthnCreationOptions{}, err
+	}
+
+	if err := s.reAuthenticate(ctx, user, payload); err != nil {
+		return schemas.WebAuthnCreationOptions{}, err
+	}
+
+	credentials, err := s.credentialRepository.ListByUser(ctx, user.ID)
+	if err != nil {
+		return schemas.WebAuthnCreationOptions{}, err
+	}
+
+	challenge, err := auth.GenerateSecret(webAuthnChallengeSize)
+	if err != nil {
+		return schemas.WebAuthnCreationOptions{}, err
+	}
+
+	err = s.cacheService.Set(ctx, webAuthnRegistrationKey(user.ID), challenge, webAuthnCeremonyDuration)
+	if err != nil {
+		return schemas.WebAuthnCreationOptions{}, err
+	}
+
+	parameters := make([]schemas.WebAuthnCredentialParameter, 0, len(auth.WebAuthnAlgorithms))
+	for _, algorithm := range auth.WebAuthnAlgorithms {
+		parameters = append(
+			parameters, schemas.WebAuthnCredentialParameter{Type: webAuthnCredentialType, Algorithm: algorithm},
+		)
+	}
+
+	return schemas.WebAuthnCreationOptions{
+		Challenge:    challenge,
+		RelyingParty: schemas.WebAuthnRelyingParty{ID: s.config.WebAuthnRPID, Name: config.ServiceName},
+		User: schemas.WebAuthnUser{
+			ID:          auth.EncodeWebAuthnID(user.ID[:]),
+			Name:        user.Email,
+			DisplayName: user.Name,
+		},
+		Parameters:         parameters,
+		Timeout:            webAuthnCeremonyDuration.Milliseconds(),
+		ExcludeCredentials: webAuthnDescriptors(credentials),
+		AuthenticatorSelection: schemas.WebAuthnAuthenticatorSelection{
+			ResidentKey:      webAuthnResidentKeyPreferred,
+			UserVerification: webAuthnUserVerificationPreferred,
+		},
+		Attestation: webAuthnAttestationNone,
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies the response of the authenticator and stores the new credential,
+// from now on it's accepted as second factor and, when it verifies the user, as passwordless login.
+// When it's the first second factor of the user the recovery codes are generated and returned.
+func (s Service) FinishWebAuthnRegistration(
+	ctx context.Context, userID uuid.UUID, payload schemas.WebAuthnRegistration,
+) (entity.WebAuthnCredential, []string, error) {
+	ctx, span := trace.NewSpan(ctx, "finish-webauthn-registration")
+	defer span.End()
+
+	challenge, _ := s.cacheService.GetDel(ctx, webAuthnRegistrationKey(userID))
+	if challenge == "" {
+		return entity.WebAuthnCredential{}, nil, errors.Wrap(ErrInvalidWebAuthnCredential, "registration not started")
+	}
+
+	clientData, err := auth.DecodeWebAuthnID(payload.ClientDataJSON)
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, ErrInvalidWebAuthnCredential
+	}
+
+	attestationObject, err := auth.DecodeWebAuthnID(payload.AttestationObject)
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, ErrInvalidWebAuthnCredential
+	}
+
+	created, err := auth.VerifyWebAuthnRegistration(s.webAuthnCeremony(challenge, false), clientData, attestationObject)
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, errors.Wrap(ErrInvalidWebAuthnCredential, err.Error())
+	}
+
+	credential := entity.NewWebAuthnCredential(userID, payload.Name, created)
+
+	if _, err := s.credentialRepository.Get(ctx, credential.ID); err == nil {
+		return entity.WebAuthnCredential{}, nil, ErrWebAuthnCredentialAlreadyRegistered
+	}
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, err
+	}
+
+	mfaWasEnabled, err := s.mfaEnabled(ctx, user)
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, err
+	}
+
+	if err := s.credentialRepository.Create(ctx, credential); err != nil {
+		return entity.WebAuthnCredential{}, nil, err
+	}
+
+	if mfaWasEnabled {
+		return credential, nil, nil
+	}
+
+	recoveryCodes, err := user.GenerateRecoveryCodes()
+	if err != nil {
+		return entity.WebAuthnCredential{}, nil, err
+	}
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return entity.WebAuthnCredential{}, nil, err
+	}
+
+	return credential, recoveryCodes, nil
+}
+
+func (s Service) ListWebAuthnCredentials(ctx context.Context, userID uuid.UUID) ([]entity.WebAuthnCredential, error) {
+	ctx, span := trace.NewSpan(ctx, "list-webauthn-credentials")
+	defer span.End()
+
+	return s.credentialRepository.ListByUser(ctx, userID)
+}
+
+// DeleteWebAuthnCredential removes the credential once the user re-authenticates, a stolen access token alone can't
+// remove the second factor.
+func (s Service) DeleteWebAuthnCredential(
+	ctx context.Context, userID uuid.UUID, id string, payload schemas.ReAuthentication,
+) error {
+	ctx, span := trace.NewSpan(ctx, "delete-webauthn-credential")
+	defer span.End()
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.reAuthenticate(ctx, user, payload); err != nil {
+		return err
+	}
+
+	if err := s.credentialRepository.Delete(ctx, userID, id); err != nil {
+		return errors.Wrap(ErrWebAuthnCredentialNotFound, err.Error())
+	}
+
+	if err := s.disableRecoveryCodes(ctx, &user); err != nil {
+		return err
+	}
+
+	return s.userService.Save(ctx, user)
+}
+
+// revokeWebAuthnCredentials removes all the credentials of the user, the removed ones are listed in an event so the
+// user can review them.
+func (s Service) revokeWebAuthnCredentials(ctx context.Context, userID uuid.UUID) error {
+	credentials, err := s.credentialRepository.ListByUser(ctx, userID)
+	if err != nil || len(credentials) == 0 {
+		return err
+	}
+
+	revoked := make([]map[string]any, 0, len(credentials))
+
+	for _, credential := range credentials {
+		if err := s.credentialRepository.Delete(ctx, userID, credential.ID); err != nil {
+			return err
+		}
+
+		revoked = append(revoked, map[string]any{
+			"id": credential.ID, "name": credential.Name, "created_at": credential.CreatedAt,
+		})
+	}
+
+	user, err := s.userService.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.disableRecoveryCodes(ctx, &user); err != nil {
+		return err
+	}
+
+	if err := s.userService.Save(ctx, user); err != nil {
+		return err
+	}
+
+	go s.sendEvent("webauthn-credentials-revoked", map[string]any{
+		"user":        map[string]string{"id": user.ID.String(), "name": user.Name, "email": user.Email},
+		"credentials": revoked,
+		"revoked_at":  time.Now(),
+	})
+
+	return nil
+}
+
+// BeginWebAuthnLogin returns the options of a passwordless login, no credential is listed so the
+// authenticator offers the passkeys it holds for the relying party.
+func (s Service) BeginWebAuthnLogin(ctx context.Context) (schemas.WebAuthnRequestOptions, error) {
+	ctx, span := trace.NewSpan(ctx, "begin-webauthn-login")
+	defer span.End()
+
+	challenge, err := auth.GenerateSecret(webAuthnChallengeSize)
+	if err != nil {
+		return schemas.WebAuthnRequestOptions{}, err
+	}
+
+	if err := s.cacheService.Set(ctx, webAuthnLoginKey(challenge), challenge, webAuthnCeremonyDuration); err != nil {
+		return schemas.WebAuthnRequestOptions{}, err
+	}
+
+	return s.webAuthnRequestOptions(challenge, nil, webAuthnUserVerificationRequired), nil
+}
+
+// FinishWebAuthnLogin starts a session for the owner of the credential, the authenticator must have verified
+// the user, so the passkey replaces both the password and the second factor.
+func (s Service) FinishWebAuthnLogin(
+	ctx context.Context, payload schemas.WebAuthnLogin,
+) (schemas.LoginResponse, error) {
+	ctx, span := trace.NewSpan(ctx, "finish-webauthn-login")
+	defer span.End()
+
+	challenge, _ := s.cacheService.GetDel(ctx, webAuthnLoginKey(payload.Challenge))
+	if payload.Challenge == "" || challenge == "" {
+		return schemas.LoginResponse{Message: "Invalid challenge"}, errors.Wrap(ErrNotAuthorized, "challenge not found")
+	}
+
+	credential, err := s.verifyWebAuthnAssertion(ctx, uuid.Nil, s.webAuthnCeremony(challenge, true), payload.Credential)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid credential"}, err
+	}
+
+	user, err := s.userService.Get(ctx, credential.UserID)
+	if err != nil {
+		return schemas.LoginResponse{Message: "Invalid credential"}, err
+	}
+
+	if response, err := s.verifyLoginAllowed(user); err != nil {
+		return response, err
+	}
+
+	return s.completeLogin(ctx, user, payload.Device, payload.IPAddress, payload.UserAgent)
+}
+
+// verifyWebAuthnAssertion checks the signature with the stored public key and registers the use of the
+// credential. When the user id is set the credential must belong to that user.
+func (s Service) verifyWebAuthnAssertion(
+	ctx context.Context, userID uuid.UUID, ceremony auth.WebAuthnCeremony, assertion schemas.WebAuthnAssertion,
+) (entity.WebAuthnCredential, error) {
+	credentialID, err := auth.DecodeWebAuthnID(assertion.ID)
+	if err != nil {
+		return entity.WebAuthnCredential{}, ErrInvalidWebAuthnCredential
+	}
+
+	credential, err := s.credentialRepository.Get(ctx, auth.EncodeWebAuthnID(credentialID))
+	if err != nil {
+		return entity.WebAuthnCredential{}, errors.Wrap(ErrInvalidWebAuthnCredential, "credential not found")
+	}
+
+	if userID != uuid.Nil && credential.UserID != userID {
+		return entity.WebAuthnCredential{}, errors.Wrap(ErrInvalidWebAuthnCredential, "credential of another user")
+	}
+
+	// Discoverable credentials return the user id given on the registration.
+	if assertion.UserHandle != "" {
+		userHandle, err := auth.DecodeWebAuthnID(assertion.UserHandle)
+		if err != nil || auth.EncodeWebAuthnID(userHandle) != auth.EncodeWebAuthnID(credential.UserID[:]) {
+			return entity.WebAuthnCredential{}, errors.Wrap(ErrInvalidWebAuthnCredential, "user handle mismatch")
+		}
+	}
+
+	clientData, errClientData := auth.DecodeWebAuthnID(assertion.ClientDataJSON)
+	authData, errAuthData := auth.DecodeWebAuthnID(assertion.AuthenticatorData)
+	signature, errSignature := auth.DecodeWebAuthnID(assertion.Signature)
+
+	if errClientData != nil || errAuthData != nil || errSignature != nil {
+		return entity.WebAuthnCredential{}, ErrInvalidWebAuthnCredential
+	}
+
+	signCount, err := auth.VerifyWebAuthnAssertion(ceremony, credential.PublicKey, clientData, authData, signature)
+	if err != nil {
+		return entity.WebAuthnCredential{}, errors.Wrap(ErrInvalidWebAuthnCredential, err.Error())
+	}
+
+	if !credential.UpdateSignCount(signCount) {
+		go s.sendEvent("webauthn-cloned-credential", map[string]string{
+			"user_id":       credential.UserID.String(),
+			"credential_id": credential.ID,
+			"detected_at":   time.Now().Format(time.RFC3339Nano),
+		})
+
+		return entity.WebAuthnCredential{}, errors.Wrap(ErrInvalidWebAuthnCredential, "signature counter didn't increase")
+	}
+
+	if err := s.credentialRepository.Update(ctx, credential); err != nil {
+		return entity.WebAuthnCredential{}, err
+	}
+
+	return credential, nil
+}
+
+func (s Service) webAuthnRequestOptions(
+	challenge string, credentials []entity.WebAuthnCredential, userVerification string,
+) schemas.WebAuthnRequestOptions {
+	return schemas.WebAuthnRequestOptions{
+		Challenge:        challenge,
+		RelyingPartyID:   s.config.WebAuthnRPID,
+		Timeout:          webAuthnCeremonyDuration.Milliseconds(),
+		AllowCredentials: webAuthnDescriptors(credentials),
+		UserVerification: userVerification,
+	}
+}
+
+func webAuthnDescriptors(credentials []entity.WebAuthnCredential) []schemas.WebAuthnCredentialDescriptor {
+	descriptors := make([]schemas.WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, schemas.WebAuthnCredentialDescriptor{
+			Type: webAuthnCredentialType,
+			ID:   credential.ID,
+		})
+	}
+
+	return descriptors
+}
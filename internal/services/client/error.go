@@ -0,0 +1,5 @@
+package client
+
+import "errors"
+
+var ErrInvalidClient = errors.New("invalid client credentials")
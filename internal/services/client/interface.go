@@ -0,0 +1,15 @@
+package client
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+)
+
+type Config = config.ClientConfig
+
+type Repository interface {
+	Get(ctx context.Context, id string) (entity.Client, error)
+	Create(ctx context.Context, client entity.Client) error
+}
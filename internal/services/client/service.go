@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+type Service struct {
+	cfg        Config
+	repository Repository
+}
+
+func NewService(repository Repository, cfg Config) *Service {
+	return &Service{cfg: cfg, repository: repository}
+}
+
+// Create registers the client and returns its secret, it's the only time the secret is available.
+func (s Service) Create(ctx context.Context, payload schemas.CreateClientPayload) (entity.Client, string, error) {
+	ctx, span := trace.NewSpan(ctx, "client.create")
+	defer span.End()
+
+	client, secret, err := entity.NewClient(
+		payload.Name, payload.RedirectURIs, payload.Public, payload.AllowedScopes, payload.TokenLifetime,
+		s.cfg.MaxTokenLifetime,
+	)
+	if err != nil {
+		return entity.Client{}, "", err
+	}
+
+	if err := s.repository.Create(ctx, client); err != nil {
+		return entity.Client{}, "", err
+	}
+
+	return client, secret, nil
+}
+
+func (s Service) Get(ctx context.Context, id string) (entity.Client, error) {
+	ctx, span := trace.NewSpan(ctx, "client.get")
+	defer span.End()
+
+	return s.repository.Get(ctx, id)
+}
+
+// Authenticate returns the client when the secret matches, the same error is returned for unknown clients.
+// Public clients are identified only by their id, they must not send a secret.
+func (s Service) Authenticate(ctx context.Context, id, secret string) (entity.Client, error) {
+	ctx, span := trace.NewSpan(ctx, "client.authenticate")
+	defer span.End()
+
+	client, err := s.repository.Get(ctx, id)
+	if err != nil || !client.ValidateSecret(secret) {
+		return entity.Client{}, ErrInvalidClient
+	}
+
+	return client, nil
+}
@@ -0,0 +1,193 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/repository"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/client"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
+)
+
+type Sut struct {
+	service    *client.Service
+	repository *repository.ClientRepository
+}
+
+func newSut() Sut {
+	db, err := database.NewSQLiteMemoryConnection()
+	if err != nil {
+		panic(err)
+	}
+
+	err = repository.AutoMigrate(db)
+	if err != nil {
+		panic(err)
+	}
+
+	clientRepository := repository.NewClientRepository(db)
+
+	return Sut{
+		service:    client.NewService(clientRepository, client.Config{MaxTokenLifetime: time.Hour}),
+		repository: clientRepository,
+	}
+}
+
+func TestCreate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario      string
+		payload       schemas.CreateClientPayload
+		expectedError string
+	}{
+		{
+			scenario: "should save client in repository",
+			payload: schemas.CreateClientPayload{
+				Name:          gofakeit.Company(),
+				AllowedScopes: []string{"reports:read"},
+				TokenLifetime: 300,
+			},
+		},
+		{
+			scenario: "when client is public should not return a secret",
+			payload: schemas.CreateClientPayload{
+				Name:         gofakeit.Company(),
+				RedirectURIs: []string{"https://app.example.com/callback"},
+				Public:       true,
+			},
+		},
+		{
+			scenario: "when redirect uri is relative should return an error",
+			payload: schemas.CreateClientPayload{
+				Name:         gofakeit.Company(),
+				RedirectURIs: []string{"/callback"},
+			},
+			expectedError: "redirect_uris: '/callback' is not a valid absolute uri",
+		},
+		{
+			scenario: "when token lifetime is longer than the max should return an error",
+			payload: schemas.CreateClientPayload{
+				Name:          gofakeit.Company(),
+				TokenLifetime: 7200,
+			},
+			expectedError: "token_lifetime: must be at most 3600",
+		},
+		{
+			scenario:      "when name is empty should return an error",
+			payload:       schemas.CreateClientPayload{Name: " "},
+			expectedError: "name: field is required",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut()
+
+			// Action
+			created, secret, err := sut.service.Create(context.TODO(), tc.payload)
+
+			// Assert
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.payload.Public, secret == "")
+
+				stored, err := sut.repository.Get(context.TODO(), created.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.payload.Name, stored.Name)
+				assert.Equal(t, tc.payload.Public, stored.Public)
+				assert.ElementsMatch(t, tc.payload.RedirectURIs, stored.RedirectURIs)
+				assert.ElementsMatch(t, tc.payload.AllowedScopes, stored.AllowedScopes)
+				assert.Equal(t, tc.payload.TokenLifetime, stored.TokenLifetime)
+				assert.True(t, stored.ValidateSecret(secret))
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	sut := newSut()
+
+	created, secret, err := sut.service.Create(context.TODO(), schemas.CreateClientPayload{Name: gofakeit.Company()})
+	assert.NoError(t, err)
+
+	public, _, err := sut.service.Create(
+		context.TODO(), schemas.CreateClientPayload{Name: gofakeit.Company(), Public: true},
+	)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario      string
+		clientID      string
+		secret        string
+		expectedID    string
+		expectedError error
+	}{
+		{
+			scenario:   "when credentials are valid",
+			clientID:   created.ID,
+			secret:     secret,
+			expectedID: created.ID,
+		},
+		{
+			scenario:      "when confidential client does not send the secret",
+			clientID:      created.ID,
+			expectedError: client.ErrInvalidClient,
+		},
+		{
+			scenario:   "when client is public only the id is required",
+			clientID:   public.ID,
+			expectedID: public.ID,
+		},
+		{
+			scenario:      "when public client sends a secret",
+			clientID:      public.ID,
+			secret:        secret,
+			expectedError: client.ErrInvalidClient,
+		},
+		{
+			scenario:      "when secret is wrong",
+			clientID:      created.ID,
+			secret:        "wrong-secret",
+			expectedError: client.ErrInvalidClient,
+		},
+		{
+			scenario:      "when client does not exist",
+			clientID:      "unknown",
+			secret:        secret,
+			expectedError: client.ErrInvalidClient,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			authenticated, err := sut.service.Authenticate(context.TODO(), tc.clientID, tc.secret)
+
+			// Assert
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedID, authenticated.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package keyring
+
+import (
+	"context"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/config"
+)
+
+type Config = config.JwtConfig
+
+type Repository interface {
+	List(ctx context.Context) ([]entity.SigningKey, error)
+	Create(ctx context.Context, key entity.SigningKey) error
+	Update(ctx context.Context, key entity.SigningKey) error
+}
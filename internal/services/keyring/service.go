@@ -0,0 +1,169 @@
+package keyring
+
+import (
+	"context"
+	"time"
+
+	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/logger"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
+)
+
+type Service struct {
+	cfg           Config
+	encryptionKey string
+	repository    Repository
+	keyRing       *auth.KeyRing
+}
+
+func NewService(repository Repository, cfg Config, encryptionKey string) *Service {
+	return &Service{
+		cfg:           cfg,
+		encryptionKey: encryptionKey,
+		repository:    repository,
+		keyRing:       auth.NewKeyRing(),
+	}
+}
+
+func (s Service) KeyRing() *auth.KeyRing {
+	return s.keyRing
+}
+
+// Load replaces the keys of the ring with the ones stored in the repository.
+func (s Service) Load(ctx context.Context) error {
+	ctx, span := trace.NewSpan(ctx, "keyring.load")
+	defer span.End()
+
+	storedKeys, err := s.repository.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]auth.SigningKey, 0, len(storedKeys))
+
+	for _, storedKey := range storedKeys {
+		privateKey, err := auth.Decrypt(s.encryptionKey, storedKey.PrivateKey)
+		if err != nil {
+			return err
+		}
+
+		key, err := auth.ParseSigningKey(storedKey.ID, storedKey.Algorithm, privateKey)
+		if err != nil {
+			return err
+		}
+
+		key.ActivatesAt = storedKey.ActivatesAt
+		key.RetiresAt = storedKey.RetiresAt
+		keys = append(keys, key)
+	}
+
+	s.keyRing.Replace(keys...)
+
+	return nil
+}
+
+// Bootstrap stores the configured key when the repository is empty and loads the ring. The key is only loaded
+// on the bootstrap, once the repository has keys the configured one is ignored and the rotations replace it.
+func (s Service) Bootstrap(ctx context.Context, loadKey func() (auth.SigningKey, error)) error {
+	ctx, span := trace.NewSpan(ctx, "keyring.bootstrap")
+	defer span.End()
+
+	storedKeys, err := s.repository.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(storedKeys) == 0 {
+		key, err := loadKey()
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.store(ctx, key, time.Now()); err != nil {
+			return err
+		}
+	} else {
+		logger.Infof("%d signing keys loaded from the database, the configured key is ignored", len(storedKeys))
+	}
+
+	return s.Load(ctx)
+}
+
+// Rotate creates a new signing key and schedules the retirement of the current ones.
+func (s Service) Rotate(ctx context.Context) (entity.SigningKey, error) {
+	ctx, span := trace.NewSpan(ctx, "keyring.rotate")
+	defer span.End()
+
+	storedKeys, err := s.repository.List(ctx)
+	if err != nil {
+		return entity.SigningKey{}, err
+	}
+
+	key, err := auth.GenerateSigningKey(s.cfg.Algorithm)
+	if err != nil {
+		return entity.SigningKey{}, err
+	}
+
+	activatesAt := time.Now().Add(s.cfg.KeyActivationDelay)
+	retiresAt := activatesAt.Add(s.cfg.KeyRetirementDelay)
+
+	for _, storedKey := range storedKeys {
+		if !storedKey.RetiresAt.IsZero() && storedKey.RetiresAt.Before(retiresAt) {
+			continue
+		}
+
+		storedKey.RetiresAt = retiresAt
+		if err := s.repository.Update(ctx, storedKey); err != nil {
+			return entity.SigningKey{}, err
+		}
+	}
+
+	newKey, err := s.store(ctx, key, activatesAt)
+	if err != nil {
+		return entity.SigningKey{}, err
+	}
+
+	logger.Infof("Signing key %s created, it will be activated at %s", newKey.ID, activatesAt.Format(time.RFC3339))
+
+	return newKey, s.Load(ctx)
+}
+
+// Watch reloads the ring periodically, so rotations made by other instances are picked up.
+func (s Service) Watch(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.KeyRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Load(ctx); err != nil {
+				logger.Error("Couldn't reload signing keys, ", err)
+			}
+		}
+	}
+}
+
+func (s Service) store(ctx context.Context, key auth.SigningKey, activatesAt time.Time) (entity.SigningKey, error) {
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return entity.SigningKey{}, err
+	}
+
+	encryptedKey, err := auth.Encrypt(s.encryptionKey, privateKey)
+	if err != nil {
+		return entity.SigningKey{}, err
+	}
+
+	storedKey := entity.SigningKey{
+		ID:          key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  encryptedKey,
+		ActivatesAt: activatesAt,
+		CreatedAt:   time.Now(),
+	}
+
+	return storedKey, s.repository.Create(ctx, storedKey)
+}
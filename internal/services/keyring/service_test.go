@@ -0,0 +1,175 @@
+package keyring_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/repository"
+	"github.com/uesleicarvalhoo/go-auth-service/internal/services/keyring"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
+)
+
+const testEncryptionKey = "my-test-encryption-key"
+
+type Sut struct {
+	service    *keyring.Service
+	repository *repository.SigningKeyRepository
+}
+
+func newSut(cfg keyring.Config) Sut {
+	db, err := database.NewSQLiteMemoryConnection()
+	if err != nil {
+		panic(err)
+	}
+
+	err = repository.AutoMigrate(db)
+	if err != nil {
+		panic(err)
+	}
+
+	keyRepository := repository.NewSigningKeyRepository(db)
+
+	return Sut{
+		service:    keyring.NewService(keyRepository, cfg, testEncryptionKey),
+		repository: keyRepository,
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(keyring.Config{Algorithm: auth.AlgorithmHS256})
+	configuredKey := auth.NewHMACKey("", "my-secret-key")
+
+	// Action
+	err := sut.service.Bootstrap(context.TODO(), func() (auth.SigningKey, error) { return configuredKey, nil })
+
+	// Assert
+	assert.NoError(t, err)
+
+	signingKey, err := sut.service.KeyRing().SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, configuredKey.ID, signingKey.ID)
+
+	storedKeys, err := sut.repository.List(context.TODO())
+	assert.NoError(t, err)
+	assert.Len(t, storedKeys, 1)
+	assert.NotContains(t, storedKeys[0].PrivateKey, "my-secret-key")
+}
+
+func TestBootstrapWhenTheRepositoryHasKeys(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut(keyring.Config{Algorithm: auth.AlgorithmHS256})
+	storedKey := auth.NewHMACKey("", "my-secret-key")
+
+	err := sut.service.Bootstrap(context.TODO(), func() (auth.SigningKey, error) { return storedKey, nil })
+	assert.NoError(t, err)
+
+	loaded := false
+
+	// Action
+	err = sut.service.Bootstrap(context.TODO(), func() (auth.SigningKey, error) {
+		loaded = true
+
+		return auth.SigningKey{}, errors.New("missing key file")
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, loaded, "the configured key is only loaded on the bootstrap")
+
+	signingKey, err := sut.service.KeyRing().SigningKey()
+	assert.NoError(t, err)
+	assert.Equal(t, storedKey.ID, signingKey.ID)
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario                  string
+		cfg                       keyring.Config
+		expectNewKeySigning       bool
+		expectPreviousKeyVerifies bool
+	}{
+		{
+			scenario: "when new key is activated immediately the previous one keeps verifying tokens",
+			cfg: keyring.Config{
+				Algorithm:          auth.AlgorithmES256,
+				KeyRetirementDelay: time.Hour,
+			},
+			expectNewKeySigning:       true,
+			expectPreviousKeyVerifies: true,
+		},
+		{
+			scenario: "when new key has an activation delay the previous one keeps signing tokens",
+			cfg: keyring.Config{
+				Algorithm:          auth.AlgorithmEdDSA,
+				KeyActivationDelay: time.Hour,
+				KeyRetirementDelay: time.Hour,
+			},
+			expectNewKeySigning:       false,
+			expectPreviousKeyVerifies: true,
+		},
+		{
+			scenario: "when previous key has no retirement delay its tokens are rejected",
+			cfg: keyring.Config{
+				Algorithm: auth.AlgorithmRS256,
+			},
+			expectNewKeySigning:       true,
+			expectPreviousKeyVerifies: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(tc.cfg)
+			previousKey := auth.NewHMACKey("", "my-secret-key")
+
+			err := sut.service.Bootstrap(context.TODO(), func() (auth.SigningKey, error) { return previousKey, nil })
+			assert.NoError(t, err)
+
+			previousToken, err := auth.GenerateJwtToken(previousKey, auth.Claims{}, time.Minute)
+			assert.NoError(t, err)
+
+			// Action
+			newKey, err := sut.service.Rotate(context.TODO())
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.cfg.Algorithm, newKey.Algorithm)
+
+			signingKey, err := sut.service.KeyRing().SigningKey()
+			assert.NoError(t, err)
+
+			if tc.expectNewKeySigning {
+				assert.Equal(t, newKey.ID, signingKey.ID)
+			} else {
+				assert.Equal(t, previousKey.ID, signingKey.ID)
+			}
+
+			_, err = auth.ValidateJwtToken(previousToken.Token, sut.service.KeyRing())
+			if tc.expectPreviousKeyVerifies {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, auth.ErrRetiredKey)
+			}
+
+			jwks := sut.service.KeyRing().JWKS()
+			assert.Len(t, jwks.Keys, 1)
+			assert.Equal(t, newKey.ID, jwks.Keys[0].KeyID)
+		})
+	}
+}
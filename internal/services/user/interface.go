@@ -12,5 +12,12 @@ type Repository interface {
 	GetByEmail(ctx context.Context, email string) (user entity.User, err error)
 	Create(ctx context.Context, user entity.User) error
 	Update(ctx context.Context, user entity.User) error
+	UpdateRecoveryCodes(ctx context.Context, user entity.User, previous entity.StringList) (bool, error)
 	DeleteByID(ctx context.Context, id uuid.UUID) error
 }
+
+type PasswordHistoryRepository interface {
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]entity.PasswordHistory, error)
+	Create(ctx context.Context, entry entity.PasswordHistory) error
+	Prune(ctx context.Context, userID uuid.UUID, keep int) error
+}
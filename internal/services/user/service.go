@@ -8,16 +8,29 @@ import (
 	"github.com/google/uuid"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/domain/entity"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/trace"
 )
 
 type Service struct {
-	eventChannel chan schemas.Event
-	repository   Repository
+	eventChannel    chan schemas.Event
+	repository      Repository
+	passwordHistory PasswordHistoryRepository
+	passwordPolicy  auth.PasswordPolicy
 }
 
-func NewService(repository Repository, eventChannel chan schemas.Event) *Service {
-	return &Service{repository: repository, eventChannel: eventChannel}
+func NewService(
+	repository Repository,
+	passwordHistory PasswordHistoryRepository,
+	passwordPolicy auth.PasswordPolicy,
+	eventChannel chan schemas.Event,
+) *Service {
+	return &Service{
+		repository:      repository,
+		passwordHistory: passwordHistory,
+		passwordPolicy:  passwordPolicy,
+		eventChannel:    eventChannel,
+	}
 }
 
 func (s Service) Get(ctx context.Context, id uuid.UUID) (entity.User, error) {
@@ -57,7 +70,14 @@ func (s Service) Update(ctx context.Context, id uuid.UUID, payload schemas.Updat
 		return nil, err
 	}
 
-	err = user.Update(payload)
+	history, err := s.recentPasswords(ctx, user.ID, payload.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	previousHash := user.PasswordHash
+
+	err = user.Update(payload, s.passwordPolicy, history)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +87,42 @@ func (s Service) Update(ctx context.Context, id uuid.UUID, payload schemas.Updat
 		return nil, err
 	}
 
+	if user.PasswordHash != previousHash {
+		if err := s.rememberPassword(ctx, user.ID, previousHash); err != nil {
+			return nil, err
+		}
+	}
+
 	go s.sendEvent("update", user)
 
 	return &user, nil
 }
 
+// UpdateProfile applies the changes of the profile sent by the user, the password can't be changed through it.
+func (s Service) UpdateProfile(
+	ctx context.Context, id uuid.UUID, payload schemas.UpdateProfilePayload,
+) (*entity.User, error) {
+	return s.Update(ctx, id, schemas.UpdateUserPayload{Name: payload.Name, Phone: payload.Phone, Email: payload.Email})
+}
+
+// Save persists the changes made through the entity methods, like the second factor settings. They aren't
+// profile changes, so no event is sent.
+func (s Service) Save(ctx context.Context, user entity.User) error {
+	ctx, span := trace.NewSpan(ctx, "user.save")
+	defer span.End()
+
+	return s.repository.Update(ctx, user)
+}
+
+// SaveRecoveryCodes saves the recovery codes of the user only when the stored ones are still the previous codes, it
+// reports whether they were saved.
+func (s Service) SaveRecoveryCodes(ctx context.Context, user entity.User, previous entity.StringList) (bool, error) {
+	ctx, span := trace.NewSpan(ctx, "user.save-recovery-codes")
+	defer span.End()
+
+	return s.repository.UpdateRecoveryCodes(ctx, user, previous)
+}
+
 func (s Service) Delete(ctx context.Context, id uuid.UUID) error {
 	ctx, span := trace.NewSpan(ctx, "user.delete")
 	defer span.End()
@@ -86,6 +137,31 @@ func (s Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+// recentPasswords are the previous passwords of the history, the current one is checked through the user.
+func (s Service) recentPasswords(
+	ctx context.Context, userID uuid.UUID, password string,
+) ([]entity.PasswordHistory, error) {
+	if password == "" || s.passwordPolicy.HistorySize <= 1 {
+		return nil, nil
+	}
+
+	return s.passwordHistory.ListByUser(ctx, userID, s.passwordPolicy.HistorySize-1)
+}
+
+// rememberPassword moves the replaced password to the history, keeping only the entries it may still block.
+func (s Service) rememberPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	if s.passwordPolicy.HistorySize <= 1 {
+		return nil
+	}
+
+	err := s.passwordHistory.Create(ctx, entity.NewPasswordHistory(userID, passwordHash))
+	if err != nil {
+		return err
+	}
+
+	return s.passwordHistory.Prune(ctx, userID, s.passwordPolicy.HistorySize-1)
+}
+
 func (s Service) sendEvent(action string, data interface{}) {
 	if body, err := json.Marshal(data); err == nil {
 		s.eventChannel <- schemas.Event{Service: "user", Action: action, Data: body}
@@ -12,6 +12,7 @@ import (
 	"github.com/uesleicarvalhoo/go-auth-service/internal/infra/repository"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
 	"github.com/uesleicarvalhoo/go-auth-service/internal/services/user"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
 	"github.com/uesleicarvalhoo/go-auth-service/pkg/database"
 )
 
@@ -21,9 +22,14 @@ type Sut struct {
 	eventChannel chan schemas.Event
 }
 
-func newSut() Sut {
+func newSut(options ...func(policy *auth.PasswordPolicy)) Sut {
 	eventChannel := make(chan schemas.Event)
 
+	passwordPolicy := auth.DefaultPasswordPolicy()
+	for _, option := range options {
+		option(&passwordPolicy)
+	}
+
 	db, err := database.NewSQLiteMemoryConnection()
 	if err != nil {
 		panic(err)
@@ -39,7 +45,9 @@ func newSut() Sut {
 	return Sut{
 		repository:   userRepository,
 		eventChannel: eventChannel,
-		service:      user.NewService(userRepository, eventChannel),
+		service: user.NewService(
+			userRepository, repository.NewPasswordHistoryRepository(db), passwordPolicy, eventChannel,
+		),
 	}
 }
 
@@ -178,6 +186,69 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateProfile(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+
+	user, err := entity.NewUser(
+		"Maria Silva", gofakeit.Email(), "5521987654321", "current-password", auth.DefaultPasswordPolicy(),
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, sut.repository.Create(context.TODO(), user))
+
+	// Action
+	updatedUser, err := sut.service.UpdateProfile(context.TODO(), user.ID, schemas.UpdateProfilePayload{Name: "Ana"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, strings.ToTitle("Ana"), updatedUser.Name)
+	assert.Equal(t, user.Email, updatedUser.Email, "the empty fields are kept")
+	assert.Equal(t, user.Phone, updatedUser.Phone, "the empty fields are kept")
+	assert.True(t, updatedUser.ValidatePassword("current-password"), "the password isn't changed")
+}
+
+func TestSaveRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut := newSut()
+
+	user, err := entity.NewUser(
+		"Maria Silva", gofakeit.Email(), "5521987654321", "current-password", auth.DefaultPasswordPolicy(),
+	)
+	assert.NoError(t, err)
+
+	codes, err := user.GenerateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.NoError(t, sut.repository.Create(context.TODO(), user))
+
+	first, err := sut.service.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+
+	second, err := sut.service.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+
+	previous := append(entity.StringList(nil), first.RecoveryCodes...)
+	assert.True(t, first.ConsumeRecoveryCode(codes[0]))
+	assert.True(t, second.ConsumeRecoveryCode(codes[0]))
+
+	// Action
+	firstSaved, firstErr := sut.service.SaveRecoveryCodes(context.TODO(), first, previous)
+	secondSaved, secondErr := sut.service.SaveRecoveryCodes(context.TODO(), second, previous)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.True(t, firstSaved)
+	assert.NoError(t, secondErr)
+	assert.False(t, secondSaved, "the codes changed since the user was loaded")
+
+	stored, err := sut.service.Get(context.TODO(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, first.RecoveryCodes, stored.RecoveryCodes)
+}
+
 func TestGet(t *testing.T) {
 	t.Parallel()
 
@@ -352,3 +423,66 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdatePasswordHistory(t *testing.T) {
+	t.Parallel()
+
+	passwords := []string{"first-password", "second-password", "third-password", "fourth-password"}
+
+	tests := []struct {
+		scenario      string
+		password      string
+		expectedError string
+	}{
+		{
+			scenario:      "when password is the current one",
+			password:      passwords[3],
+			expectedError: "password: password must be different from the last 3 passwords",
+		},
+		{
+			scenario:      "when password is in the history",
+			password:      passwords[1],
+			expectedError: "password: password must be different from the last 3 passwords",
+		},
+		{
+			scenario: "when password left the history",
+			password: passwords[0],
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut := newSut(func(policy *auth.PasswordPolicy) {
+				policy.HistorySize = 3
+			})
+
+			user, err := entity.NewUser(gofakeit.Name(), gofakeit.Email(), gofakeit.Phone(), passwords[0], auth.PasswordPolicy{})
+			assert.NoError(t, err)
+			assert.NoError(t, sut.repository.Create(context.TODO(), user))
+
+			for _, password := range passwords[1:] {
+				_, err := sut.service.Update(context.TODO(), user.ID, schemas.UpdateUserPayload{Password: password})
+				assert.NoError(t, err)
+				<-sut.eventChannel
+			}
+
+			// Action
+			_, err = sut.service.Update(context.TODO(), user.ID, schemas.UpdateUserPayload{Password: tc.password})
+
+			// Assert
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			<-sut.eventChannel
+		})
+	}
+}
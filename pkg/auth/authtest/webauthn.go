@@ -0,0 +1,139 @@
+// Package authtest provides helpers to test the authentication flows without real devices.
+package authtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+)
+
+const (
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+
+	credentialIDSize = 16
+	coordinateSize   = 32
+)
+
+// Authenticator emulates a WebAuthn authenticator holding a single ES256 credential.
+type Authenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	SignCount    uint32
+	UserVerified bool
+
+	key *ecdsa.PrivateKey
+}
+
+func NewAuthenticator(rpID, origin string) (*Authenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := make([]byte, credentialIDSize)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: credentialID,
+		UserVerified: true,
+		key:          key,
+	}, nil
+}
+
+// Register returns the client data and the attestation object of navigator.credentials.create.
+func (a *Authenticator) Register(challenge string) (clientDataJSON, attestationObject []byte) {
+	clientDataJSON = a.clientData("webauthn.create", challenge)
+
+	authData := a.authenticatorData(flagAttestedCredential)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = append(authData, byte(len(a.CredentialID)>>8), byte(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, a.COSEKey()...)
+
+	attestationObject = []byte{0xa3}
+	attestationObject = appendText(attestationObject, "fmt")
+	attestationObject = appendText(attestationObject, "none")
+	attestationObject = appendText(attestationObject, "attStmt")
+	attestationObject = append(attestationObject, 0xa0)
+	attestationObject = appendText(attestationObject, "authData")
+	attestationObject = appendBytes(attestationObject, authData)
+
+	return clientDataJSON, attestationObject
+}
+
+// Assert returns the client data, the authenticator data and the signature of navigator.credentials.get,
+// the signature counter is incremented on every assertion.
+func (a *Authenticator) Assert(challenge string) (clientDataJSON, authenticatorData, signature []byte) {
+	a.SignCount++
+
+	clientDataJSON = a.clientData("webauthn.get", challenge)
+	authenticatorData = a.authenticatorData(0)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return clientDataJSON, authenticatorData, signature
+}
+
+// COSEKey is the public key of the credential encoded as a COSE EC2 key.
+func (a *Authenticator) COSEKey() []byte {
+	key := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21}
+	key = appendBytes(key, a.key.X.FillBytes(make([]byte, coordinateSize)))
+	key = append(key, 0x22)
+
+	return appendBytes(key, a.key.Y.FillBytes(make([]byte, coordinateSize)))
+}
+
+func (a *Authenticator) clientData(ceremonyType, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{"type": ceremonyType, "challenge": challenge, "origin": a.Origin})
+
+	return data
+}
+
+func (a *Authenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	data := append(rpIDHash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[len(data)-4:], a.SignCount)
+
+	return data
+}
+
+func appendText(data []byte, value string) []byte {
+	return append(appendHeader(data, 0x60, len(value)), value...)
+}
+
+func appendBytes(data, value []byte) []byte {
+	return append(appendHeader(data, 0x40, len(value)), value...)
+}
+
+func appendHeader(data []byte, major byte, size int) []byte {
+	switch {
+	case size < 24:
+		return append(data, major|byte(size))
+	case size <= 0xff:
+		return append(data, major|24, byte(size))
+	default:
+		return append(data, major|25, byte(size>>8), byte(size))
+	}
+}
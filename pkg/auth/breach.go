@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // sha1 is the format of the breach corpus, it doesn't protect anything.
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidPasswordCorpus = errors.New("invalid password corpus")
+
+// PasswordCorpus tells if a password is known by attackers, like the passwords of data breaches. Lookups are
+// local, so it works without network access.
+type PasswordCorpus interface {
+	Contains(password string) bool
+}
+
+// PasswordCorpora contains a password when any of the corpora does.
+type PasswordCorpora []PasswordCorpus
+
+func (c PasswordCorpora) Contains(password string) bool {
+	for _, corpus := range c {
+		if corpus.Contains(password) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SHA1Corpus is the list of SHA-1 hashes of the pwned passwords, in the format of the k-anonymity range files
+// joined with their prefixes, one "HASH:COUNT" per line. The hashes are kept sorted for binary search.
+type SHA1Corpus struct {
+	hashes [][sha1.Size]byte
+}
+
+// LoadSHA1Corpus reads the hashes of the file, the counts are optional and ignored.
+func LoadSHA1Corpus(path string) (*SHA1Corpus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadSHA1Corpus(file)
+}
+
+func ReadSHA1Corpus(r io.Reader) (*SHA1Corpus, error) {
+	corpus := &SHA1Corpus{}
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if separator := strings.IndexByte(line, ':'); separator >= 0 {
+			line = line[:separator]
+		}
+
+		var hash [sha1.Size]byte
+		if n, err := hex.Decode(hash[:], []byte(line)); err != nil || n != sha1.Size {
+			return nil, ErrInvalidPasswordCorpus
+		}
+
+		corpus.hashes = append(corpus.hashes, hash)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(corpus.hashes, func(i, j int) bool {
+		return bytes.Compare(corpus.hashes[i][:], corpus.hashes[j][:]) < 0
+	})
+
+	return corpus, nil
+}
+
+func (c *SHA1Corpus) Contains(password string) bool {
+	hash := sha1.Sum([]byte(password)) //nolint:gosec // see the import
+
+	i := sort.Search(len(c.hashes), func(i int) bool {
+		return bytes.Compare(c.hashes[i][:], hash[:]) >= 0
+	})
+
+	return i < len(c.hashes) && c.hashes[i] == hash
+}
+
+// BloomFilter is a compact set of passwords, it may contain passwords that were never added with the false
+// positive rate it was built for, but never misses the added ones.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// NewBloomFilter sizes the filter for the expected number of passwords and false positive rate.
+func NewBloomFilter(expected int, falsePositiveRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = 1
+	}
+
+	size := math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	hashes := math.Max(1, math.Round(size/float64(expected)*math.Ln2))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (uint64(size)+63)/64),
+		size:   uint64(size),
+		hashes: uint64(hashes),
+	}
+}
+
+// LoadPasswordList builds the filter from a file with one password per line, like the lists of common
+// passwords. The file is read twice, to size the filter before adding the passwords.
+func LoadPasswordList(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	count := 0
+	if err := scanPasswords(file, func(string) { count++ }); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	filter := NewBloomFilter(count, falsePositiveRate)
+	if err := scanPasswords(file, filter.Add); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func scanPasswords(r io.Reader, f func(password string)) error {
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if password := strings.TrimRight(scanner.Text(), "\r"); password != "" {
+			f(password)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (f *BloomFilter) Add(password string) {
+	h1, h2 := bloomHashes(password)
+
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *BloomFilter) Contains(password string) bool {
+	h1, h2 := bloomHashes(password)
+
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.size
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bloomHashes derives the positions of the password with double hashing, from the halves of its sha256.
+func bloomHashes(password string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(password))
+
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
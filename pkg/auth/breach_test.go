@@ -0,0 +1,140 @@
+package auth_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestSHA1Corpus(t *testing.T) {
+	t.Parallel()
+
+	// sha1 of "password" and "123456", in upper and lower case, with and without counts
+	content := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n\n7c4a8d09ca3762af61e59520943dc26494f8941b\n"
+
+	corpus, err := auth.ReadSHA1Corpus(strings.NewReader(content))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario string
+		password string
+		expected bool
+	}{
+		{
+			scenario: "when password hash has a count",
+			password: "password",
+			expected: true,
+		},
+		{
+			scenario: "when password hash is lower case without count",
+			password: "123456",
+			expected: true,
+		},
+		{
+			scenario: "when password is not in the corpus",
+			password: "Tr0ub4dor&3x",
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			contains := corpus.Contains(tc.password)
+
+			// Assert
+			assert.Equal(t, tc.expected, contains)
+		})
+	}
+}
+
+func TestReadSHA1CorpusInvalidLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		content  string
+	}{
+		{
+			scenario: "when line is not hex",
+			content:  "password:10\n",
+		},
+		{
+			scenario: "when hash is too short",
+			content:  "5BAA61E4C9B93F3F0682250B6CF8331B:10\n",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			_, err := auth.ReadSHA1Corpus(strings.NewReader(tc.content))
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrInvalidPasswordCorpus)
+		})
+	}
+}
+
+func TestLoadPasswordList(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	passwords := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		passwords = append(passwords, fmt.Sprintf("common-%d", i))
+	}
+
+	path := filepath.Join(t.TempDir(), "common.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(passwords, "\n")), 0o600))
+
+	// Action
+	filter, err := auth.LoadPasswordList(path, 0.001)
+
+	// Assert
+	assert.NoError(t, err)
+
+	for _, password := range passwords {
+		assert.True(t, filter.Contains(password), password)
+	}
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if filter.Contains(fmt.Sprintf("uncommon-%d", i)) {
+			falsePositives++
+		}
+	}
+
+	assert.LessOrEqual(t, falsePositives, 10)
+}
+
+func TestPasswordCorpora(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	common := auth.NewBloomFilter(1, 0.001)
+	common.Add("qwerty")
+
+	breached, err := auth.ReadSHA1Corpus(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n"))
+	assert.NoError(t, err)
+
+	corpora := auth.PasswordCorpora{common, breached}
+
+	// Assert
+	assert.True(t, corpora.Contains("qwerty"))
+	assert.True(t, corpora.Contains("password"))
+	assert.False(t, corpora.Contains("Tr0ub4dor&3x"))
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// WebAuthn encodes the attestation and the credential keys with CBOR, only the subset used by the
+// authenticators is decoded: integers, byte and text strings, arrays, maps and simple values.
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const cborMaxDepth = 16
+
+var ErrInvalidCBOR = errors.New("invalid cbor data")
+
+// decodeCBOR decodes the first item of the data, it returns the bytes that follow it.
+// Integers are decoded as int64, maps as map[interface{}]interface{} and simple values as bool or nil.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if len(data) == 0 || depth > cborMaxDepth {
+		return nil, nil, ErrInvalidCBOR
+	}
+
+	major, info := data[0]>>5, data[0]&0x1f
+
+	if major == cborSimple {
+		return decodeCBORSimple(info, data[1:])
+	}
+
+	value, rest, err := decodeCBORArgument(info, data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if value > 1<<63-1 {
+			return nil, nil, ErrInvalidCBOR
+		}
+
+		return int64(value), rest, nil
+
+	case cborNegative:
+		if value > 1<<63-1 {
+			return nil, nil, ErrInvalidCBOR
+		}
+
+		return -1 - int64(value), rest, nil
+
+	case cborBytes, cborText:
+		if value > uint64(len(rest)) {
+			return nil, nil, ErrInvalidCBOR
+		}
+
+		if major == cborText {
+			return string(rest[:value]), rest[value:], nil
+		}
+
+		return rest[:value], rest[value:], nil
+
+	case cborArray:
+		return decodeCBORArray(value, rest, depth)
+
+	case cborMap:
+		return decodeCBORMap(value, rest, depth)
+
+	default:
+		// Tags aren't used by WebAuthn, the tagged item is decoded as is.
+		return decodeCBORItem(rest, depth+1)
+	}
+}
+
+// decodeCBORArgument reads the length or value that follows the initial byte, indefinite lengths aren't
+// allowed on the CTAP2 canonical encoding.
+func decodeCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	default:
+		return 0, nil, ErrInvalidCBOR
+	}
+}
+
+func decodeCBORSimple(info byte, data []byte) (interface{}, []byte, error) {
+	switch info {
+	case 20:
+		return false, data, nil
+	case 21:
+		return true, data, nil
+	case 22, 23:
+		return nil, data, nil
+	default:
+		return nil, nil, ErrInvalidCBOR
+	}
+}
+
+func decodeCBORArray(size uint64, data []byte, depth int) (interface{}, []byte, error) {
+	if size > uint64(len(data)) {
+		return nil, nil, ErrInvalidCBOR
+	}
+
+	items := make([]interface{}, 0, size)
+
+	for i := uint64(0); i < size; i++ {
+		item, rest, err := decodeCBORItem(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, item)
+		data = rest
+	}
+
+	return items, data, nil
+}
+
+func decodeCBORMap(size uint64, data []byte, depth int) (interface{}, []byte, error) {
+	if size > uint64(len(data)) {
+		return nil, nil, ErrInvalidCBOR
+	}
+
+	items := make(map[interface{}]interface{}, size)
+
+	for i := uint64(0); i < size; i++ {
+		key, rest, err := decodeCBORItem(data, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch key.(type) {
+		case int64, string:
+		default:
+			return nil, nil, ErrInvalidCBOR
+		}
+
+		value, rest, err := decodeCBORItem(rest, depth+1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items[key] = value
+		data = rest
+	}
+
+	return items, data, nil
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// GenerateNumericCode returns a random code with the given number of digits, leading zeros are kept so every
+// code has the same length, as expected by the users typing it from an SMS.
+func GenerateNumericCode(digits int) (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*s", digits, n.String()), nil
+}
@@ -0,0 +1,44 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestGenerateNumericCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		digits   int
+		expected string
+	}{
+		{
+			scenario: "when code has 6 digits",
+			digits:   6,
+			expected: "^[0-9]{6}$",
+		},
+		{
+			scenario: "when code has 8 digits",
+			digits:   8,
+			expected: "^[0-9]{8}$",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			code, err := auth.GenerateNumericCode(tc.digits)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Regexp(t, tc.expected, code)
+		})
+	}
+}
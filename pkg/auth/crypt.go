@@ -1,25 +1,44 @@
 package auth
 
 import (
-	"fmt"
-
-	"golang.org/x/crypto/bcrypt"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 )
 
-const MinPasswordLength = 5
-
+// GeneratePasswordHash hashes the password with the configured hasher, the requirements of the password are
+// checked by the PasswordPolicy.
 func GeneratePasswordHash(password string) (string, error) {
-	if len(password) < MinPasswordLength {
-		return "", fmt.Errorf("password must be %d or more caracters", MinPasswordLength)
-	}
+	return passwordHasher.Hash(password)
+}
 
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// CheckPasswordHash verifies the password with the algorithm of the hash, so the hashes made before a change of
+// the hasher and the legacy hashes of the imported users keep working until they are rehashed on the login.
+func CheckPasswordHash(plain, hash string) bool {
+	return verifyPasswordHash(plain, hash)
+}
 
-	return string(bytes), err
+// GenerateSecret returns a random url safe string with size bytes of entropy.
+func GenerateSecret(size int) (string, error) {
+	secret := make([]byte, size)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
 }
 
-func CheckPasswordHash(plain, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain))
+// HashSecret returns the SHA-256 of a random secret, like the recovery codes and the client secrets. They have too
+// much entropy to be guessed, so the slow password hash isn't needed.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
 
-	return err == nil
+// CheckSecretHash compares the hash of the secret in constant time.
+func CheckSecretHash(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(hash)) == 1
 }
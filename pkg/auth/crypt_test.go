@@ -0,0 +1,65 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestGeneratePasswordHash(t *testing.T) {
+	t.Parallel()
+
+	// Action
+	hash, err := auth.GeneratePasswordHash("abcd")
+
+	// Assert
+	assert.NoError(t, err, "the length is a rule of the password policy")
+	assert.True(t, auth.CheckPasswordHash("abcd", hash))
+}
+
+func TestCheckSecretHash(t *testing.T) {
+	t.Parallel()
+
+	hash := auth.HashSecret("ab12cd34")
+
+	tests := []struct {
+		scenario string
+		secret   string
+		hash     string
+		expected bool
+	}{
+		{
+			scenario: "when the secret matches",
+			secret:   "ab12cd34",
+			hash:     hash,
+			expected: true,
+		},
+		{
+			scenario: "when the secret is different",
+			secret:   "ab12cd35",
+			hash:     hash,
+			expected: false,
+		},
+		{
+			scenario: "when the hash is empty",
+			secret:   "ab12cd34",
+			hash:     "",
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			valid := auth.CheckSecretHash(tc.secret, tc.hash)
+
+			// Assert
+			assert.Equal(t, tc.expected, valid)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithms of the password hashes, they are detected from the format of the stored hash.
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+var (
+	ErrUnknownPasswordHash = errors.New("unknown password hash algorithm")
+	ErrInvalidPasswordHash = errors.New("invalid password hash")
+)
+
+// PasswordHasher hashes the passwords with one algorithm, the hashes of the other algorithms are verified by
+// the hasher of their format.
+type PasswordHasher interface {
+	Algorithm() string
+	Hash(password string) (string, error)
+	Verify(password, hash string) bool
+	// NeedsRehash tells if the hash was made by other algorithm or with other parameters
+	NeedsRehash(hash string) bool
+}
+
+// Argon2Params are the costs of argon2id, the memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Limits of the argon2 costs, it needs 8 KiB of memory for each thread and the hashes above the limits would stop
+// the server on a single verification.
+const (
+	Argon2MemoryPerThread = 8
+	Argon2MaxMemory       = 4 * 1024 * 1024
+	Argon2MaxIterations   = 1024
+)
+
+// DefaultArgon2Params are the minimum parameters recommended by OWASP, 19 MiB of memory and 2 iterations.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+// Argon2idHasher encodes the hashes in the PHC string format, like "$argon2id$v=19$m=19456,t=2,p=1$salt$key".
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+func NewArgon2idHasher(params Argon2Params) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+func (h *Argon2idHasher) Algorithm() string {
+	return PasswordHashArgon2id
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism,
+		h.params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.params.Memory,
+		h.params.Iterations, h.params.Parallelism, base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, hash string) bool {
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return false
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism,
+		params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, other) == 1
+}
+
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory || params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism || params.KeyLength != h.params.KeyLength
+}
+
+func decodeArgon2idHash(hash string) (Argon2Params, []byte, []byte, error) {
+	var (
+		params  Argon2Params
+		version int
+	)
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	if !validArgon2Costs(params) {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+func validArgon2Costs(params Argon2Params) bool {
+	return params.Parallelism > 0 && params.Iterations > 0 && params.Iterations <= Argon2MaxIterations &&
+		params.Memory >= Argon2MemoryPerThread*uint32(params.Parallelism) && params.Memory <= Argon2MaxMemory
+}
+
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Algorithm() string {
+	return PasswordHashBcrypt
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+
+	return string(bytes), err
+}
+
+func (h *BcryptHasher) Verify(password, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+
+	return err != nil || cost != h.cost
+}
+
+// PasswordHashAlgorithm detects the algorithm from the prefix of the hash, including the legacy ones.
+func PasswordHashAlgorithm(hash string) (string, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return PasswordHashArgon2id, nil
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return PasswordHashBcrypt, nil
+	case strings.HasPrefix(hash, PasswordHashPBKDF2SHA256+"$"):
+		return PasswordHashPBKDF2SHA256, nil
+	case strings.HasPrefix(hash, PasswordHashScrypt+"$"):
+		return PasswordHashScrypt, nil
+	case strings.HasPrefix(hash, "$6$"):
+		return PasswordHashSHA512Crypt, nil
+	case strings.HasPrefix(hash, PasswordHashSaltedMD5+"$"):
+		return PasswordHashSaltedMD5, nil
+	default:
+		return "", ErrUnknownPasswordHash
+	}
+}
+
+// passwordHasher hashes the new passwords, it's replaced on the startup by the configured one.
+var passwordHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2Params())
+
+// SetPasswordHasher replaces the hasher of the new passwords, it must be called before the hashes are generated.
+func SetPasswordHasher(hasher PasswordHasher) {
+	passwordHasher = hasher
+}
+
+// PasswordNeedsRehash tells if the hash is outdated for the current hasher, so it should be replaced by a new
+// hash of the password once it's known, like on the login.
+func PasswordNeedsRehash(hash string) bool {
+	return passwordHasher.NeedsRehash(hash)
+}
+
+// verifyPasswordHash checks the password with the algorithm of the hash, the parameters are read from the hash
+// itself.
+func verifyPasswordHash(password, hash string) bool {
+	algorithm, err := PasswordHashAlgorithm(hash)
+	if err != nil {
+		return false
+	}
+
+	switch algorithm {
+	case PasswordHashArgon2id:
+		return NewArgon2idHasher(DefaultArgon2Params()).Verify(password, hash)
+	case PasswordHashBcrypt:
+		return NewBcryptHasher(bcrypt.DefaultCost).Verify(password, hash)
+	default:
+		return verifyLegacyPasswordHash(algorithm, password, hash)
+	}
+}
@@ -0,0 +1,188 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario          string
+		hasher            auth.PasswordHasher
+		expectedAlgorithm string
+	}{
+		{
+			scenario:          "when hasher is argon2id",
+			hasher:            auth.NewArgon2idHasher(auth.DefaultArgon2Params()),
+			expectedAlgorithm: auth.PasswordHashArgon2id,
+		},
+		{
+			scenario:          "when hasher is bcrypt",
+			hasher:            auth.NewBcryptHasher(bcrypt.MinCost),
+			expectedAlgorithm: auth.PasswordHashBcrypt,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			hash, err := tc.hasher.Hash("my-password")
+
+			// Assert
+			assert.NoError(t, err)
+
+			algorithm, err := auth.PasswordHashAlgorithm(hash)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedAlgorithm, algorithm)
+
+			assert.True(t, tc.hasher.Verify("my-password", hash))
+			assert.False(t, tc.hasher.Verify("other-password", hash))
+			assert.False(t, tc.hasher.NeedsRehash(hash))
+
+			// The hashes of any algorithm are verified from their format
+			assert.True(t, auth.CheckPasswordHash("my-password", hash))
+			assert.False(t, auth.CheckPasswordHash("other-password", hash))
+		})
+	}
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	t.Parallel()
+
+	outdatedParams := auth.DefaultArgon2Params()
+	outdatedParams.Iterations = 1
+
+	argon2Hash, err := auth.NewArgon2idHasher(outdatedParams).Hash("my-password")
+	assert.NoError(t, err)
+
+	bcryptHash, err := auth.NewBcryptHasher(bcrypt.MinCost).Hash("my-password")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario string
+		hasher   auth.PasswordHasher
+		hash     string
+		expected bool
+	}{
+		{
+			scenario: "when argon2id params changed",
+			hasher:   auth.NewArgon2idHasher(auth.DefaultArgon2Params()),
+			hash:     argon2Hash,
+			expected: true,
+		},
+		{
+			scenario: "when argon2id params are the same",
+			hasher:   auth.NewArgon2idHasher(outdatedParams),
+			hash:     argon2Hash,
+			expected: false,
+		},
+		{
+			scenario: "when hash is other algorithm",
+			hasher:   auth.NewArgon2idHasher(outdatedParams),
+			hash:     bcryptHash,
+			expected: true,
+		},
+		{
+			scenario: "when bcrypt cost changed",
+			hasher:   auth.NewBcryptHasher(bcrypt.DefaultCost),
+			hash:     bcryptHash,
+			expected: true,
+		},
+		{
+			scenario: "when bcrypt cost is the same",
+			hasher:   auth.NewBcryptHasher(bcrypt.MinCost),
+			hash:     bcryptHash,
+			expected: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			needsRehash := tc.hasher.NeedsRehash(tc.hash)
+
+			// Assert
+			assert.Equal(t, tc.expected, needsRehash)
+		})
+	}
+}
+
+func TestPasswordHashAlgorithmUnknown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		hash     string
+	}{
+		{
+			scenario: "when hash is empty",
+			hash:     "",
+		},
+		{
+			scenario: "when hash is plain text",
+			hash:     "my-password",
+		},
+		{
+			scenario: "when hash is argon2i",
+			hash:     "$argon2i$v=19$m=19456,t=2,p=1$c2FsdA$a2V5",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			_, err := auth.PasswordHashAlgorithm(tc.hash)
+
+			// Assert
+			assert.ErrorIs(t, err, auth.ErrUnknownPasswordHash)
+			assert.False(t, auth.CheckPasswordHash(tc.hash, tc.hash))
+		})
+	}
+}
+
+func TestArgon2idHasherInvalidHash(t *testing.T) {
+	t.Parallel()
+
+	hasher := auth.NewArgon2idHasher(auth.DefaultArgon2Params())
+
+	hash, err := hasher.Hash("my-password")
+	assert.NoError(t, err)
+
+	parts := strings.Split(hash, "$")
+	invalidHashes := []string{
+		strings.Join(append([]string{}, parts[:5]...), "$"),
+		strings.Replace(hash, "v=19", "v=16", 1),
+		strings.Replace(hash, "m=", "x=", 1),
+		strings.Join(append(append([]string{}, parts[:5]...), "!invalid!"), "$"),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=19456,t=0,p=1", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=19456,t=2,p=0", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=0,t=2,p=1", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=31,t=2,p=4", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=4194305,t=2,p=1", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=19456,t=1025,p=1", 1),
+		strings.Replace(hash, "m=19456,t=2,p=1", "m=19456,t=4294967295,p=1", 1),
+	}
+
+	for _, invalidHash := range invalidHashes {
+		assert.False(t, hasher.Verify("my-password", invalidHash), invalidHash)
+		assert.True(t, hasher.NeedsRehash(invalidHash), invalidHash)
+	}
+}
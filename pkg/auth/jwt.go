@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,40 +10,118 @@ import (
 	"github.com/uesleicarvalhoo/go-auth-service/internal/schemas"
 )
 
-func GenerateJwtToken(secret string, userID uuid.UUID, expiration time.Duration) (schemas.JwtToken, error) {
-	exp := time.Now().Add(expiration).Unix()
-	claims := jwt.NewWithClaims(
-		jwt.SigningMethodHS256, jwt.StandardClaims{
-			Subject:   userID.String(),
-			ExpiresAt: exp,
-		})
+// Claims of the tokens issued by the service, token_use tells what the token is for, so a token can't be used in
+// place of another kind.
+type Claims struct {
+	ID        string `json:"jti,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Email     string `json:"email,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	TokenUse  string `json:"token_use,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+}
+
+func (c Claims) Valid() error {
+	return jwt.StandardClaims{ExpiresAt: c.ExpiresAt}.Valid()
+}
+
+// IDTokenClaims are the claims of the OpenID Connect id token, the audience is the client id.
+type IDTokenClaims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Nonce     string `json:"nonce,omitempty"`
+	schemas.UserInfo
+}
+
+func (c IDTokenClaims) Valid() error {
+	return jwt.StandardClaims{ExpiresAt: c.ExpiresAt}.Valid()
+}
 
-	token, err := claims.SignedString([]byte(secret))
+// GenerateJwtToken signs the claims, the issue and expiration times are always set here and the token id
+// when the caller doesn't need to know it beforehand.
+func GenerateJwtToken(key SigningKey, claims Claims, expiration time.Duration) (schemas.JwtToken, error) {
+	now := time.Now()
+
+	if claims.ID == "" {
+		claims.ID = uuid.NewString()
+	}
+
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(expiration).Unix()
+
+	token, err := sign(key, claims)
 	if err != nil {
-		return schemas.JwtToken{}, nil
+		return schemas.JwtToken{}, err
+	}
+
+	return schemas.JwtToken{Token: token, ExpiresAt: claims.ExpiresAt}, nil
+}
+
+// ErrSymmetricIDToken is returned for symmetric keys, they aren't published in the JWKS so the clients couldn't
+// verify the id tokens.
+var ErrSymmetricIDToken = errors.New("id tokens must be signed with an asymmetric key")
+
+// GenerateIDToken signs the OpenID Connect id token, the issue and expiration times are set here.
+func GenerateIDToken(key SigningKey, claims IDTokenClaims, expiration time.Duration) (string, error) {
+	if key.IsSymmetric() {
+		return "", ErrSymmetricIDToken
 	}
 
-	return schemas.JwtToken{Token: token, ExpiresAt: exp}, err
+	now := time.Now()
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(expiration).Unix()
+
+	return sign(key, claims)
+}
+
+func sign(key SigningKey, claims jwt.Claims) (string, error) {
+	jwtToken := jwt.NewWithClaims(key.method(), claims)
+	jwtToken.Header["kid"] = key.ID
+
+	return jwtToken.SignedString(key.signingKey())
 }
 
-func ValidateJwtToken(token, secret string) (uuid.UUID, error) {
-	tokenObj, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// ValidateJwtToken verifies the token with the key referenced by its kid header.
+func ValidateJwtToken(token string, keys KeySet) (Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrKeyIDMissing
+		}
+
+		key, err := keys.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %s", token.Header["alg"])
 		}
 
-		return []byte(secret), nil
+		return key.verificationKey(), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, err
+		return Claims{}, unwrapValidationError(err)
 	}
 
-	claims, ok := tokenObj.Claims.(jwt.MapClaims)
-	if ok && tokenObj.Valid {
-		sub, _ := claims["sub"].(string)
+	return claims, nil
+}
 
-		return uuid.Parse(sub)
+// unwrapValidationError exposes the errors returned by the key lookup, jwt.ValidationError doesn't unwrap them.
+func unwrapValidationError(err error) error {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Inner != nil {
+		return validationErr.Inner
 	}
 
-	return uuid.UUID{}, nil
+	return err
 }
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const (
+	ecP256CoordinateSize = 32
+	hmacSecretSize       = 32
+	rsaKeySize           = 2048
+)
+
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	ActivatesAt time.Time
+	RetiresAt   time.Time
+	secret      []byte
+	private     crypto.PrivateKey
+	public      crypto.PublicKey
+}
+
+// NewHMACKey creates a symmetric HS256 key, when id is empty a random one is used.
+func NewHMACKey(id, secret string) SigningKey {
+	key := SigningKey{ID: id, Algorithm: AlgorithmHS256, secret: []byte(secret)}
+	if key.ID == "" {
+		key.ID = key.defaultID()
+	}
+
+	return key
+}
+
+// GenerateSigningKey creates a new random key for the algorithm, identified like on ParseSigningKey.
+func GenerateSigningKey(algorithm string) (SigningKey, error) {
+	var private any
+
+	var err error
+
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, hmacSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return SigningKey{}, err
+		}
+
+		return NewHMACKey("", encodeSegment(secret)), nil
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	return ParseSigningKey("", algorithm, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// ParseSigningKey loads the key material, a raw secret for HS256 or a PEM encoded private key otherwise.
+// When id is empty the asymmetric keys are identified by their RFC 7638 thumbprint and the symmetric ones by
+// a random id.
+func ParseSigningKey(id, algorithm string, privateKey []byte) (SigningKey, error) {
+	key := SigningKey{ID: id, Algorithm: algorithm}
+
+	switch algorithm {
+	case AlgorithmHS256:
+		key.secret = privateKey
+	case AlgorithmRS256:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		key.private, key.public = private, &private.PublicKey
+	case AlgorithmES256:
+		private, err := jwt.ParseECPrivateKeyFromPEM(privateKey)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		if private.Curve != elliptic.P256() {
+			return SigningKey{}, fmt.Errorf("%s requires a P-256 key", AlgorithmES256)
+		}
+
+		key.private, key.public = private, &private.PublicKey
+	case AlgorithmEdDSA:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(privateKey)
+		if err != nil {
+			return SigningKey{}, err
+		}
+
+		edKey, _ := private.(ed25519.PrivateKey)
+		key.private, key.public = edKey, edKey.Public()
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	if key.ID == "" {
+		key.ID = key.defaultID()
+	}
+
+	return key, nil
+}
+
+// MarshalPrivateKey returns the key material in the format accepted by ParseSigningKey.
+func (k SigningKey) MarshalPrivateKey() ([]byte, error) {
+	if k.IsSymmetric() {
+		return k.secret, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k SigningKey) IsSymmetric() bool {
+	return k.Algorithm == AlgorithmHS256
+}
+
+// IsActive reports if the key can sign new tokens.
+func (k SigningKey) IsActive(now time.Time) bool {
+	return !now.Before(k.ActivatesAt) && !k.IsRetired(now)
+}
+
+// IsRetired reports if tokens signed by the key must be rejected.
+func (k SigningKey) IsRetired(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !now.Before(k.RetiresAt)
+}
+
+func (k SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k SigningKey) signingKey() interface{} {
+	if k.IsSymmetric() {
+		return k.secret
+	}
+
+	return k.private
+}
+
+func (k SigningKey) verificationKey() interface{} {
+	if k.IsSymmetric() {
+		return k.secret
+	}
+
+	return k.public
+}
+
+// PublicJWK returns the public part of the key, symmetric keys are never published.
+func (k SigningKey) PublicJWK() (JWK, bool) {
+	jwk := JWK{KeyID: k.ID, Algorithm: k.Algorithm, Use: "sig"}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeSegment(public.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.KeyType = "EC"
+		jwk.Curve = "P-256"
+		jwk.X = encodeSegment(padLeft(public.X.Bytes(), ecP256CoordinateSize))
+		jwk.Y = encodeSegment(padLeft(public.Y.Bytes(), ecP256CoordinateSize))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeSegment(public)
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// defaultID identifies the keys without a configured id, the thumbprint of a symmetric key is a hash of the
+// secret, so it must not be exposed in the token headers.
+func (k SigningKey) defaultID() string {
+	if k.IsSymmetric() {
+		return uuid.NewString()
+	}
+
+	return k.Thumbprint()
+}
+
+// Thumbprint computes the RFC 7638 JWK thumbprint of the key.
+func (k SigningKey) Thumbprint() string {
+	var members string
+
+	if k.IsSymmetric() {
+		members = fmt.Sprintf(`{"k":"%s","kty":"oct"}`, encodeSegment(k.secret))
+	} else {
+		jwk, _ := k.PublicJWK()
+
+		switch jwk.KeyType {
+		case "RSA":
+			members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+		case "EC":
+			members = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, jwk.Curve, jwk.X, jwk.Y)
+		case "OKP":
+			members = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Curve, jwk.X)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(members))
+
+	return encodeSegment(sum[:])
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func padLeft(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(data):], data)
+
+	return padded
+}
@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func encodePrivateKey(t *testing.T, key any) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestSigningKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		scenario        string
+		algorithm       string
+		privateKey      any
+		expectedKeyType string
+	}{
+		{scenario: "when algorithm is RS256", algorithm: auth.AlgorithmRS256, privateKey: rsaKey, expectedKeyType: "RSA"},
+		{scenario: "when algorithm is ES256", algorithm: auth.AlgorithmES256, privateKey: ecKey, expectedKeyType: "EC"},
+		{scenario: "when algorithm is EdDSA", algorithm: auth.AlgorithmEdDSA, privateKey: edKey, expectedKeyType: "OKP"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			key, err := auth.ParseSigningKey("", tc.algorithm, encodePrivateKey(t, tc.privateKey))
+			assert.NoError(t, err)
+
+			userID := uuid.New()
+
+			// Action
+			token, err := auth.GenerateJwtToken(key, auth.Claims{Subject: userID.String()}, time.Minute)
+			assert.NoError(t, err)
+
+			claims, err := auth.ValidateJwtToken(token.Token, auth.NewKeyRing(key))
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, userID.String(), claims.Subject)
+			assert.Equal(t, key.Thumbprint(), key.ID)
+
+			jwks := auth.NewJWKS(key)
+			assert.Len(t, jwks.Keys, 1)
+			assert.Equal(t, key.ID, jwks.Keys[0].KeyID)
+			assert.Equal(t, tc.algorithm, jwks.Keys[0].Algorithm)
+			assert.Equal(t, tc.expectedKeyType, jwks.Keys[0].KeyType)
+		})
+	}
+}
+
+func TestParseSigningKeyReturnErrorWhenAlgorithmDoesNotMatchKey(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	_, err = auth.ParseSigningKey("", auth.AlgorithmES256, encodePrivateKey(t, rsaKey))
+	assert.Error(t, err)
+}
+
+func TestValidateJwtTokenRejectTokenSignedByAnotherKey(t *testing.T) {
+	t.Parallel()
+
+	signer := auth.NewHMACKey("key-1", "first-secret")
+	verifier := auth.NewHMACKey("key-1", "second-secret")
+
+	token, err := auth.GenerateJwtToken(signer, auth.Claims{Subject: uuid.NewString()}, time.Minute)
+	assert.NoError(t, err)
+
+	_, err = auth.ValidateJwtToken(token.Token, auth.NewKeyRing(verifier))
+	assert.Error(t, err)
+}
+
+func TestNewJWKSDoesNotPublishSymmetricKeys(t *testing.T) {
+	t.Parallel()
+
+	jwks := auth.NewJWKS(auth.NewHMACKey("", "my-secret"))
+	assert.Empty(t, jwks.Keys)
+}
+
+func TestNewHMACKeyDoesNotUseThumbprintAsKeyID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario   string
+		id         string
+		expectedID string
+	}{
+		{
+			scenario:   "when id is configured",
+			id:         "my-key",
+			expectedID: "my-key",
+		},
+		{
+			scenario: "when id is empty",
+			id:       "",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			key := auth.NewHMACKey(tc.id, "my-secret")
+
+			// Assert
+			assert.NotEmpty(t, key.ID)
+			assert.NotEqual(t, key.Thumbprint(), key.ID)
+
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, key.ID)
+			} else {
+				assert.NotEqual(t, key.ID, auth.NewHMACKey(tc.id, "my-secret").ID)
+			}
+		})
+	}
+}
+
+func TestGenerateIDTokenRejectSymmetricKeys(t *testing.T) {
+	t.Parallel()
+
+	_, err := auth.GenerateIDToken(auth.NewHMACKey("", "my-secret"), auth.IDTokenClaims{}, time.Minute)
+	assert.ErrorIs(t, err, auth.ErrSymmetricIDToken)
+}
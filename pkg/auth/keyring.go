@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownKey   = errors.New("unknown signing key")
+	ErrRetiredKey   = errors.New("signing key is retired")
+	ErrNoActiveKey  = errors.New("there is no active signing key")
+	ErrKeyIDMissing = errors.New("token has no key id")
+)
+
+type KeySet interface {
+	Lookup(kid string) (SigningKey, error)
+}
+
+// KeyRing holds every key that can verify tokens, only the newest active key signs new ones.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []SigningKey
+}
+
+func NewKeyRing(keys ...SigningKey) *KeyRing {
+	ring := &KeyRing{}
+	ring.Replace(keys...)
+
+	return ring
+}
+
+// Replace swaps the keys of the ring, it's safe to call while the ring is in use.
+func (kr *KeyRing) Replace(keys ...SigningKey) {
+	sorted := make([]SigningKey, len(keys))
+	copy(sorted, keys)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivatesAt.After(sorted[j].ActivatesAt)
+	})
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	kr.keys = sorted
+}
+
+func (kr *KeyRing) SigningKey() (SigningKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, key := range kr.keys {
+		if key.IsActive(now) {
+			return key, nil
+		}
+	}
+
+	return SigningKey{}, ErrNoActiveKey
+}
+
+func (kr *KeyRing) Lookup(kid string) (SigningKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	for _, key := range kr.keys {
+		if key.ID != kid {
+			continue
+		}
+
+		if key.IsRetired(time.Now()) {
+			return SigningKey{}, ErrRetiredKey
+		}
+
+		return key, nil
+	}
+
+	return SigningKey{}, ErrUnknownKey
+}
+
+// VerificationKeys returns the keys that aren't retired, including the ones waiting for activation.
+func (kr *KeyRing) VerificationKeys() []SigningKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]SigningKey, 0, len(kr.keys))
+
+	for _, key := range kr.keys {
+		if !key.IsRetired(now) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (kr *KeyRing) JWKS() JWKS {
+	return NewJWKS(kr.VerificationKeys()...)
+}
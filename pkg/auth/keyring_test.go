@@ -0,0 +1,132 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestKeyRingSigningKey(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	retired := auth.NewHMACKey("retired", "retired-secret")
+	retired.ActivatesAt = now.Add(-time.Hour * 2)
+	retired.RetiresAt = now.Add(-time.Hour)
+
+	current := auth.NewHMACKey("current", "current-secret")
+	current.ActivatesAt = now.Add(-time.Hour)
+	current.RetiresAt = now.Add(time.Hour)
+
+	next := auth.NewHMACKey("next", "next-secret")
+	next.ActivatesAt = now.Add(time.Minute)
+
+	tests := []struct {
+		scenario      string
+		keys          []auth.SigningKey
+		expectedKeyID string
+		expectedError error
+	}{
+		{
+			scenario:      "when ring is empty",
+			expectedError: auth.ErrNoActiveKey,
+		},
+		{
+			scenario:      "when newest key is not active yet",
+			keys:          []auth.SigningKey{retired, next, current},
+			expectedKeyID: current.ID,
+		},
+		{
+			scenario:      "when all keys are retired or pending",
+			keys:          []auth.SigningKey{retired, next},
+			expectedError: auth.ErrNoActiveKey,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := auth.NewKeyRing(tc.keys...).SigningKey()
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expectedKeyID, key.ID)
+			}
+		})
+	}
+}
+
+func TestKeyRingValidateTokens(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	previous := auth.NewHMACKey("previous", "previous-secret")
+	previous.ActivatesAt = now.Add(-time.Hour)
+	previous.RetiresAt = now.Add(time.Hour)
+
+	retired := auth.NewHMACKey("retired", "retired-secret")
+	retired.RetiresAt = now.Add(-time.Minute)
+
+	current := auth.NewHMACKey("current", "current-secret")
+	current.ActivatesAt = now.Add(-time.Minute)
+
+	ring := auth.NewKeyRing(previous, retired, current)
+
+	tests := []struct {
+		scenario      string
+		key           auth.SigningKey
+		expectedError error
+	}{
+		{scenario: "when token is signed by the current key", key: current},
+		{scenario: "when token is signed by a superseded key", key: previous},
+		{scenario: "when token is signed by a retired key", key: retired, expectedError: auth.ErrRetiredKey},
+		{
+			scenario:      "when token is signed by an unknown key",
+			key:           auth.NewHMACKey("unknown", "unknown-secret"),
+			expectedError: auth.ErrUnknownKey,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			token, err := auth.GenerateJwtToken(tc.key, auth.Claims{Subject: uuid.NewString()}, time.Minute)
+			assert.NoError(t, err)
+
+			_, err = auth.ValidateJwtToken(token.Token, ring)
+
+			if tc.expectedError != nil {
+				assert.ErrorIs(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	ciphertext, err := auth.Encrypt("encryption-key", []byte("my-private-key"))
+	assert.NoError(t, err)
+
+	plaintext, err := auth.Decrypt("encryption-key", ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-private-key", string(plaintext))
+
+	_, err = auth.Decrypt("another-key", ciphertext)
+	assert.Error(t, err)
+}
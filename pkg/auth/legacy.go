@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"crypto/md5" //nolint:gosec // md5 is only verified to upgrade the imported hashes, it's never generated.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Legacy algorithms of the imported users, their hashes are only verified and replaced by the current hasher
+// on the first successful login.
+const (
+	// Django format, "pbkdf2_sha256$iterations$salt$base64(key)"
+	PasswordHashPBKDF2SHA256 = "pbkdf2_sha256"
+	// Django format, "scrypt$n$salt$r$p$base64(key)"
+	PasswordHashScrypt = "scrypt"
+	// crypt(3) format, "$6$rounds=N$salt$hash", the rounds are optional
+	PasswordHashSHA512Crypt = "sha512_crypt"
+	// Django format, "md5$salt$hex(md5(salt + password))"
+	PasswordHashSaltedMD5 = "md5"
+)
+
+const (
+	sha512CryptDefaultRounds = 5000
+	sha512CryptMinRounds     = 1000
+	sha512CryptMaxRounds     = 999999999
+	sha512CryptMaxSalt       = 16
+	sha512CryptRoundsPrefix  = "rounds="
+	cryptAlphabet            = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// verifyLegacyPasswordHash checks the password against a hash of a legacy algorithm, the malformed hashes never
+// match.
+func verifyLegacyPasswordHash(algorithm, password, hash string) bool {
+	var expected, actual []byte
+
+	switch algorithm {
+	case PasswordHashPBKDF2SHA256:
+		expected, actual = pbkdf2SHA256Keys(password, hash)
+	case PasswordHashScrypt:
+		expected, actual = scryptKeys(password, hash)
+	case PasswordHashSHA512Crypt:
+		expected, actual = []byte(hash), []byte(sha512Crypt(password, hash))
+	case PasswordHashSaltedMD5:
+		expected, actual = saltedMD5Keys(password, hash)
+	}
+
+	return len(expected) > 0 && subtle.ConstantTimeCompare(expected, actual) == 1
+}
+
+func pbkdf2SHA256Keys(password, hash string) ([]byte, []byte) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 {
+		return nil, nil
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return nil, nil
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil
+	}
+
+	return expected, pbkdf2.Key([]byte(password), []byte(parts[2]), iterations, len(expected), sha256.New)
+}
+
+func scryptKeys(password, hash string) ([]byte, []byte) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, nil
+	}
+
+	var costs [3]int
+
+	for i, part := range []string{parts[1], parts[3], parts[4]} {
+		cost, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, nil
+		}
+
+		costs[i] = cost
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil
+	}
+
+	actual, err := scrypt.Key([]byte(password), []byte(parts[2]), costs[0], costs[1], costs[2], len(expected))
+	if err != nil {
+		return nil, nil
+	}
+
+	return expected, actual
+}
+
+func saltedMD5Keys(password, hash string) ([]byte, []byte) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+
+	expected, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil
+	}
+
+	sum := md5.Sum([]byte(parts[1] + password)) //nolint:gosec // see the import
+
+	return expected, sum[:]
+}
+
+// sha512Crypt hashes the password with the salt and rounds of the setting, it returns an empty string when the
+// setting isn't valid. The algorithm is the one specified by Ulrich Drepper for the glibc crypt(3).
+func sha512Crypt(password, setting string) string {
+	if !strings.HasPrefix(setting, "$6$") {
+		return ""
+	}
+
+	rest := strings.TrimPrefix(setting, "$6$")
+	rounds, customRounds := sha512CryptDefaultRounds, false
+
+	if strings.HasPrefix(rest, sha512CryptRoundsPrefix) {
+		end := strings.IndexByte(rest, '$')
+		if end < 0 {
+			return ""
+		}
+
+		value, err := strconv.Atoi(rest[len(sha512CryptRoundsPrefix):end])
+		if err != nil {
+			return ""
+		}
+
+		rounds, customRounds = clampSHA512CryptRounds(value), true
+		rest = rest[end+1:]
+	}
+
+	salt := rest
+	if end := strings.IndexByte(salt, '$'); end >= 0 {
+		salt = salt[:end]
+	}
+
+	if len(salt) > sha512CryptMaxSalt {
+		salt = salt[:sha512CryptMaxSalt]
+	}
+
+	digest := sha512CryptDigest([]byte(password), []byte(salt), rounds)
+
+	var output strings.Builder
+
+	output.WriteString("$6$")
+
+	if customRounds {
+		output.WriteString(sha512CryptRoundsPrefix + strconv.Itoa(rounds) + "$")
+	}
+
+	output.WriteString(salt + "$")
+	output.WriteString(encodeSHA512CryptDigest(digest))
+
+	return output.String()
+}
+
+func clampSHA512CryptRounds(rounds int) int {
+	if rounds < sha512CryptMinRounds {
+		return sha512CryptMinRounds
+	}
+
+	if rounds > sha512CryptMaxRounds {
+		return sha512CryptMaxRounds
+	}
+
+	return rounds
+}
+
+func sha512CryptDigest(password, salt []byte, rounds int) []byte {
+	alternate := sha512.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alternateSum := alternate.Sum(nil)
+
+	first := sha512.New()
+	first.Write(password)
+	first.Write(salt)
+	first.Write(repeatBytes(alternateSum, len(password)))
+
+	for length := len(password); length > 0; length >>= 1 {
+		if length&1 == 1 {
+			first.Write(alternateSum)
+		} else {
+			first.Write(password)
+		}
+	}
+
+	sum := first.Sum(nil)
+
+	passwordDigest := sha512.New()
+	for i := 0; i < len(password); i++ {
+		passwordDigest.Write(password)
+	}
+
+	passwordSequence := repeatBytes(passwordDigest.Sum(nil), len(password))
+
+	saltDigest := sha512.New()
+	for i := 0; i < 16+int(sum[0]); i++ {
+		saltDigest.Write(salt)
+	}
+
+	saltSequence := repeatBytes(saltDigest.Sum(nil), len(salt))
+
+	for i := 0; i < rounds; i++ {
+		round := sha512.New()
+
+		if i%2 == 1 {
+			round.Write(passwordSequence)
+		} else {
+			round.Write(sum)
+		}
+
+		if i%3 != 0 {
+			round.Write(saltSequence)
+		}
+
+		if i%7 != 0 {
+			round.Write(passwordSequence)
+		}
+
+		if i%2 == 1 {
+			round.Write(sum)
+		} else {
+			round.Write(passwordSequence)
+		}
+
+		sum = round.Sum(nil)
+	}
+
+	return sum
+}
+
+// repeatBytes fills length bytes with the sequence repeated.
+func repeatBytes(sequence []byte, length int) []byte {
+	repeated := make([]byte, 0, length)
+	for len(repeated) < length {
+		repeated = append(repeated, sequence[:min(len(sequence), length-len(repeated))]...)
+	}
+
+	return repeated
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// encodeSHA512CryptDigest encodes the digest with the byte permutation and alphabet of crypt(3).
+func encodeSHA512CryptDigest(digest []byte) string {
+	var output strings.Builder
+
+	encode := func(b2, b1, b0 byte, chars int) {
+		value := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for i := 0; i < chars; i++ {
+			output.WriteByte(cryptAlphabet[value&0x3f])
+			value >>= 6
+		}
+	}
+
+	for i := 0; i < 21; i++ {
+		encode(digest[i*22%63], digest[(i*22+21)%63], digest[(i*22+42)%63], 4)
+	}
+
+	encode(0, 0, digest[63], 2)
+
+	return output.String()
+}
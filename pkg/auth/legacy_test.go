@@ -0,0 +1,129 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestCheckPasswordHashLegacy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario          string
+		password          string
+		hash              string
+		expectedAlgorithm string
+	}{
+		{
+			scenario:          "when hash is django pbkdf2 sha256",
+			password:          "my-password",
+			hash:              "pbkdf2_sha256$1000$seasalt$SWQeYfPvhvhtuV9OhCKeufOo3f2A9VB7jYT1LGbV1/w=",
+			expectedAlgorithm: auth.PasswordHashPBKDF2SHA256,
+		},
+		{
+			scenario: "when hash is django scrypt",
+			password: "my-password",
+			hash: "scrypt$16384$seasalt$8$1$ESiGzxu+V4ZVL9pxCFLhFzopcJ8T0Cnk4WCVyIt1HP/+NL5G5wIeMNbYDNdlaoFuNLKkpOuAvd5" +
+				"ACyBRFEXaKw==",
+			expectedAlgorithm: auth.PasswordHashScrypt,
+		},
+		{
+			scenario: "when hash is sha512 crypt with the default rounds",
+			password: "my-password",
+			hash: "$6$seasalt$sqA3BXb3DhQdiyyx9fGxaPakdIVmSpHNXIFYkAgweRorgL5s9JPDe3tOhOTZ7d8jeB4RVGWXRWGGdkKxCGoF" +
+				"7/",
+			expectedAlgorithm: auth.PasswordHashSHA512Crypt,
+		},
+		{
+			scenario: "when hash is sha512 crypt with custom rounds",
+			password: "my-password",
+			hash: "$6$rounds=1000$seasalt$LpgkGTR34at5VGSnR7gAQ99hj4MorYQvp4RhiTlkjwKyC.zxP2MrO3DZBRfB.FVXLc9tdsraCRM" +
+				"jNb8y4599./",
+			expectedAlgorithm: auth.PasswordHashSHA512Crypt,
+		},
+		{
+			scenario: "when hash is sha512 crypt of the specification with a long salt",
+			password: "Hello world!",
+			hash: "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy" +
+				"/YTBmSK6H9qs/y3RnOaw5v.",
+			expectedAlgorithm: auth.PasswordHashSHA512Crypt,
+		},
+		{
+			scenario: "when hash is sha512 crypt of a password longer than the digest",
+			password: "we have a short salt string but not a short password, longer than the sixty four bytes",
+			hash: "$6$rounds=1000$roundstoolow$nywZ7UxjQUxY5LMDdXAX859rZN.R.mwsvRcGIR95Ns7JhdBlxWs9QbpxX9x.fRpWSEov" +
+				"2mIwjqHlQwLirbGM6.",
+			expectedAlgorithm: auth.PasswordHashSHA512Crypt,
+		},
+		{
+			scenario:          "when hash is salted md5",
+			password:          "my-password",
+			hash:              "md5$seasalt$9adca04dd1bcbe3619188fb11cbff586",
+			expectedAlgorithm: auth.PasswordHashSaltedMD5,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			algorithm, err := auth.PasswordHashAlgorithm(tc.hash)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedAlgorithm, algorithm)
+			assert.True(t, auth.CheckPasswordHash(tc.password, tc.hash))
+			assert.False(t, auth.CheckPasswordHash("wrong-password", tc.hash))
+			assert.True(t, auth.PasswordNeedsRehash(tc.hash))
+		})
+	}
+}
+
+func TestCheckPasswordHashLegacyMalformed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		hash     string
+	}{
+		{
+			scenario: "when pbkdf2 iterations are invalid",
+			hash:     "pbkdf2_sha256$many$seasalt$SWQeYfPvhvhtuV9OhCKeufOo3f2A9VB7jYT1LGbV1/w=",
+		},
+		{
+			scenario: "when pbkdf2 key is missing",
+			hash:     "pbkdf2_sha256$1000$seasalt$",
+		},
+		{
+			scenario: "when scrypt cost is invalid",
+			hash:     "scrypt$1000$seasalt$8$1$Qrp6uJggRdoLi+1qoHZOEp+7NfUjUuG0o48qah8JD8U=",
+		},
+		{
+			scenario: "when sha512 crypt has no salt separator",
+			hash:     "$6$rounds=1000",
+		},
+		{
+			scenario: "when md5 is not hex",
+			hash:     "md5$seasalt$not-hex",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			valid := auth.CheckPasswordHash("my-password", tc.hash)
+
+			// Assert
+			assert.False(t, valid)
+		})
+	}
+}
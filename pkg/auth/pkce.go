@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// CodeChallengeMethodS256 is the only PKCE method accepted, plain doesn't protect the code if it leaks.
+const CodeChallengeMethodS256 = "S256"
+
+const (
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// NewCodeChallenge derives the S256 code challenge of the verifier as RFC 7636.
+func NewCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge checks the verifier sent on the token request matches the challenge of the authorization.
+func VerifyCodeChallenge(verifier, challenge, method string) bool {
+	if method != CodeChallengeMethodS256 {
+		return false
+	}
+
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(NewCodeChallenge(verifier)), []byte(challenge)) == 1
+}
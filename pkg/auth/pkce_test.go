@@ -0,0 +1,61 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	t.Parallel()
+
+	// Example of RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		scenario  string
+		verifier  string
+		challenge string
+		method    string
+		expected  bool
+	}{
+		{
+			scenario:  "when verifier matches the challenge",
+			verifier:  verifier,
+			challenge: challenge,
+			method:    auth.CodeChallengeMethodS256,
+			expected:  true,
+		},
+		{
+			scenario:  "when verifier does not match",
+			verifier:  strings.Repeat("a", 43),
+			challenge: challenge,
+			method:    auth.CodeChallengeMethodS256,
+		},
+		{
+			scenario:  "when method is plain",
+			verifier:  verifier,
+			challenge: verifier,
+			method:    "plain",
+		},
+		{
+			scenario:  "when verifier is too short",
+			verifier:  "short",
+			challenge: auth.NewCodeChallenge("short"),
+			method:    auth.CodeChallengeMethodS256,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, auth.VerifyCodeChallenge(tc.verifier, tc.challenge, tc.method))
+		})
+	}
+}
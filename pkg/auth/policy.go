@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules of the password policy, each violation reports the rule it failed.
+const (
+	PasswordRuleMinLength    = "min_length"
+	PasswordRuleMaxLength    = "max_length"
+	PasswordRuleUppercase    = "uppercase"
+	PasswordRuleLowercase    = "lowercase"
+	PasswordRuleDigit        = "digit"
+	PasswordRuleSymbol       = "symbol"
+	PasswordRuleMaxRepeated  = "max_repeated"
+	PasswordRulePersonalInfo = "personal_info"
+	PasswordRuleBannedWord   = "banned_word"
+	PasswordRuleBreached     = "breached"
+	// The password was used recently by the user, it's checked with the password history.
+	PasswordRuleHistory = "history"
+)
+
+// MinPasswordLength is the floor of the min length rule, the policies with a lower min length are raised to it.
+const MinPasswordLength = 5
+
+// Parts of the name and email shorter than it are too common to be banned.
+const minPersonalInfoLength = 3
+
+// PasswordPolicy are the requirements of the user passwords, the zero value only requires the
+// MinPasswordLength. Zero max length and max repeated disable those rules.
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// Max times a character can be repeated in a row, like "aaa"
+	MaxRepeated int
+	// Rejects passwords containing the name or the email of the user
+	BanPersonalInfo bool
+	// Case insensitive words the passwords can't contain, like the name of the product
+	BannedWords []string
+	// Breached and common passwords, nil disables the rule
+	Corpus PasswordCorpus
+	// Number of recent passwords, the current one included, that can't be reused. Zero disables the history
+	HistorySize int
+}
+
+type PasswordViolation struct {
+	Rule    string
+	Message string
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: MinPasswordLength}
+}
+
+// Validate returns the violated rules in a stable order, the personal info are the name and email of the user.
+func (p PasswordPolicy) Validate(password string, personalInfo ...string) []PasswordViolation {
+	violations := []PasswordViolation{}
+	add := func(rule, message string, args ...any) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(message, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	minLength := p.MinLength
+
+	if minLength < MinPasswordLength {
+		minLength = MinPasswordLength
+	}
+
+	if length < minLength {
+		add(PasswordRuleMinLength, "password must be %d or more caracters", minLength)
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		add(PasswordRuleMaxLength, "password must be %d or less characters", p.MaxLength)
+	}
+
+	if p.RequireUppercase && !containsFunc(password, unicode.IsUpper) {
+		add(PasswordRuleUppercase, "password must contain an uppercase letter")
+	}
+
+	if p.RequireLowercase && !containsFunc(password, unicode.IsLower) {
+		add(PasswordRuleLowercase, "password must contain a lowercase letter")
+	}
+
+	if p.RequireDigit && !containsFunc(password, unicode.IsDigit) {
+		add(PasswordRuleDigit, "password must contain a digit")
+	}
+
+	if p.RequireSymbol && !containsFunc(password, isSymbol) {
+		add(PasswordRuleSymbol, "password must contain a symbol")
+	}
+
+	if p.MaxRepeated > 0 && maxRepeated(password) > p.MaxRepeated {
+		add(PasswordRuleMaxRepeated, "password must not repeat a character more than %d times in a row", p.MaxRepeated)
+	}
+
+	lowered := strings.ToLower(password)
+
+	if p.BanPersonalInfo && containsAny(lowered, personalInfoParts(personalInfo)) != "" {
+		add(PasswordRulePersonalInfo, "password must not contain the name or email")
+	}
+
+	if word := containsAny(lowered, p.BannedWords); word != "" {
+		add(PasswordRuleBannedWord, "password must not contain %q", word)
+	}
+
+	if p.Corpus != nil && p.Corpus.Contains(password) {
+		add(PasswordRuleBreached, "password is too common or was exposed in a data breach")
+	}
+
+	return violations
+}
+
+func containsFunc(password string, f func(rune) bool) bool {
+	return strings.IndexFunc(password, f) >= 0
+}
+
+func isSymbol(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+}
+
+func maxRepeated(password string) int {
+	longest, current := 0, 0
+
+	var previous rune
+
+	for i, r := range []rune(password) {
+		if i > 0 && r == previous {
+			current++
+		} else {
+			current = 1
+		}
+
+		if current > longest {
+			longest = current
+		}
+
+		previous = r
+	}
+
+	return longest
+}
+
+// personalInfoParts splits the name in words and the email in the parts of the local part.
+func personalInfoParts(personalInfo []string) []string {
+	parts := []string{}
+
+	for _, info := range personalInfo {
+		info = strings.ToLower(info)
+		if at := strings.LastIndex(info, "@"); at >= 0 {
+			info = info[:at]
+		}
+
+		for _, part := range strings.FieldsFunc(info, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if utf8.RuneCountInString(part) >= minPersonalInfoLength {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	return parts
+}
+
+func containsAny(lowered string, words []string) string {
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(lowered, word) {
+			return word
+		}
+	}
+
+	return ""
+}
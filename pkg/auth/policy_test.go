@@ -0,0 +1,112 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	strict := auth.PasswordPolicy{
+		MinLength:        8,
+		MaxLength:        20,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		MaxRepeated:      2,
+		BanPersonalInfo:  true,
+		BannedWords:      []string{"Acme"},
+	}
+
+	breached := auth.NewBloomFilter(1, 0.001)
+	breached.Add("Tr0ub4dor&3x")
+
+	tests := []struct {
+		scenario      string
+		policy        auth.PasswordPolicy
+		password      string
+		expectedRules []string
+	}{
+		{
+			scenario:      "when password follows every rule",
+			policy:        strict,
+			password:      "Tr0ub4dor&3x",
+			expectedRules: []string{},
+		},
+		{
+			scenario:      "when policy is empty the min length is still required",
+			policy:        auth.PasswordPolicy{},
+			password:      "1234",
+			expectedRules: []string{auth.PasswordRuleMinLength},
+		},
+		{
+			scenario: "when password is short and misses the character classes",
+			policy:   strict,
+			password: "abc",
+			expectedRules: []string{
+				auth.PasswordRuleMinLength, auth.PasswordRuleUppercase, auth.PasswordRuleDigit, auth.PasswordRuleSymbol,
+			},
+		},
+		{
+			scenario:      "when password is too long",
+			policy:        strict,
+			password:      "Tr0ub4dor&3x-Tr0ub4dor&3x",
+			expectedRules: []string{auth.PasswordRuleMaxLength},
+		},
+		{
+			scenario:      "when password repeats a character",
+			policy:        strict,
+			password:      "Tr0ub4dor&3xxx",
+			expectedRules: []string{auth.PasswordRuleMaxRepeated},
+		},
+		{
+			scenario:      "when password contains the name",
+			policy:        strict,
+			password:      "Tr0ub4dor&John",
+			expectedRules: []string{auth.PasswordRulePersonalInfo},
+		},
+		{
+			scenario:      "when password contains the email",
+			policy:        strict,
+			password:      "Tr0ub4dor&jdoe",
+			expectedRules: []string{auth.PasswordRulePersonalInfo},
+		},
+		{
+			scenario:      "when password contains a banned word",
+			policy:        strict,
+			password:      "Tr0ub4dor&ACME",
+			expectedRules: []string{auth.PasswordRuleBannedWord},
+		},
+		{
+			scenario:      "when password is in the corpus",
+			policy:        auth.PasswordPolicy{Corpus: auth.PasswordCorpora{breached}},
+			password:      "Tr0ub4dor&3x",
+			expectedRules: []string{auth.PasswordRuleBreached},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			violations := tc.policy.Validate(tc.password, "JOHN SMITH", "jdoe@example.com")
+
+			// Assert
+			rules := []string{}
+			for _, violation := range violations {
+				assert.NotEmpty(t, violation.Message)
+
+				rules = append(rules, violation.Rule)
+			}
+
+			assert.Equal(t, tc.expectedRules, rules)
+		})
+	}
+}
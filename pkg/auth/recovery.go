@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+const (
+	RecoveryCodeCount = 10
+
+	recoveryCodeSize      = 5
+	recoveryCodeGroupSize = 4
+)
+
+// Crockford's base32 alphabet in lowercase, it has no letters easily confused with digits, like i, l and o.
+var recoveryCodeEncoding = base32.NewEncoding("0123456789abcdefghjkmnpqrstvwxyz").WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes returns random single use codes formatted in groups, like "abcd-efgh".
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		raw := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		code := recoveryCodeEncoding.EncodeToString(raw)
+		codes = append(codes, code[:recoveryCodeGroupSize]+"-"+code[recoveryCodeGroupSize:])
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode removes the formatting of the code, users may type it without the separator or with
+// uppercase letters.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
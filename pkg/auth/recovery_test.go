@@ -0,0 +1,62 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	// Action
+	codes, err := auth.GenerateRecoveryCodes(auth.RecoveryCodeCount)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, codes, auth.RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, "^[0-9a-z]{4}-[0-9a-z]{4}$", code)
+		assert.False(t, seen[code])
+
+		seen[code] = true
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario string
+		code     string
+		expected string
+	}{
+		{
+			scenario: "when code is formatted",
+			code:     "ab12-cd34",
+			expected: "ab12cd34",
+		},
+		{
+			scenario: "when code is typed in uppercase with spaces",
+			code:     " AB12 CD34 ",
+			expected: "ab12cd34",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			normalized := auth.NormalizeRecoveryCode(tc.code)
+
+			// Assert
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+)
+
+func TestTOTPCode(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors of RFC 6238 appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		scenario string
+		at       time.Time
+		expected string
+	}{
+		{scenario: "when time is 59", at: time.Unix(59, 0), expected: "287082"},
+		{scenario: "when time is 1111111109", at: time.Unix(1111111109, 0), expected: "081804"},
+		{scenario: "when time is 1234567890", at: time.Unix(1234567890, 0), expected: "005924"},
+		{scenario: "when time is 20000000000", at: time.Unix(20000000000, 0), expected: "353130"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := auth.TOTPCode(secret, tc.at)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, code)
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	t.Parallel()
+
+	secret, err := auth.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	tests := []struct {
+		scenario string
+		codeAt   time.Time
+		expected bool
+	}{
+		{scenario: "when code is of the current period", codeAt: now, expected: true},
+		{scenario: "when code is of the previous period", codeAt: now.Add(-auth.TOTPPeriod), expected: true},
+		{scenario: "when code is too old", codeAt: now.Add(-3 * auth.TOTPPeriod), expected: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			code, err := auth.TOTPCode(secret, tc.codeAt)
+			assert.NoError(t, err)
+
+			_, ok := auth.ValidateTOTP(secret, code, now)
+			assert.Equal(t, tc.expected, ok)
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	t.Parallel()
+
+	uri := auth.TOTPProvisioningURI("go-auth-service", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/go-auth-service:user@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=go-auth-service")
+}
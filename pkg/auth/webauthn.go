@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// COSE algorithms accepted for the credential keys, in order of preference.
+const (
+	COSEAlgorithmES256 = -7
+	COSEAlgorithmEdDSA = -8
+	COSEAlgorithmRS256 = -257
+)
+
+const (
+	webAuthnTypeCreate = "webauthn.create"
+	webAuthnTypeGet    = "webauthn.get"
+
+	// Flags of the authenticator data
+	webAuthnUserPresent        = 0x01
+	webAuthnUserVerified       = 0x04
+	webAuthnAttestedCredential = 0x40
+
+	webAuthnRPIDHashSize    = 32
+	webAuthnAuthDataMinSize = webAuthnRPIDHashSize + 1 + 4
+	webAuthnAAGUIDSize      = 16
+
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyTypeOKP   = 1
+	coseKeyTypeEC2   = 2
+	coseKeyTypeRSA   = 3
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+var (
+	ErrInvalidWebAuthnResponse = errors.New("invalid webauthn response")
+	ErrUnsupportedCOSEKey      = errors.New("unsupported cose key")
+)
+
+// WebAuthnAlgorithms are the COSE algorithms offered to the authenticators on the registration.
+var WebAuthnAlgorithms = []int{COSEAlgorithmES256, COSEAlgorithmEdDSA, COSEAlgorithmRS256}
+
+// WebAuthnCeremony is what the relying party expects from the response of the authenticator.
+type WebAuthnCeremony struct {
+	RPID      string
+	Origin    string
+	Challenge string
+	// Passwordless logins require the authenticator to verify the user, with a PIN or biometrics.
+	RequireUserVerification bool
+}
+
+// WebAuthnCredential is the credential created by the authenticator, the public key is COSE encoded.
+type WebAuthnCredential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type webAuthnAuthenticatorData struct {
+	Flags      byte
+	SignCount  uint32
+	Credential WebAuthnCredential
+}
+
+// VerifyWebAuthnRegistration validates the response of navigator.credentials.create and returns the new
+// credential. Attestation statements aren't verified, the registration asks for the "none" conveyance.
+func VerifyWebAuthnRegistration(
+	ceremony WebAuthnCeremony, clientDataJSON, attestationObject []byte,
+) (WebAuthnCredential, error) {
+	if err := verifyWebAuthnClientData(ceremony, clientDataJSON, webAuthnTypeCreate); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return WebAuthnCredential{}, ErrInvalidWebAuthnResponse
+	}
+
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return WebAuthnCredential{}, ErrInvalidWebAuthnResponse
+	}
+
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return WebAuthnCredential{}, ErrInvalidWebAuthnResponse
+	}
+
+	authData, err := parseWebAuthnAuthenticatorData(ceremony, rawAuthData)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	if authData.Flags&webAuthnAttestedCredential == 0 {
+		return WebAuthnCredential{}, ErrInvalidWebAuthnResponse
+	}
+
+	if _, err := ParseCOSEKey(authData.Credential.PublicKey); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	authData.Credential.SignCount = authData.SignCount
+
+	return authData.Credential, nil
+}
+
+// VerifyWebAuthnAssertion validates the response of navigator.credentials.get against the stored public key,
+// it returns the signature counter of the authenticator.
+func VerifyWebAuthnAssertion(
+	ceremony WebAuthnCeremony, publicKey, clientDataJSON, authenticatorData, signature []byte,
+) (uint32, error) {
+	if err := verifyWebAuthnClientData(ceremony, clientDataJSON, webAuthnTypeGet); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseWebAuthnAuthenticatorData(ceremony, authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := ParseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := make([]byte, 0, len(authenticatorData)+len(clientDataHash))
+	signed = append(signed, authenticatorData...)
+	signed = append(signed, clientDataHash[:]...)
+
+	if !verifyWebAuthnSignature(key, signed, signature) {
+		return 0, ErrInvalidWebAuthnResponse
+	}
+
+	return authData.SignCount, nil
+}
+
+func verifyWebAuthnClientData(ceremony WebAuthnCeremony, clientDataJSON []byte, ceremonyType string) error {
+	var clientData webAuthnClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return ErrInvalidWebAuthnResponse
+	}
+
+	if clientData.Type != ceremonyType || clientData.Origin != ceremony.Origin {
+		return ErrInvalidWebAuthnResponse
+	}
+
+	if ceremony.Challenge == "" ||
+		subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(ceremony.Challenge)) != 1 {
+		return ErrInvalidWebAuthnResponse
+	}
+
+	return nil
+}
+
+func parseWebAuthnAuthenticatorData(ceremony WebAuthnCeremony, data []byte) (webAuthnAuthenticatorData, error) {
+	if len(data) < webAuthnAuthDataMinSize {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	rpIDHash := sha256.Sum256([]byte(ceremony.RPID))
+	if subtle.ConstantTimeCompare(data[:webAuthnRPIDHashSize], rpIDHash[:]) != 1 {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	authData := webAuthnAuthenticatorData{
+		Flags:     data[webAuthnRPIDHashSize],
+		SignCount: binary.BigEndian.Uint32(data[webAuthnRPIDHashSize+1:]),
+	}
+
+	if authData.Flags&webAuthnUserPresent == 0 {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	if ceremony.RequireUserVerification && authData.Flags&webAuthnUserVerified == 0 {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	if authData.Flags&webAuthnAttestedCredential == 0 {
+		return authData, nil
+	}
+
+	rest := data[webAuthnAuthDataMinSize:]
+	if len(rest) < webAuthnAAGUIDSize+2 {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	rest = rest[webAuthnAAGUIDSize:]
+	idLength := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+
+	if idLength == 0 || len(rest) < idLength {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	authData.Credential.ID = rest[:idLength]
+
+	// The key is followed by the extensions, its size is only known after decoding it.
+	_, extensions, err := decodeCBOR(rest[idLength:])
+	if err != nil {
+		return webAuthnAuthenticatorData{}, ErrInvalidWebAuthnResponse
+	}
+
+	authData.Credential.PublicKey = rest[idLength : len(rest)-len(extensions)]
+
+	return authData, nil
+}
+
+// ParseCOSEKey decodes the public key of a credential, ES256, EdDSA and RS256 keys are supported.
+func ParseCOSEKey(data []byte) (crypto.PublicKey, error) {
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, ErrUnsupportedCOSEKey
+	}
+
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrUnsupportedCOSEKey
+	}
+
+	keyType, _ := key[int64(coseKeyType)].(int64)
+	algorithm, _ := key[int64(coseKeyAlgorithm)].(int64)
+	curve, _ := key[int64(-1)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == COSEAlgorithmES256 && curve == coseCurveP256:
+		x, okX := key[int64(-2)].([]byte)
+		y, okY := key[int64(-3)].([]byte)
+
+		if !okX || !okY {
+			return nil, ErrUnsupportedCOSEKey
+		}
+
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, ErrUnsupportedCOSEKey
+		}
+
+		return publicKey, nil
+
+	case keyType == coseKeyTypeOKP && algorithm == COSEAlgorithmEdDSA && curve == coseCurveEd25519:
+		x, ok := key[int64(-2)].([]byte)
+		if !ok || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedCOSEKey
+		}
+
+		return ed25519.PublicKey(x), nil
+
+	case keyType == coseKeyTypeRSA && algorithm == COSEAlgorithmRS256:
+		n, okN := key[int64(-1)].([]byte)
+		e, okE := key[int64(-2)].([]byte)
+
+		if !okN || !okE || len(e) > 4 {
+			return nil, ErrUnsupportedCOSEKey
+		}
+
+		publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if publicKey.N.BitLen() < rsaKeySize || publicKey.E < 3 {
+			return nil, ErrUnsupportedCOSEKey
+		}
+
+		return publicKey, nil
+
+	default:
+		return nil, ErrUnsupportedCOSEKey
+	}
+}
+
+func verifyWebAuthnSignature(key crypto.PublicKey, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+
+	switch publicKey := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(publicKey, signed, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
+
+// EncodeWebAuthnID encodes the binary values of the ceremonies, such as the credential ids, as the browsers do.
+func EncodeWebAuthnID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// DecodeWebAuthnID accepts the padded and unpadded forms of base64url.
+func DecodeWebAuthnID(id string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(id, "="))
+}
@@ -0,0 +1,213 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/auth/authtest"
+)
+
+const (
+	testRPID      = "auth.example.com"
+	testOrigin    = "https://auth.example.com"
+	testChallenge = "c2lnbi10aGlzLWNoYWxsZW5nZQ"
+)
+
+func TestVerifyWebAuthnRegistration(t *testing.T) {
+	t.Parallel()
+
+	ceremony := auth.WebAuthnCeremony{RPID: testRPID, Origin: testOrigin, Challenge: testChallenge}
+
+	tests := []struct {
+		scenario    string
+		rpID        string
+		origin      string
+		challenge   string
+		expectedErr error
+	}{
+		{
+			scenario:  "when the response matches the ceremony",
+			rpID:      testRPID,
+			origin:    testOrigin,
+			challenge: testChallenge,
+		},
+		{
+			scenario:    "when the challenge is different",
+			rpID:        testRPID,
+			origin:      testOrigin,
+			challenge:   "another-challenge",
+			expectedErr: auth.ErrInvalidWebAuthnResponse,
+		},
+		{
+			scenario:    "when the origin is different",
+			rpID:        testRPID,
+			origin:      "https://phishing.example.com",
+			challenge:   testChallenge,
+			expectedErr: auth.ErrInvalidWebAuthnResponse,
+		},
+		{
+			scenario:    "when the credential is scoped to another relying party",
+			rpID:        "phishing.example.com",
+			origin:      testOrigin,
+			challenge:   testChallenge,
+			expectedErr: auth.ErrInvalidWebAuthnResponse,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			authenticator, err := authtest.NewAuthenticator(tc.rpID, tc.origin)
+			assert.NoError(t, err)
+
+			clientData, attestation := authenticator.Register(tc.challenge)
+
+			// Action
+			credential, err := auth.VerifyWebAuthnRegistration(ceremony, clientData, attestation)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+
+			if tc.expectedErr == nil {
+				assert.Equal(t, authenticator.CredentialID, credential.ID)
+				assert.Equal(t, authenticator.COSEKey(), credential.PublicKey)
+			}
+		})
+	}
+}
+
+func TestVerifyWebAuthnAssertion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario                string
+		requireUserVerification bool
+		userVerified            bool
+		tamperSignature         bool
+		expectedErr             error
+	}{
+		{
+			scenario: "when the signature is valid",
+		},
+		{
+			scenario:                "when the user was verified and it's required",
+			requireUserVerification: true,
+			userVerified:            true,
+		},
+		{
+			scenario:                "when the user wasn't verified and it's required",
+			requireUserVerification: true,
+			expectedErr:             auth.ErrInvalidWebAuthnResponse,
+		},
+		{
+			scenario:        "when the signature is invalid",
+			tamperSignature: true,
+			expectedErr:     auth.ErrInvalidWebAuthnResponse,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			authenticator, err := authtest.NewAuthenticator(testRPID, testOrigin)
+			assert.NoError(t, err)
+
+			authenticator.UserVerified = tc.userVerified
+			clientData, authData, signature := authenticator.Assert(testChallenge)
+
+			if tc.tamperSignature {
+				signature[len(signature)-1] ^= 0xff
+			}
+
+			ceremony := auth.WebAuthnCeremony{
+				RPID:                    testRPID,
+				Origin:                  testOrigin,
+				Challenge:               testChallenge,
+				RequireUserVerification: tc.requireUserVerification,
+			}
+
+			// Action
+			signCount, err := auth.VerifyWebAuthnAssertion(
+				ceremony, authenticator.COSEKey(), clientData, authData, signature,
+			)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+
+			if tc.expectedErr == nil {
+				assert.Equal(t, authenticator.SignCount, signCount)
+			}
+		})
+	}
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario    string
+		key         []byte
+		expectedErr error
+	}{
+		{
+			scenario: "when the key is an ed25519 key",
+			key: append(
+				[]byte{0xa4, 0x01, 0x01, 0x03, 0x27, 0x20, 0x06, 0x21, 0x58, 0x20},
+				make([]byte, 32)...,
+			),
+		},
+		{
+			scenario: "when the key is a rsa key",
+			key:      coseRSAKey(2048),
+		},
+		{
+			scenario:    "when the rsa key is shorter than 2048 bits",
+			key:         coseRSAKey(1024),
+			expectedErr: auth.ErrUnsupportedCOSEKey,
+		},
+		{
+			scenario:    "when the algorithm isn't supported",
+			key:         []byte{0xa2, 0x01, 0x02, 0x03, 0x38, 0x22},
+			expectedErr: auth.ErrUnsupportedCOSEKey,
+		},
+		{
+			scenario:    "when the data is truncated",
+			key:         []byte{0xa5, 0x01, 0x02, 0x03},
+			expectedErr: auth.ErrUnsupportedCOSEKey,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Action
+			_, err := auth.ParseCOSEKey(tc.key)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+// coseRSAKey encodes a RS256 COSE key with a modulus of the given size and the exponent 65537.
+func coseRSAKey(bits int) []byte {
+	n := make([]byte, bits/8)
+	n[0] = 0x80
+
+	key := []byte{0xa4, 0x01, 0x03, 0x03, 0x39, 0x01, 0x00, 0x20, 0x59, byte(len(n) >> 8), byte(len(n))}
+	key = append(key, n...)
+
+	return append(key, 0x21, 0x43, 0x01, 0x00, 0x01)
+}
@@ -13,6 +13,14 @@ type Client interface {
 	HSet(ctx context.Context, key string, values ...any) error
 	Set(ctx context.Context, key, value string, expiration time.Duration) error
 	HSetExp(ctx context.Context, key string, expiration time.Duration, values ...any) error
+	HGet(ctx context.Context, key, field string) (string, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HDel(ctx context.Context, key string, fields ...string) error
 	Get(ctx context.Context, key string) (string, error)
 	Del(ctx context.Context, key string) error
+	// GetDel returns the value and deletes the key atomically, so the value can be consumed only once
+	GetDel(ctx context.Context, key string) (string, error)
+	// SetNX sets the value only when the key doesn't exist, it reports whether the value was set
+	SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error)
+	Incr(ctx context.Context, key string, expiration time.Duration) (int64, error)
 }
@@ -3,6 +3,8 @@ package cache
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/dgraph-io/ristretto"
@@ -17,6 +19,7 @@ const (
 
 type MemoryCache struct {
 	Client *ristretto.Cache
+	mu     sync.Mutex
 }
 
 func NewMemoryCacheClient() (*MemoryCache, error) {
@@ -45,10 +48,90 @@ func (cache *MemoryCache) Set(ctx context.Context, key, value string, expiration
 }
 
 func (cache *MemoryCache) HSet(ctx context.Context, key string, values ...any) error {
-	return nil
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	// Keep the current expiration of the hash, like redis does
+	ttl, _ := cache.Client.GetTTL(key)
+
+	return cache.hset(key, ttl, values)
 }
 
 func (cache *MemoryCache) HSetExp(ctx context.Context, key string, expiration time.Duration, values ...any) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.hset(key, expiration, values)
+}
+
+func (cache *MemoryCache) HGet(ctx context.Context, key, field string) (string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	value, ok := cache.hash(key)[field]
+	if !ok {
+		return "", errors.Wrap(ErrCache, "Error on hget")
+	}
+
+	return value, nil
+}
+
+func (cache *MemoryCache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	return cache.hash(key), nil
+}
+
+func (cache *MemoryCache) HDel(ctx context.Context, key string, fields ...string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	hash := cache.hash(key)
+	for _, field := range fields {
+		delete(hash, field)
+	}
+
+	ttl, _ := cache.Client.GetTTL(key)
+	cache.Client.SetWithTTL(key, hash, 0, ttl)
+	cache.Client.Wait()
+
+	return nil
+}
+
+// hash returns a copy of the hash stored in the key, the caller must hold the lock.
+func (cache *MemoryCache) hash(key string) map[string]string {
+	hash := map[string]string{}
+
+	if data, ok := cache.Client.Get(key); ok {
+		if stored, ok := data.(map[string]string); ok {
+			for field, value := range stored {
+				hash[field] = value
+			}
+		}
+	}
+
+	return hash
+}
+
+// hset expects the values as field and value pairs, the caller must hold the lock.
+func (cache *MemoryCache) hset(key string, expiration time.Duration, values []any) error {
+	if len(values)%2 != 0 {
+		return errors.Wrap(ErrCache, "HSet expects field and value pairs")
+	}
+
+	hash := cache.hash(key)
+	for i := 0; i < len(values); i += 2 {
+		hash[fmt.Sprint(values[i])] = fmt.Sprint(values[i+1])
+	}
+
+	ok := cache.Client.SetWithTTL(key, hash, 0, expiration)
+	cache.Client.Wait()
+
+	if !ok {
+		return errors.Wrap(ErrCache, "Error on hset")
+	}
+
 	return nil
 }
 
@@ -66,3 +149,69 @@ func (cache *MemoryCache) Del(ctx context.Context, key string) error {
 
 	return nil
 }
+
+func (cache *MemoryCache) GetDel(ctx context.Context, key string) (string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	data, ok := cache.Client.Get(key)
+	if !ok {
+		return "", errors.Wrap(ErrCache, "Error on getdel")
+	}
+
+	cache.Client.Del(key)
+
+	return fmt.Sprintf("%s", data), nil
+}
+
+// SetNX sets the value only when the key doesn't exist, like the SET NX command of redis.
+func (cache *MemoryCache) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if _, ok := cache.Client.Get(key); ok {
+		return false, nil
+	}
+
+	ok := cache.Client.SetWithTTL(key, value, 0, expiration)
+	cache.Client.Wait()
+
+	if !ok {
+		return false, errors.Wrap(ErrCache, "Error on setnx")
+	}
+
+	return true, nil
+}
+
+// Incr increments the counter in the key, the expiration is only set when the key is created, like the INCR and
+// EXPIRE NX commands of redis.
+func (cache *MemoryCache) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	var counter int64
+
+	if data, ok := cache.Client.Get(key); ok {
+		value, err := strconv.ParseInt(fmt.Sprint(data), 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(ErrCache, "value is not an integer")
+		}
+
+		counter = value
+
+		if ttl, ok := cache.Client.GetTTL(key); ok && ttl > 0 {
+			expiration = ttl
+		}
+	}
+
+	counter++
+
+	ok := cache.Client.SetWithTTL(key, strconv.FormatInt(counter, 10), 0, expiration)
+	cache.Client.Wait()
+
+	if !ok {
+		return 0, errors.Wrap(ErrCache, "Error on incr")
+	}
+
+	return counter, nil
+}
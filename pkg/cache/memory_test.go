@@ -0,0 +1,172 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uesleicarvalhoo/go-auth-service/pkg/cache"
+)
+
+func TestMemoryCacheIncr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario        string
+		arrange         func(t *testing.T, sut *cache.MemoryCache)
+		expectedCounter int64
+		expectedErr     error
+	}{
+		{
+			scenario:        "when the key doesn't exist",
+			expectedCounter: 1,
+		},
+		{
+			scenario: "when the key has a counter",
+			arrange: func(t *testing.T, sut *cache.MemoryCache) {
+				t.Helper()
+
+				assert.NoError(t, sut.Set(context.TODO(), "counter", "41", time.Minute))
+			},
+			expectedCounter: 42,
+		},
+		{
+			scenario: "when the value isn't an integer",
+			arrange: func(t *testing.T, sut *cache.MemoryCache) {
+				t.Helper()
+
+				assert.NoError(t, sut.Set(context.TODO(), "counter", "value", time.Minute))
+			},
+			expectedErr: cache.ErrCache,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut, err := cache.NewMemoryCacheClient()
+			assert.NoError(t, err)
+
+			if tc.arrange != nil {
+				tc.arrange(t, sut)
+			}
+
+			// Action
+			counter, err := sut.Incr(context.TODO(), "counter", time.Minute)
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expectedCounter, counter)
+		})
+	}
+}
+
+func TestMemoryCacheIncrKeepsTheExpiration(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut, err := cache.NewMemoryCacheClient()
+	assert.NoError(t, err)
+
+	_, err = sut.Incr(context.TODO(), "counter", time.Millisecond*200)
+	assert.NoError(t, err)
+
+	// Action
+	counter, err := sut.Incr(context.TODO(), "counter", time.Hour)
+	time.Sleep(time.Millisecond * 300)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+
+	value, _ := sut.Get(context.TODO(), "counter")
+	assert.Empty(t, value, "the counter expires with the expiration of the first increment")
+}
+
+func TestMemoryCacheIncrIsAtomic(t *testing.T) {
+	t.Parallel()
+
+	// Arrange
+	sut, err := cache.NewMemoryCacheClient()
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+
+	// Action
+	for i := 0; i < 50; i++ {
+		go func() {
+			_, err := sut.Incr(context.TODO(), "counter", time.Minute)
+			assert.NoError(t, err)
+
+			done <- struct{}{}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		<-done
+	}
+
+	// Assert
+	value, err := sut.Get(context.TODO(), "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, "50", value)
+}
+
+func TestMemoryCacheSetNX(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		scenario      string
+		arrange       func(t *testing.T, sut *cache.MemoryCache)
+		expectedSet   bool
+		expectedValue string
+	}{
+		{
+			scenario:      "when the key doesn't exist",
+			expectedSet:   true,
+			expectedValue: "new",
+		},
+		{
+			scenario: "when the key exists",
+			arrange: func(t *testing.T, sut *cache.MemoryCache) {
+				t.Helper()
+
+				assert.NoError(t, sut.Set(context.TODO(), "key", "current", time.Minute))
+			},
+			expectedSet:   false,
+			expectedValue: "current",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			// Arrange
+			sut, err := cache.NewMemoryCacheClient()
+			assert.NoError(t, err)
+
+			if tc.arrange != nil {
+				tc.arrange(t, sut)
+			}
+
+			// Action
+			set, err := sut.SetNX(context.TODO(), "key", "new", time.Minute)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedSet, set)
+
+			value, err := sut.Get(context.TODO(), "key")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValue, value)
+		})
+	}
+}
@@ -57,6 +57,23 @@ func (cache *RedisClient) HSetExp(ctx context.Context, key string, expiration ti
 	return err
 }
 
+func (cache *RedisClient) HGet(ctx context.Context, key, field string) (string, error) {
+	data, err := cache.Client.HGet(ctx, key, field).Result()
+	if err != nil && err.Error() == "redis: nil" {
+		return data, nil
+	}
+
+	return data, err
+}
+
+func (cache *RedisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return cache.Client.HGetAll(ctx, key).Result()
+}
+
+func (cache *RedisClient) HDel(ctx context.Context, key string, fields ...string) error {
+	return cache.Client.HDel(ctx, key, fields...).Err()
+}
+
 func (cache *RedisClient) Get(ctx context.Context, key string) (string, error) {
 	data, err := cache.Client.Get(ctx, key).Result()
 	if err != nil && err.Error() == "redis: nil" {
@@ -72,3 +89,30 @@ func (cache *RedisClient) Del(ctx context.Context, key string) error {
 
 	return err
 }
+
+func (cache *RedisClient) GetDel(ctx context.Context, key string) (string, error) {
+	data, err := cache.Client.GetDel(ctx, key).Result()
+	if err != nil && err.Error() == "redis: nil" {
+		return data, nil
+	}
+
+	return data, err
+}
+
+func (cache *RedisClient) SetNX(ctx context.Context, key, value string, expiration time.Duration) (bool, error) {
+	return cache.Client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// incrScript sets the expiration with the counter, a key without expiration would never be reset.
+var incrScript = redis.NewScript(`
+local counter = redis.call("INCR", KEYS[1])
+if counter == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return counter
+`)
+
+// Incr increments the counter in the key, the expiration is only set when the key is created.
+func (cache *RedisClient) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	return incrScript.Run(ctx, cache.Client, []string{key}, expiration.Milliseconds()).Int64()
+}